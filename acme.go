@@ -13,12 +13,12 @@ import (
 	"flag"
 	"fmt"
 	"log"
-	"os"
 	"regexp"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"9fans.net/go/acme"
 	"9fans.net/go/plumb"
@@ -65,63 +65,119 @@ func acmeMode() {
 }
 
 func (w *awin) plumbserve() {
+	servePlumb(func(project, what string) bool {
+		var plummy awin
+		plummy.prefix = "/issue/" + project + "/"
+		return plummy.Look(what)
+	}, w.Err)
+}
+
+// servePlumb receives messages on the plumber's githubissue port,
+// calling look with the project and text of each reference,
+// and reporting problems with errorf.
+func servePlumb(look func(project, what string) bool, errorf func(string)) {
 	fid, err := plumb.Open("githubissue", 0)
 	if err != nil {
-		w.Err(fmt.Sprintf("plumb: %v", err))
+		errorf(fmt.Sprintf("plumb: %v", err))
 		return
 	}
 	r := bufio.NewReader(fid)
 	for {
 		var m plumb.Message
 		if err := m.Recv(r); err != nil {
-			w.Err(fmt.Sprintf("plumb recv: %v", err))
+			errorf(fmt.Sprintf("plumb recv: %v", err))
 			return
 		}
 		if m.Type != "text" {
-			w.Err(fmt.Sprintf("plumb recv: unexpected type: %s\n", m.Type))
+			errorf(fmt.Sprintf("plumb recv: unexpected type: %s\n", m.Type))
 			continue
 		}
 		if m.Dst != "githubissue" {
-			w.Err(fmt.Sprintf("plumb recv: unexpected dst: %s\n", m.Dst))
+			errorf(fmt.Sprintf("plumb recv: unexpected dst: %s\n", m.Dst))
 			continue
 		}
 		// TODO use m.Dir
 		data := string(m.Data)
-		var project, what string
-		if strings.HasPrefix(data, "/issue/") {
-			project = data[len("/issue/"):]
-			i := strings.LastIndex(project, "/")
-			if i < 0 {
-				w.Err(fmt.Sprintf("plumb recv: bad text %q", data))
-				continue
-			}
-			project, what = project[:i], project[i+1:]
-		} else {
-			i := strings.Index(data, "#")
-			if i < 0 {
-				w.Err(fmt.Sprintf("plumb recv: bad text %q", data))
-				continue
-			}
-			project, what = data[:i], data[i+1:]
-		}
-		if strings.Count(project, "/") != 1 {
-			w.Err(fmt.Sprintf("plumb recv: bad text %q", data))
+		project, what, ok := parsePlumbRef(data)
+		if !ok {
+			errorf(fmt.Sprintf("plumb recv: bad text %q", data))
 			continue
 		}
-		var plummy awin
-		plummy.prefix = "/issue/" + project + "/"
-		if !plummy.Look(what) {
-			w.Err(fmt.Sprintf("plumb recv: can't look %s%s", plummy.prefix, what))
+		if !look(project, what) {
+			errorf(fmt.Sprintf("plumb recv: can't look /issue/%s/%s", project, what))
 		}
 	}
 }
 
+// plumbURLRE matches the URL of a GitHub issue or pull request.
+var plumbURLRE = regexp.MustCompile(`\Ahttps?://github\.com/([A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+)/(?:issues|pull)/([0-9]+)\z`)
+
+// parsePlumbRef parses the text of a plumbed message, returning
+// the project and the text to look for in that project's windows.
+// The accepted forms are a window name like /issue/golang/go/1234,
+// a reference like golang/go#1234 or golang/go#all, a GitHub URL,
+// and a bare #1234, which refers to the -p project.
+func parsePlumbRef(data string) (proj, what string, ok bool) {
+	if m := plumbURLRE.FindStringSubmatch(data); m != nil {
+		return m[1], m[2], true
+	}
+	if strings.HasPrefix(data, "/issue/") {
+		proj = data[len("/issue/"):]
+		i := strings.LastIndex(proj, "/")
+		if i < 0 {
+			return "", "", false
+		}
+		proj, what = proj[:i], proj[i+1:]
+	} else {
+		i := strings.Index(data, "#")
+		if i < 0 {
+			return "", "", false
+		}
+		proj, what = data[:i], data[i+1:]
+		if proj == "" {
+			proj = *project
+		}
+	}
+	if strings.Count(proj, "/") != 1 {
+		return "", "", false
+	}
+	return proj, what, true
+}
+
+// plumbingRules are plumbing rules that send GitHub issue references
+// to a running issue -a, or start one to open the issue.
+const plumbingRules = `# GitHub issue references, like golang/go#1234 or
+# https://github.com/golang/go/issues/1234, open in issue -a.
+type is text
+data matches 'https?://github\.com/([A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+)/(issues|pull)/([0-9]+)'
+data set $1#$3
+plumb to githubissue
+plumb start issue -a -p $1 $3
+
+type is text
+data matches '([A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+)#([0-9]+)'
+plumb to githubissue
+plumb start issue -a -p $1 $2
+`
+
+func runPlumbing(project string, args []string) {
+	c := lookupCommand("plumbing")
+	fs := c.flags()
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+	}
+	fmt.Print(plumbingRules)
+}
+
 const (
 	modeSingle = 1 + iota
 	modeQuery
 	modeCreate
 	modeMilestone
 	modeBulk
+	modeHistory
+	modeBoth
 )
 
 type awin struct {
@@ -131,6 +187,7 @@ type awin struct {
 	query        string
 	id           int
 	github       *github.Issue
+	shown        map[int]time.Time // in a bulk edit window, update times of issues
 	title        string
 	sortByNumber bool // otherwise sort by title
 }
@@ -147,7 +204,7 @@ func (w *awin) exit() {
 		delete(all.m, w.Win)
 	}
 	if len(all.m) == 0 {
-		os.Exit(0)
+		exit(0)
 	}
 }
 
@@ -166,7 +223,7 @@ func (w *awin) new(prefix, title string) *awin {
 		time.Sleep(10 * time.Millisecond)
 		w1.Win, err = acme.New()
 		if err != nil {
-			log.Fatalf("creating acme window again: %v", err)
+			fatalf("creating acme window again: %v", err)
 		}
 	}
 	w1.prefix = prefix
@@ -181,7 +238,7 @@ func (w *awin) show(title string) bool {
 }
 
 var numRE = regexp.MustCompile(`(?m)^#[0-9]+\t`)
-var repoHashRE = regexp.MustCompile(`\A([A-Za-z0-9_]+/[A-Za-z0-9_]+)#(all|[0-9]+)\z`)
+var repoHashRE = regexp.MustCompile(`\A([A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+)#(all|[0-9]+)\z`)
 
 var milecache struct {
 	sync.Mutex
@@ -289,30 +346,35 @@ func (w *awin) setMilestone(milestone, text string) {
 	stop := w.Blink()
 	defer stop()
 	if w.mode == modeSingle {
-		w.setMilestone1(milestoneID, w.id)
+		w.setMilestone1(milestone, milestoneID, w.id)
 		w.load()
 		return
 	}
 	if n, _ := strconv.Atoi(strings.TrimPrefix(text, "#")); 0 < n && n < 100000 {
-		w.setMilestone1(milestoneID, n)
+		w.setMilestone1(milestone, milestoneID, n)
 		return
 	}
 	if m := numRE.FindAllString(text, -1); m != nil {
 		for _, s := range m {
 			n, _ := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(s, "#")))
 			if 0 < n && n < 100000 {
-				w.setMilestone1(milestoneID, n)
+				w.setMilestone1(milestone, milestoneID, n)
 			}
 		}
 		return
 	}
 }
 
-func (w *awin) setMilestone1(milestoneID, n int) {
+func (w *awin) setMilestone1(milestone string, milestoneID, n int) {
 	var edit github.IssueRequest
 	edit.Milestone = &milestoneID
 
-	_, _, err := client.Issues.Edit(context.TODO(), projectOwner(w.project()), projectRepo(w.project()), n, &edit)
+	err := putIssue(w.project(), n, &hookChange{Milestone: &milestone}, func() ([]string, error) {
+		if _, _, err := client.Issues.Edit(context.TODO(), projectOwner(w.project()), projectRepo(w.project()), n, &edit); err != nil {
+			return nil, err
+		}
+		return []string{"set milestone " + milestone}, nil
+	})
 	if err != nil {
 		w.Err(fmt.Sprintf("Error changing issue #%d: %v", n, err))
 	}
@@ -332,7 +394,27 @@ func (w *awin) newIssue(prefix, title string, id int) {
 	w.mode = modeSingle
 	w.id = id
 	w.Ctl("cleartag")
-	w.Fprintf("tag", " Get Put Look ")
+	w.Fprintf("tag", " Get Put Look Task History Both Expand Reply Hide ")
+	go w.load()
+	go w.loop()
+}
+
+func (w *awin) newHistory() {
+	w = w.new(w.prefix, fmt.Sprintf("%d/history", w.id))
+	w.mode = modeHistory
+	w.Ctl("cleartag")
+	w.Fprintf("tag", " Get Look ")
+	w.Write("body", []byte("Loading..."))
+	go w.load()
+	go w.loop()
+}
+
+func (w *awin) newBoth() {
+	w = w.new(w.prefix, fmt.Sprintf("%d/both", w.id))
+	w.mode = modeBoth
+	w.Ctl("cleartag")
+	w.Fprintf("tag", " Get Look ")
+	w.Write("body", []byte("Loading..."))
 	go w.load()
 	go w.loop()
 }
@@ -389,7 +471,7 @@ func (w *awin) load() {
 	case modeSingle:
 		var buf bytes.Buffer
 		stop := w.Blink()
-		issue, err := showIssue(&buf, w.project(), w.id)
+		issue, err := showIssue(&buf, w.project(), w.id, true)
 		stop()
 		w.Clear()
 		if err != nil {
@@ -400,6 +482,24 @@ func (w *awin) load() {
 		w.Ctl("clean")
 		w.github = issue
 
+	case modeHistory, modeBoth:
+		var buf bytes.Buffer
+		stop := w.Blink()
+		var err error
+		if w.mode == modeHistory {
+			err = showHistory(&buf, w.project(), w.id)
+		} else {
+			err = showBoth(&buf, w.project(), w.id)
+		}
+		stop()
+		w.Clear()
+		if err != nil {
+			w.Write("body", []byte(err.Error()))
+			break
+		}
+		w.Write("body", buf.Bytes())
+		w.Ctl("clean")
+
 	case modeMilestone:
 		stop := w.Blink()
 		milestones, err := loadMilestones(w.project())
@@ -425,7 +525,7 @@ func (w *awin) load() {
 	case modeQuery:
 		var buf bytes.Buffer
 		stop := w.Blink()
-		err := showQuery(&buf, w.project(), w.query)
+		_, err := showQuery(&buf, w.project(), w.query)
 		if w.title == "all" {
 			cachedMilestones(w.project())
 		}
@@ -458,7 +558,7 @@ func (w *awin) load() {
 			stop()
 			break
 		}
-		base, original, err := bulkEditStartFromText(w.project(), body)
+		base, original, shown, err := bulkEditStartFromText(w.project(), body)
 		stop()
 		if err != nil {
 			w.Err(fmt.Sprintf("%v", err))
@@ -468,6 +568,7 @@ func (w *awin) load() {
 		w.PrintTabbed(string(original))
 		w.Ctl("clean")
 		w.github = base
+		w.shown = shown
 	}
 
 	w.Addr("0")
@@ -518,7 +619,12 @@ func (w *awin) put() {
 			w.Err(fmt.Sprintf("Put: %v", err))
 			return
 		}
-		ids, err := bulkWriteIssue(w.project(), w.github, data, func(s string) { w.Err("Put: " + s) })
+		ids, results, err := bulkWriteIssue(w.project(), w.github, data, w.shown, func(s string) { w.Err("Put: " + s) }, nil)
+		var buf bytes.Buffer
+		printBulkResults(&buf, results)
+		if buf.Len() > 0 {
+			w.Err(strings.TrimSuffix(buf.String(), "\n"))
+		}
 		if err != nil {
 			errText := strings.Replace(err.Error(), "\n", "\t\n", -1)
 			if len(ids) > 0 {
@@ -533,6 +639,12 @@ func (w *awin) put() {
 	case modeMilestone:
 		w.Err("cannot Put milestone list")
 
+	case modeHistory:
+		w.Err("cannot Put edit history")
+
+	case modeBoth:
+		w.Err("cannot Put side-by-side view")
+
 	case modeQuery:
 		w.Err("cannot Put issue list")
 	}
@@ -597,6 +709,60 @@ func (w *awin) Execute(cmd string) bool {
 		w.sortByNumber = !w.sortByNumber
 		w.sort()
 		return true
+	case "Task":
+		if w.mode != modeSingle {
+			w.Err("can only update tasks in issue windows")
+			return true
+		}
+		w.toggleTask()
+		return true
+	case "History":
+		if w.mode != modeSingle {
+			w.Err("can only show history of issue windows")
+			return true
+		}
+		if w.show(fmt.Sprintf("%d/history", w.id)) {
+			return true
+		}
+		w.newHistory()
+		return true
+	case "Both":
+		if w.mode != modeSingle {
+			w.Err("can only show raw text of issue windows")
+			return true
+		}
+		if w.show(fmt.Sprintf("%d/both", w.id)) {
+			return true
+		}
+		w.newBoth()
+		return true
+	case "Expand":
+		if w.mode != modeSingle {
+			w.Err("can only expand comments in issue windows")
+			return true
+		}
+		toggleExpanded(w.project(), w.id)
+		w.load()
+		return true
+	case "Reply":
+		if w.mode != modeSingle {
+			w.Err("can only reply to comments in issue windows")
+			return true
+		}
+		id, ok := parseCommentID(w.selectedLine())
+		if !ok {
+			w.Err("Reply: select a comment's header line or ID")
+			return true
+		}
+		stop := w.Blink()
+		draft, err := replyDraft(w.project(), w.id, id)
+		stop()
+		if err != nil {
+			w.Err(err.Error())
+			return true
+		}
+		w.insertReply(draft)
+		return true
 	case "Bulk":
 		// TODO(rsc): If Bulk has an argument, treat as search query and use results?
 		if w.mode != modeQuery {
@@ -620,6 +786,30 @@ func (w *awin) Execute(cmd string) bool {
 		w.newSearch(w.prefix, "search", strings.TrimSpace(strings.TrimPrefix(cmd, "Search")))
 		return true
 	}
+	if cmd == "Hide" || strings.HasPrefix(cmd, "Hide ") {
+		if w.mode != modeSingle {
+			w.Err("can only hide comments in issue windows")
+			return true
+		}
+		reason := strings.TrimSpace(strings.TrimPrefix(cmd, "Hide"))
+		if reason == "" {
+			reason = "off-topic"
+		}
+		id, ok := parseCommentID(w.selectedLine())
+		if !ok {
+			w.Err("Hide: select a comment's header line or ID")
+			return true
+		}
+		stop := w.Blink()
+		err := hideComment(w.project(), id, reason)
+		stop()
+		if err != nil {
+			w.Err(err.Error())
+			return true
+		}
+		w.load()
+		return true
+	}
 	if strings.HasPrefix(cmd, "Milestone ") {
 		text := w.Selection()
 		w.setMilestone(strings.TrimSpace(strings.TrimPrefix(cmd, "Milestone")), text)
@@ -629,6 +819,62 @@ func (w *awin) Execute(cmd string) bool {
 	return false
 }
 
+// selectedLine returns the selected text,
+// or the line containing the cursor if the selection is empty.
+func (w *awin) selectedLine() string {
+	text := w.Selection()
+	if text == "" {
+		w.Ctl("addr=dot")
+		w.Addr("-+")
+		data, err := w.ReadAll("xdata")
+		if err != nil {
+			w.Err(err.Error())
+		}
+		text = string(data)
+	}
+	return text
+}
+
+// insertReply adds the reply draft to the issue window
+// where a new comment is written, leaving the cursor after it.
+func (w *awin) insertReply(draft string) {
+	body, err := w.ReadAll("body")
+	if err != nil {
+		w.Err(err.Error())
+		return
+	}
+	q := utf8.RuneCount(body)
+	if i := bytes.Index(body, []byte("\nReported by ")); i >= 0 {
+		q = utf8.RuneCount(body[:i+1])
+	}
+	w.Addr("#%d", q)
+	w.Write("data", []byte(draft+"\n"))
+	// Leave the cursor on the blank line after the quotation.
+	w.Addr("#%d", q+utf8.RuneCountInString(draft)-1)
+	w.Ctl("dot=addr")
+	w.Ctl("show")
+}
+
+func (w *awin) toggleTask() {
+	if w.github == nil {
+		w.Err("issue not loaded")
+		return
+	}
+	t, err := findTaskLine(getString(w.github.Body), w.selectedLine())
+	if err != nil {
+		w.Err(err.Error())
+		return
+	}
+	stop := w.Blink()
+	_, err = updateTask(w.project(), w.github, t, "toggle")
+	stop()
+	if err != nil {
+		w.Err(err.Error())
+		return
+	}
+	w.load()
+}
+
 func (w *awin) loop() {
 	defer w.exit()
 	w.EventLoop(w)
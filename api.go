@@ -0,0 +1,259 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// The github package lags the GitHub API. These helpers make
+// requests directly, for fields and endpoints it does not yet support.
+
+// apiURLs returns the base and upload URLs of the GitHub API, from the
+// -api and -upload-api flags or else the api and upload-api settings,
+// or empty strings for github.com. Without an upload URL, uploads go
+// to the API host.
+func apiURLs() (base, upload string) {
+	c := loadConfig()
+	base, upload = *apiFlag, *uploadFlag
+	if base == "" {
+		base = c.API
+	}
+	if upload == "" {
+		upload = c.UploadAPI
+	}
+	if upload == "" {
+		upload = base
+	}
+	return base, upload
+}
+
+// apiHost returns the host name of the GitHub API,
+// for looking up credentials.
+func apiHost() string {
+	base, _ := apiURLs()
+	if u, err := url.Parse(base); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return "api.github.com"
+}
+
+// newClient returns a client for the GitHub API that sends its
+// requests using httpClient. For a GitHub Enterprise Server host,
+// such as https://github.example.com, the API paths /api/v3/ and
+// /api/uploads/ are added to the URLs unless already present.
+//
+// With only an upload URL, the client uses api.github.com
+// and sends uploads to the upload URL as given.
+func newClient(httpClient *http.Client) (*github.Client, error) {
+	base, upload := apiURLs()
+	if base != "" {
+		return github.NewEnterpriseClient(base, upload, httpClient)
+	}
+	c := github.NewClient(httpClient)
+	if upload != "" {
+		u, err := url.Parse(upload)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasSuffix(u.Path, "/") {
+			u.Path += "/"
+		}
+		c.UploadURL = u
+	}
+	return c, nil
+}
+
+// webURL returns the URL of the GitHub web site, ending in a slash:
+// https://github.com/, or the GitHub Enterprise Server host
+// given by -api or the api setting.
+func webURL() string {
+	base, _ := apiURLs()
+	u, err := url.Parse(base)
+	if base == "" || err != nil || u.Host == "" {
+		return "https://github.com/"
+	}
+	host := u.Host
+	if host == "api.github.com" {
+		host = "github.com"
+	}
+	return u.Scheme + "://" + host + "/"
+}
+
+// getJSON fetches the API path u, relative to the API base URL,
+// and decodes the JSON response into v.
+func getJSON(u string, v interface{}) (*github.Response, error) {
+	req, err := client.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(context.TODO(), req, v)
+}
+
+// issueExtra holds the fields of an issue that the github package omits.
+type issueExtra struct {
+	StateReason string     `json:"state_reason"`
+	Type        *issueType `json:"type"`
+}
+
+// An issueType is one of the issue types defined by an organization,
+// such as Bug or Feature.
+type issueType struct {
+	Name string `json:"name"`
+}
+
+func (x *issueExtra) typeName() string {
+	if x.Type == nil {
+		return ""
+	}
+	return x.Type.Name
+}
+
+// loadIssueExtra fetches the fields of issue n that the github package omits.
+func loadIssueExtra(project string, n int) (*issueExtra, error) {
+	x := new(issueExtra)
+	_, err := getJSON(fmt.Sprintf("repos/%s/%s/issues/%d", projectOwner(project), projectRepo(project), n), x)
+	if err != nil {
+		return nil, err
+	}
+	cacheIssueType(project, n, x.typeName())
+	return x, nil
+}
+
+// getIssue fetches issue n, like client.Issues.Get,
+// also recording the issue's type for getIssueType.
+func getIssue(project string, n int) (*github.Issue, error) {
+	var x struct {
+		github.Issue
+		issueExtra
+	}
+	_, err := getJSON(fmt.Sprintf("repos/%s/%s/issues/%d", projectOwner(project), projectRepo(project), n), &x)
+	if err != nil {
+		return nil, err
+	}
+	cacheIssueType(project, n, x.typeName())
+	return &x.Issue, nil
+}
+
+// issueTypes holds the issue types recorded by getIssue and loadIssueExtra.
+var issueTypes struct {
+	sync.Mutex
+	m map[projectAndNumber]string
+}
+
+func cacheIssueType(project string, n int, name string) {
+	issueTypes.Lock()
+	defer issueTypes.Unlock()
+	if issueTypes.m == nil {
+		issueTypes.m = make(map[projectAndNumber]string)
+	}
+	issueTypes.m[projectAndNumber{project, n}] = name
+}
+
+// getIssueType returns the name of the issue's type,
+// or the empty string if it has none or the type cannot be loaded.
+func getIssueType(project string, issue *github.Issue) string {
+	n := getInt(issue.Number)
+	issueTypes.Lock()
+	name, ok := issueTypes.m[projectAndNumber{project, n}]
+	issueTypes.Unlock()
+	if ok {
+		return name
+	}
+	x, err := loadIssueExtra(project, n)
+	if err != nil {
+		return ""
+	}
+	return x.typeName()
+}
+
+// setIssueType sets the type of issue n to the named type,
+// or clears it if name is empty.
+func setIssueType(project string, n int, name string) error {
+	var typ interface{}
+	if name != "" {
+		typ = name
+	}
+	req, err := client.NewRequest("PATCH", fmt.Sprintf("repos/%s/%s/issues/%d", projectOwner(project), projectRepo(project), n), map[string]interface{}{"type": typ})
+	if err != nil {
+		return err
+	}
+	if _, err := client.Do(context.TODO(), req, nil); err != nil {
+		return err
+	}
+	cacheIssueType(project, n, name)
+	return nil
+}
+
+// closeIssue closes issue n with the given state reason,
+// "completed" or "not_planned", which the github package cannot set.
+func closeIssue(project string, n int, reason string) error {
+	req, err := client.NewRequest("PATCH", fmt.Sprintf("repos/%s/%s/issues/%d", projectOwner(project), projectRepo(project), n), map[string]interface{}{
+		"state":        "closed",
+		"state_reason": reason,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = client.Do(context.TODO(), req, nil)
+	return err
+}
+
+// loadIssueTypeNames returns the names of the issue types
+// enabled for the organization owning project, if any.
+func loadIssueTypeNames(project string) ([]string, error) {
+	var types []issueType
+	resp, err := getJSON(fmt.Sprintf("orgs/%s/issue-types", projectOwner(project)), &types)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, nil // not an organization
+		}
+		return nil, err
+	}
+	var names []string
+	for _, t := range types {
+		names = append(names, t.Name)
+	}
+	return names, nil
+}
+
+// graphQL runs the GraphQL query with the given variables
+// and decodes the data in the response into v.
+func graphQL(query string, vars map[string]interface{}, v interface{}) error {
+	// GitHub Enterprise Server serves GraphQL at /api/graphql,
+	// not under the REST API's /api/v3/.
+	endpoint := "graphql"
+	if strings.HasSuffix(client.BaseURL.Path, "/api/v3/") {
+		endpoint = "../graphql"
+	}
+	req, err := client.NewRequest("POST", endpoint, map[string]interface{}{
+		"query":     query,
+		"variables": vars,
+	})
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Data   json.RawMessage
+		Errors []struct {
+			Message string
+		}
+	}
+	if _, err := client.Do(context.TODO(), req, &resp); err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		return fmt.Errorf("graphql: %s", resp.Errors[0].Message)
+	}
+	return json.Unmarshal(resp.Data, v)
+}
@@ -0,0 +1,246 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v45/github"
+)
+
+var self struct {
+	sync.Once
+	login string
+	err   error
+}
+
+// selfLogin returns the login of the authenticated user.
+func selfLogin() (string, error) {
+	self.Do(func() {
+		u, _, err := client.Users.Get(context.TODO(), "")
+		self.login, self.err = getUserLogin(u), err
+	})
+	return self.login, self.err
+}
+
+// resolveLogins returns logins with any "@me" replaced
+// by the authenticated user's login.
+func resolveLogins(logins []string) ([]string, error) {
+	var out []string
+	for _, login := range logins {
+		if login == "@me" {
+			me, err := selfLogin()
+			if err != nil {
+				return nil, err
+			}
+			login = me
+		}
+		out = append(out, login)
+	}
+	return out, nil
+}
+
+// resolveAssignees is like resolveLogins but also replaces any team,
+// written "@org/team", by the member chosen by teamAssignee.
+func resolveAssignees(project string, logins []string) ([]string, error) {
+	logins, err := resolveLogins(logins)
+	if err != nil {
+		return nil, err
+	}
+	for i, login := range logins {
+		if isTeam(login) {
+			if logins[i], err = teamAssignee(project, login, canPrompt()); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return logins, nil
+}
+
+// isTeam reports whether login names a team, as in "@golang/release".
+func isTeam(login string) bool {
+	return strings.HasPrefix(login, "@") && strings.Count(login, "/") == 1
+}
+
+// canPrompt reports whether issue can ask the user questions
+// on standard input, which is only so when running commands
+// or editing in a terminal, not in the editor and server modes.
+func canPrompt() bool {
+	return isTerminal(os.Stdin) && isTerminal(os.Stderr) &&
+		!*acmeFlag && !*samFlag && !*stdioFlag && !*batchFlag && *serveFlag == "" && !*tuiFlag
+}
+
+// teamAssigneeLock serializes teamRoster.choose,
+// which records the rotation in the cache.
+var teamAssigneeLock sync.Mutex
+
+// teamAssignee returns the member of team, written "@org/team",
+// to assign an issue in project: the member with the fewest open
+// issues assigned in project, taking turns among members with equally
+// few. If prompt is set, teamAssignee lists the members and their
+// open issues on standard error and lets the user choose a member,
+// defaulting to that one.
+func teamAssignee(project, team string, prompt bool) (string, error) {
+	r, err := loadTeamRoster(project, team)
+	if err != nil {
+		return "", err
+	}
+	return r.choose(prompt)
+}
+
+// A teamRoster lists the members of a team
+// and the open issues assigned to each in a project.
+type teamRoster struct {
+	project string
+	team    string
+	members []string // sorted
+	load    map[string]int
+}
+
+// loadTeamRoster looks up the members of team and their open issues
+// in project, without choosing one.
+func loadTeamRoster(project, team string) (*teamRoster, error) {
+	org, slug, _ := strings.Cut(strings.TrimPrefix(team, "@"), "/")
+	var members []string
+	for page := 1; ; {
+		list, resp, err := client.Teams.ListTeamMembersBySlug(context.TODO(), org, slug, &github.TeamListTeamMembersOptions{
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: 100,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("listing members of %s: %v", team, err)
+		}
+		for _, u := range list {
+			members = append(members, getUserLogin(u))
+		}
+		if resp.NextPage < page {
+			break
+		}
+		page = resp.NextPage
+	}
+	if len(members) == 0 {
+		return nil, fmt.Errorf("team %s has no members", team)
+	}
+	sort.Strings(members)
+
+	load := make(map[string]int)
+	for _, login := range members {
+		x, _, err := client.Search.Issues(context.TODO(), "is:issue is:open repo:"+project+" assignee:"+login, &github.SearchOptions{
+			ListOptions: github.ListOptions{PerPage: 1},
+		})
+		if err != nil {
+			return nil, err
+		}
+		load[login] = x.GetTotal()
+	}
+	return &teamRoster{project, team, members, load}, nil
+}
+
+// choose returns the member to assign, as described for teamAssignee,
+// and records the choice so that the next one takes the next turn.
+func (r *teamRoster) choose(prompt bool) (string, error) {
+	teamAssigneeLock.Lock()
+	defer teamAssigneeLock.Unlock()
+
+	// Take turns: start looking just after the member chosen last time.
+	last := make(map[string]string)
+	readCache(r.project, "team-assignees", anyAge, &last)
+	start := sort.SearchStrings(r.members, last[r.team]+"\x00")
+	pick := ""
+	for i := range r.members {
+		login := r.members[(start+i)%len(r.members)]
+		if pick == "" || r.load[login] < r.load[pick] {
+			pick = login
+		}
+	}
+
+	if prompt {
+		fmt.Fprintf(os.Stderr, "%s members (open issues assigned):\n", r.team)
+		for i, login := range r.members {
+			fmt.Fprintf(os.Stderr, "\t%d. %s (%d)\n", i+1, login, r.load[login])
+		}
+		fmt.Fprintf(os.Stderr, "assign to [%s]: ", pick)
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("no assignee chosen from %s", r.team)
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			if i, err := strconv.Atoi(line); err == nil && 1 <= i && i <= len(r.members) {
+				pick = r.members[i-1]
+			} else {
+				pick = strings.TrimPrefix(line, "@")
+			}
+		}
+	}
+
+	last[r.team] = pick
+	writeCache(r.project, "team-assignees", last)
+	return pick, nil
+}
+
+func runAssign(project string, args []string) {
+	fs := lookupCommand("assign").flags()
+	parseFlags(fs, args)
+	if fs.NArg() < 2 {
+		fs.Usage()
+	}
+	n := issueArgs(fs, fs.Args()[:1])[0]
+	logins, err := resolveAssignees(project, fs.Args()[1:])
+	if err != nil {
+		fatal(err)
+	}
+	err = putIssue(project, n, &hookChange{AddAssignees: logins}, func() ([]string, error) {
+		if _, _, err := client.Issues.AddAssignees(context.TODO(), projectOwner(project), projectRepo(project), n, logins); err != nil {
+			return nil, err
+		}
+		return []string{"assigned " + strings.Join(logins, " ")}, nil
+	})
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func runUnassign(project string, args []string) {
+	fs := lookupCommand("unassign").flags()
+	parseFlags(fs, args)
+	if fs.NArg() < 1 {
+		fs.Usage()
+	}
+	n := issueArgs(fs, fs.Args()[:1])[0]
+	logins, err := resolveLogins(fs.Args()[1:])
+	if err != nil {
+		fatal(err)
+	}
+	if len(logins) == 0 {
+		issue, _, err := client.Issues.Get(context.TODO(), projectOwner(project), projectRepo(project), n)
+		if err != nil {
+			fatal(err)
+		}
+		for _, u := range issue.Assignees {
+			logins = append(logins, getUserLogin(u))
+		}
+		if len(logins) == 0 {
+			return
+		}
+	}
+	err = putIssue(project, n, &hookChange{RemoveAssignees: logins}, func() ([]string, error) {
+		if _, _, err := client.Issues.RemoveAssignees(context.TODO(), projectOwner(project), projectRepo(project), n, logins); err != nil {
+			return nil, err
+		}
+		return []string{"unassigned " + strings.Join(logins, " ")}, nil
+	})
+	if err != nil {
+		fatal(err)
+	}
+}
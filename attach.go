@@ -0,0 +1,160 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// attachmentRE matches the URLs GitHub assigns to files and images
+// uploaded into issue bodies and comments.
+var attachmentRE = regexp.MustCompile(`https://(?:(?:private-)?user-images\.githubusercontent\.com|github\.com/user-attachments/(?:assets|files)|github\.com/[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+/(?:files|assets))/[^\s)"'<>\]]+`)
+
+// attachmentURLs returns the attachment URLs in text, in order of appearance.
+func attachmentURLs(text string) []string {
+	return attachmentRE.FindAllString(text, -1)
+}
+
+// attachmentTransport sends the GitHub token only to host, the host
+// of the attachment URL found in the issue, which attachmentRE limits
+// to GitHub's own. Attachment downloads redirect to presigned storage
+// URLs on other hosts, which need no token and reject requests
+// carrying a second form of authorization.
+type attachmentTransport struct {
+	source *tokenSource
+	host   string
+}
+
+func (t *attachmentTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.URL.Scheme == "https" && r.URL.Host == t.host {
+		r = r.Clone(r.Context())
+		r.Header.Set("Authorization", "token "+t.source.current())
+	}
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// downloadAttachment saves the file at u into dir, returning the file name.
+// The name comes from the URL, with an extension added from the
+// Content-Type if the URL has none. Names already in use are
+// disambiguated with a numeric prefix.
+func downloadAttachment(dir, u string, used map[string]bool) (string, int64, error) {
+	pu, err := url.Parse(u)
+	if err != nil {
+		return "", 0, err
+	}
+	c := &http.Client{Transport: &attachmentTransport{auth, pu.Host}}
+	resp, err := c.Get(u)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("%s: %s", u, resp.Status)
+	}
+
+	name := "attachment"
+	if path.Base(pu.Path) != "/" {
+		name = path.Base(pu.Path)
+	}
+	if path.Ext(name) == "" {
+		if typ, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+			if exts, _ := mime.ExtensionsByType(typ); len(exts) > 0 {
+				name += exts[0]
+			}
+		}
+	}
+	for i, base := 1, name; used[name]; i++ {
+		name = strconv.Itoa(i) + "-" + base
+	}
+	used[name] = true
+
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return "", 0, err
+	}
+	n, err := io.Copy(f, resp.Body)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	return name, n, err
+}
+
+func runAttachments(project string, args []string) {
+	c := lookupCommand("attachments")
+	fs := c.flags()
+	dir := fs.String("o", ".", "write attachments to `dir`")
+	parseFlags(fs, args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+	}
+	n, err := strconv.Atoi(fs.Arg(0))
+	if err != nil || n <= 0 {
+		fs.Usage()
+	}
+
+	issue, _, err := client.Issues.Get(context.TODO(), projectOwner(project), projectRepo(project), n)
+	if err != nil {
+		fatal(err)
+	}
+	urls := attachmentURLs(getString(issue.Body))
+	for page := 1; ; {
+		list, resp, err := client.Issues.ListComments(context.TODO(), projectOwner(project), projectRepo(project), n, &github.IssueListCommentsOptions{
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: 100,
+			},
+		})
+		if err != nil {
+			fatal(err)
+		}
+		for _, com := range list {
+			urls = append(urls, attachmentURLs(getString(com.Body))...)
+		}
+		if resp.NextPage < page {
+			break
+		}
+		page = resp.NextPage
+	}
+	if len(urls) == 0 {
+		log.Printf("#%d has no attachments", n)
+		return
+	}
+
+	if err := os.MkdirAll(*dir, 0777); err != nil {
+		fatal(err)
+	}
+	seen := make(map[string]bool)
+	used := make(map[string]bool)
+	failed := false
+	for _, u := range urls {
+		if seen[u] {
+			continue
+		}
+		seen[u] = true
+		name, size, err := downloadAttachment(*dir, u, used)
+		if err != nil {
+			log.Print(err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s\t%d\t%s\n", filepath.Join(*dir, name), size, u)
+	}
+	if failed {
+		exit(exitError)
+	}
+}
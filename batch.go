@@ -0,0 +1,238 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// splitBatchLine splits a batch command line into words.
+// Words are separated by spaces, and a word beginning with a double quote
+// is a Go-syntax quoted string that may contain spaces.
+func splitBatchLine(line string) ([]string, error) {
+	var words []string
+	for {
+		line = strings.TrimLeft(line, " \t")
+		if line == "" {
+			return words, nil
+		}
+		if line[0] != '"' {
+			i := strings.IndexAny(line, " \t")
+			if i < 0 {
+				i = len(line)
+			}
+			words = append(words, line[:i])
+			line = line[i:]
+			continue
+		}
+		q, err := strconv.QuotedPrefix(line)
+		if err != nil {
+			return nil, fmt.Errorf("bad quoted string: %s", line)
+		}
+		s, _ := strconv.Unquote(q)
+		words = append(words, s)
+		line = line[len(q):]
+	}
+}
+
+// runBatchOp runs a single batch operation, returning the rate limit
+// reported by its last API call.
+func runBatchOp(project string, words []string) (*github.Rate, error) {
+	if len(words) < 2 {
+		return nil, fmt.Errorf("missing issue number")
+	}
+	op := words[0]
+	n, err := strconv.Atoi(strings.TrimPrefix(words[1], "#"))
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("invalid issue number %q", words[1])
+	}
+	args := words[2:]
+	owner, repo := projectOwner(project), projectRepo(project)
+	var rate *github.Rate
+	setRate := func(resp *github.Response) {
+		if resp != nil {
+			rate = &resp.Rate
+		}
+	}
+
+	// Check the arguments and describe the change for the hooks,
+	// and then make it.
+	change := new(hookChange)
+	var apply func() ([]string, error)
+	switch op {
+	default:
+		return nil, fmt.Errorf("unknown operation %q", op)
+
+	case "comment":
+		if len(args) == 0 {
+			return nil, fmt.Errorf("missing comment text")
+		}
+		change.Comment = strings.Join(args, " ")
+		apply = func() ([]string, error) {
+			body, err := gistBody(fmt.Sprintf("Attachment for %s#%d", project, n), change.Comment)
+			if err != nil {
+				return nil, err
+			}
+			_, resp, err := client.Issues.CreateComment(context.TODO(), owner, repo, n, &github.IssueComment{Body: &body})
+			setRate(resp)
+			if err != nil {
+				return nil, err
+			}
+			return []string{"saved comment"}, nil
+		}
+
+	case "close", "reopen":
+		if len(args) != 0 {
+			return nil, fmt.Errorf("unexpected arguments")
+		}
+		state := "closed"
+		if op == "reopen" {
+			state = "open"
+		}
+		change.NewState = &state
+		apply = func() ([]string, error) {
+			_, resp, err := client.Issues.Edit(context.TODO(), owner, repo, n, &github.IssueRequest{State: &state})
+			setRate(resp)
+			if err != nil {
+				return nil, err
+			}
+			return []string{op + "d"}, nil
+		}
+
+	case "label":
+		if len(args) == 0 {
+			return nil, fmt.Errorf("missing labels")
+		}
+		for _, a := range args {
+			switch {
+			case strings.HasPrefix(a, "+") && len(a) > 1:
+				change.AddLabels = append(change.AddLabels, a[1:])
+			case strings.HasPrefix(a, "-") && len(a) > 1:
+				change.RemoveLabels = append(change.RemoveLabels, a[1:])
+			default:
+				return nil, fmt.Errorf("label %q must begin with + or -", a)
+			}
+		}
+		apply = func() ([]string, error) {
+			var did []string
+			if add := change.AddLabels; len(add) > 0 {
+				_, resp, err := client.Issues.AddLabelsToIssue(context.TODO(), owner, repo, n, add)
+				setRate(resp)
+				if err != nil {
+					return did, err
+				}
+				did = append(did, "added label"+suffix(len(add))+" "+strings.Join(add, " "))
+			}
+			for _, lab := range change.RemoveLabels {
+				resp, err := client.Issues.RemoveLabelForIssue(context.TODO(), owner, repo, n, lab)
+				setRate(resp)
+				if err != nil {
+					return did, fmt.Errorf("removing label %s: %v", lab, err)
+				}
+				did = append(did, "removed label "+lab)
+			}
+			return did, nil
+		}
+
+	case "milestone":
+		if len(args) == 0 {
+			return nil, fmt.Errorf("missing milestone")
+		}
+		name := strings.Join(args, " ")
+		if name == "none" {
+			change.Milestone = new(string)
+			apply = func() ([]string, error) {
+				_, resp, err := client.Issues.RemoveMilestone(context.TODO(), owner, repo, n)
+				setRate(resp)
+				if err != nil {
+					return nil, err
+				}
+				return []string{"removed milestone"}, nil
+			}
+			break
+		}
+		var errbuf strings.Builder
+		id := findMilestone(&errbuf, project, &name)
+		if id == nil {
+			return nil, fmt.Errorf("%s", strings.TrimSpace(errbuf.String()))
+		}
+		change.Milestone = &name
+		apply = func() ([]string, error) {
+			_, resp, err := client.Issues.Edit(context.TODO(), owner, repo, n, &github.IssueRequest{Milestone: id})
+			setRate(resp)
+			if err != nil {
+				return nil, err
+			}
+			return []string{"set milestone " + name}, nil
+		}
+
+	case "retitle":
+		title := strings.TrimSpace(strings.Join(args, " "))
+		if title == "" {
+			return nil, fmt.Errorf("missing title")
+		}
+		change.Title = &title
+		apply = func() ([]string, error) {
+			_, resp, err := client.Issues.Edit(context.TODO(), owner, repo, n, &github.IssueRequest{Title: &title})
+			setRate(resp)
+			if err != nil {
+				return nil, err
+			}
+			return []string{"updated title"}, nil
+		}
+	}
+	err = putIssue(project, n, change, apply)
+	return rate, err
+}
+
+// runBatch executes the batch commands read from r, one per line,
+// and reports the results, exiting with status 1 if any failed.
+func runBatch(project string, r io.Reader) {
+	status := func(s string) { log.Print(s) }
+	var rate *github.Rate
+	var ok, failed int
+	var errbuf strings.Builder
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rate = waitRateLimit(rate, fmt.Sprintf("completed %d operations", ok+failed), status)
+		words, err := splitBatchLine(line)
+		if err == nil {
+			var r *github.Rate
+			r, err = runBatchOp(project, words)
+			if r != nil {
+				rate = r
+			}
+		}
+		if err != nil {
+			failed++
+			fmt.Fprintf(&errbuf, "line %d: %s: %v\n", lineno, line, err)
+			continue
+		}
+		ok++
+	}
+	if err := scanner.Err(); err != nil {
+		fatalf("reading batch commands: %v", err)
+	}
+
+	fmt.Fprint(os.Stderr, errbuf.String())
+	log.Printf("%d operation%s succeeded, %d failed", ok, suffix(ok), failed)
+	if failed > 0 {
+		exit(exitError)
+	}
+}
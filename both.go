@@ -0,0 +1,78 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// The -both flag and the acme Both command show the raw markdown of
+// an issue's body and comments beside the wrapped text that issue
+// prints, which helps when editing other people's formatting.
+
+// bothRawWidth is the width of the raw markdown column.
+const bothRawWidth = 60
+
+// sideBySide returns text laid out in two columns, each line indented
+// by a tab: the raw text on the left, broken at bothRawWidth characters,
+// and the text as wrap wraps it on the right.
+func sideBySide(text string) string {
+	expand := func(s string) []string {
+		s = strings.Replace(s, "\r\n", "\n", -1)
+		return strings.Split(strings.Replace(s, "\t", "    ", -1), "\n")
+	}
+	left := wrapLines(expand(text), bothRawWidth)
+	right := expand(wrap(text, ""))
+	var b strings.Builder
+	for i := 0; i < len(left) || i < len(right); i++ {
+		l, r := "", ""
+		if i < len(left) {
+			l = left[i]
+		}
+		if i < len(right) {
+			r = right[i]
+		}
+		b.WriteString(strings.TrimRight("\t"+fit(l, bothRawWidth)+" │ "+r, " "))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// showBoth prints the body and comments of issue n, each with its
+// raw markdown beside the wrapped text.
+func showBoth(w io.Writer, project string, n int) error {
+	issue, err := getIssue(project, n)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Title: %s\n", getString(issue.Title))
+	fmt.Fprintf(w, "\nReported by %s (%s)\n", authorName(issue.User, issue.AuthorAssociation), formatTime(getTime(issue.CreatedAt)))
+	printText(w, issue.Body, true)
+	for page := 1; ; {
+		list, resp, err := client.Issues.ListComments(context.TODO(), projectOwner(project), projectRepo(project), n, &github.IssueListCommentsOptions{
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: 100,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		for _, com := range list {
+			fmt.Fprintf(w, "\n%s\n", commentHeader(com))
+			printText(w, com.Body, true)
+		}
+		if resp.NextPage < page {
+			break
+		}
+		page = resp.NextPage
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// Branches can be linked to an issue in the Development section of
+// its GitHub page. The REST API does not report them, so they are
+// loaded using GraphQL.
+
+const linkedBranchesQuery = `
+query($owner: String!, $repo: String!, $number: Int!) {
+  repository(owner: $owner, name: $repo) {
+    issue(number: $number) {
+      linkedBranches(first: 100) {
+        nodes { ref { name repository { nameWithOwner } } }
+      }
+    }
+  }
+}`
+
+// A linkedBranch is a branch linked to an issue.
+type linkedBranch struct {
+	Repo string // owner/repo
+	Name string
+}
+
+// findLinkedBranches returns the branches linked to issue n.
+func findLinkedBranches(project string, n int) ([]*linkedBranch, error) {
+	var data struct {
+		Repository struct {
+			Issue *struct {
+				LinkedBranches struct {
+					Nodes []struct {
+						Ref *struct {
+							Name       string
+							Repository struct{ NameWithOwner string }
+						}
+					}
+				}
+			}
+		}
+	}
+	vars := map[string]interface{}{
+		"owner":  projectOwner(project),
+		"repo":   projectRepo(project),
+		"number": n,
+	}
+	if err := graphQL(linkedBranchesQuery, vars, &data); err != nil {
+		return nil, err
+	}
+	issue := data.Repository.Issue
+	if issue == nil {
+		return nil, fmt.Errorf("issue %s#%d not found", project, n)
+	}
+	var list []*linkedBranch
+	for _, b := range issue.LinkedBranches.Nodes {
+		if b.Ref != nil { // deleted branches have no ref
+			list = append(list, &linkedBranch{b.Ref.Repository.NameWithOwner, b.Ref.Name})
+		}
+	}
+	return list, nil
+}
+
+// printLinkedBranches prints a Branch: header line for each branch
+// linked to the issue, naming the branch's repository if it is not
+// the project. Like the PR: lines, the lines are informational only,
+// so a failed lookup is reported in the header.
+func printLinkedBranches(w io.Writer, project string, issue *github.Issue) {
+	branches, err := findLinkedBranches(project, getInt(issue.Number))
+	for _, b := range branches {
+		if b.Repo == project {
+			fmt.Fprintf(w, "Branch: %s\n", b.Name)
+		} else {
+			fmt.Fprintf(w, "Branch: %s in %s\n", b.Name, b.Repo)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(w, "Branch: error finding linked branches: %v\n", err)
+	}
+}
+
+// branchName returns the name of a working branch for issue n:
+// the number followed by the title's words, lower-cased and
+// joined by hyphens, stopping before the name grows past 50 bytes.
+func branchName(n int, title string) string {
+	name := fmt.Sprint(n)
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) || r > unicode.MaxASCII
+	})
+	for _, w := range words {
+		if len(name)+1+len(w) > 50 {
+			break
+		}
+		name += "-" + w
+	}
+	return name
+}
+
+const linkBranchQuery = `
+query($owner: String!, $repo: String!, $number: Int!, $base: String!, $useBase: Boolean!) {
+  repository(owner: $owner, name: $repo) {
+    id
+    issue(number: $number) { id }
+    defaultBranchRef @skip(if: $useBase) { target { oid } }
+    ref(qualifiedName: $base) @include(if: $useBase) { target { oid } }
+  }
+}`
+
+const createLinkedBranchMutation = `
+mutation($issue: ID!, $repo: ID!, $oid: GitObjectID!, $name: String!) {
+  createLinkedBranch(input: {issueId: $issue, repositoryId: $repo, oid: $oid, name: $name}) {
+    linkedBranch { id }
+  }
+}`
+
+// createLinkedBranch creates the named branch in project, starting at
+// the head of the base branch, or of the default branch if base is empty,
+// and links it to issue n in the issue's Development section.
+func createLinkedBranch(project string, n int, name, base string) error {
+	type target struct{ Target struct{ Oid string } }
+	var data struct {
+		Repository struct {
+			ID               string
+			Issue            *struct{ ID string }
+			DefaultBranchRef *target
+			Ref              *target
+		}
+	}
+	vars := map[string]interface{}{
+		"owner":   projectOwner(project),
+		"repo":    projectRepo(project),
+		"number":  n,
+		"base":    base,
+		"useBase": base != "",
+	}
+	if err := graphQL(linkBranchQuery, vars, &data); err != nil {
+		return err
+	}
+	repo := data.Repository
+	if repo.Issue == nil {
+		return fmt.Errorf("issue %s#%d not found", project, n)
+	}
+	ref := repo.DefaultBranchRef
+	if base != "" {
+		ref = repo.Ref
+	}
+	if ref == nil {
+		return fmt.Errorf("branch %q not found in %s", base, project)
+	}
+	vars = map[string]interface{}{
+		"issue": repo.Issue.ID,
+		"repo":  repo.ID,
+		"oid":   ref.Target.Oid,
+		"name":  name,
+	}
+	var created struct{}
+	return graphQL(createLinkedBranchMutation, vars, &created)
+}
+
+// git runs git with the given arguments in the current directory,
+// passing its output through to standard output and standard error.
+func git(args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git %s: %v", strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+func runBranch(project string, args []string) {
+	fs := lookupCommand("branch").flags()
+	base := fs.String("base", "", "start the branch at `ref` instead of the current commit, or with -link, the default branch")
+	link := fs.Bool("link", false, "create the branch on GitHub, linked to the issue, and check out a local branch tracking it")
+	remote := fs.String("remote", "origin", "with -link, fetch the new branch from the git `remote`")
+	name := fs.String("name", "", "name the branch `name` instead of deriving it from the issue")
+	parseFlags(fs, args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+	}
+	n := issueArgs(fs, fs.Args())[0]
+	issue, err := getIssue(project, n)
+	if err != nil {
+		fatal(err)
+	}
+	if *name == "" {
+		*name = branchName(n, getString(issue.Title))
+	}
+
+	if !*link {
+		gitArgs := []string{"checkout", "-b", *name}
+		if *base != "" {
+			gitArgs = append(gitArgs, *base)
+		}
+		if err := git(gitArgs...); err != nil {
+			fatal(err)
+		}
+		return
+	}
+
+	if err := createLinkedBranch(project, n, *name, *base); err != nil {
+		fatal(err)
+	}
+	log.Printf("created branch %s in %s, linked to #%d", *name, project, n)
+	tracking := *remote + "/" + *name
+	if err := git("fetch", *remote, "+refs/heads/"+*name+":refs/remotes/"+tracking); err != nil {
+		fatal(err)
+	}
+	if err := git("checkout", "-b", *name, "--track", tracking); err != nil {
+		fatal(err)
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// cacheMaxAge is how long cached repository metadata is used before
+// it is fetched again.
+const cacheMaxAge = 24 * time.Hour
+
+// cacheFile returns the name of the file holding the named
+// cached data for project, in the user's cache directory.
+func cacheFile(project, name string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "issue", projectOwner(project), projectRepo(project), name+".json"), nil
+}
+
+// readCache reads the named cached data for project into v,
+// reporting whether the cache held data no older than maxAge.
+func readCache(project, name string, maxAge time.Duration, v interface{}) bool {
+	file, err := cacheFile(project, name)
+	if err != nil {
+		return false
+	}
+	fi, err := os.Stat(file)
+	if err != nil || time.Since(fi.ModTime()) > maxAge {
+		return false
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, v) == nil
+}
+
+// writeCache saves v as the named cached data for project.
+// Failing to write the cache is not an error worth reporting.
+func writeCache(project, name string, v interface{}) {
+	file, err := cacheFile(project, name)
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return
+	}
+	ioutil.WriteFile(file, data, 0600)
+}
+
+// cachedNames returns the names of the project's labels, open milestones,
+// assignable users, or issue types, according to kind
+// ("labels", "milestones", "assignees", or "types"),
+// using the cache if possible and refreshing it if not.
+func cachedNames(project, kind string) ([]string, error) {
+	var names []string
+	if readCache(project, kind, cacheMaxAge, &names) {
+		return names, nil
+	}
+	return loadNames(project, kind)
+}
+
+// loadNames is like cachedNames but always fetches the names
+// from GitHub, refreshing the cache.
+func loadNames(project, kind string) ([]string, error) {
+	var names []string
+	if client == nil {
+		loadAuth()
+	}
+	var err error
+	switch kind {
+	case "labels":
+		names, err = loadLabelNames(project)
+	case "milestones":
+		var all []*github.Milestone
+		all, err = loadMilestones(project)
+		for _, m := range all {
+			names = append(names, getString(m.Title))
+		}
+	case "assignees":
+		names, err = loadAssigneeLogins(project)
+	case "types":
+		names, err = loadIssueTypeNames(project)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	writeCache(project, kind, names)
+	return names, nil
+}
+
+// checkName reports whether name, ignoring case, is one of the
+// project's names of the given kind, as listed by cachedNames.
+// If name is not in the cached list, checkName fetches the list again,
+// in case name was added since it was cached. It also returns the list,
+// for suggesting similar names, or nil if it cannot be loaded, in which
+// case it reports name as found, leaving GitHub to decide.
+func checkName(project, kind, name string) (bool, []string) {
+	has := func(names []string) bool {
+		for _, n := range names {
+			if strings.EqualFold(n, name) {
+				return true
+			}
+		}
+		return false
+	}
+	names, err := cachedNames(project, kind)
+	if err != nil {
+		return true, nil
+	}
+	if has(names) {
+		return true, names
+	}
+	if fresh, err := loadNames(project, kind); err == nil {
+		names = fresh
+	}
+	return has(names), names
+}
+
+func loadLabelNames(project string) ([]string, error) {
+	var names []string
+	for page := 1; ; {
+		list, resp, err := client.Issues.ListLabels(context.TODO(), projectOwner(project), projectRepo(project), &github.ListOptions{
+			Page:    page,
+			PerPage: 100,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, lab := range list {
+			names = append(names, getString(lab.Name))
+		}
+		if resp.NextPage < page {
+			break
+		}
+		page = resp.NextPage
+	}
+	return names, nil
+}
+
+func loadAssigneeLogins(project string) ([]string, error) {
+	var logins []string
+	for page := 1; ; {
+		list, resp, err := client.Issues.ListAssignees(context.TODO(), projectOwner(project), projectRepo(project), &github.ListOptions{
+			Page:    page,
+			PerPage: 100,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range list {
+			logins = append(logins, getUserLogin(u))
+		}
+		if resp.NextPage < page {
+			break
+		}
+		page = resp.NextPage
+	}
+	return logins, nil
+}
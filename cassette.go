@@ -0,0 +1,245 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// The -record flag saves the HTTP traffic of a run of issue in a
+// cassette file, and the -replay flag answers requests from one
+// instead of the network, for demos and offline testing.
+// Credentials are removed from the recording.
+
+// An interaction is a request and its response, recorded as one
+// line of JSON in a cassette file. Bodies that are not UTF-8 text
+// are base64-encoded.
+type interaction struct {
+	Method        string
+	URL           string
+	RequestBody   string `json:",omitempty"`
+	RequestBase64 bool   `json:",omitempty"`
+	Status        int
+	Header        http.Header `json:",omitempty"`
+	Body          string      `json:",omitempty"`
+	Base64        bool        `json:",omitempty"`
+}
+
+// tokenRE matches GitHub tokens, such as those minted for apps,
+// which must not be recorded.
+var tokenRE = regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{20,}|github_pat_[A-Za-z0-9_]{20,})\b`)
+
+// sanitize replaces the credentials in s.
+func sanitize(s string) string {
+	if token := auth.current(); token != "" {
+		s = strings.Replace(s, token, "REDACTED", -1)
+	}
+	return tokenRE.ReplaceAllString(s, "REDACTED")
+}
+
+// encodeBody returns data as a string for a cassette,
+// and whether it is base64-encoded.
+func encodeBody(data []byte) (string, bool) {
+	if utf8.Valid(data) {
+		return sanitize(string(data)), false
+	}
+	return base64.StdEncoding.EncodeToString(data), true
+}
+
+func decodeBody(s string, isBase64 bool) ([]byte, error) {
+	if isBase64 {
+		return base64.StdEncoding.DecodeString(s)
+	}
+	return []byte(s), nil
+}
+
+// readRequestBody returns the body of r, leaving r able to send it.
+func readRequestBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+	data, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(data))
+	return data, err
+}
+
+// A recordTransport records the requests made with transport
+// and their responses in a cassette file.
+type recordTransport struct {
+	transport http.RoundTripper
+	mu        sync.Mutex
+	f         *os.File
+}
+
+func newRecordTransport(t http.RoundTripper, file string) (http.RoundTripper, error) {
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &recordTransport{transport: t, f: f}, nil
+}
+
+func (t *recordTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	reqBody, err := readRequestBody(r)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.transport.RoundTrip(r)
+	if err != nil {
+		return resp, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return resp, err
+	}
+
+	x := &interaction{
+		Method: r.Method,
+		URL:    sanitize(r.URL.String()),
+		Status: resp.StatusCode,
+		Header: resp.Header.Clone(),
+	}
+	x.Header.Del("Set-Cookie")
+	x.RequestBody, x.RequestBase64 = encodeBody(reqBody)
+	x.Body, x.Base64 = encodeBody(body)
+	data, err := json.Marshal(x)
+	if err != nil {
+		return resp, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, err := t.f.Write(append(data, '\n')); err != nil {
+		return resp, fmt.Errorf("-record: %v", err)
+	}
+	return resp, nil
+}
+
+// dateRE matches the dates and times in search queries,
+// such as "updated:<2022-06-01T00:00:00Z".
+var dateRE = regexp.MustCompile(`[0-9]{4}-[0-9]{2}-[0-9]{2}(T[0-9:]+(Z|[-+][0-9:]+)?)?`)
+
+// replayURL returns u with the parts that depend on when the request
+// was made removed: the since and before parameters, and the dates in
+// a search query, which are computed from the current time.
+func replayURL(u string) string {
+	pu, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+	q := pu.Query()
+	q.Del("since")
+	q.Del("before")
+	if v := q.Get("q"); v != "" {
+		q.Set("q", dateRE.ReplaceAllString(v, "DATE"))
+	}
+	pu.RawQuery = q.Encode()
+	return pu.String()
+}
+
+// A replayTransport answers requests with the responses
+// recorded in a cassette file, never using the network.
+// Each recorded interaction answers one request with the same
+// method, URL, and body, in the order they were recorded.
+// If no interaction has the same URL, one whose URL differs only in
+// the parts that replayURL removes is used instead, so that a run
+// replayed on another day still finds its responses.
+type replayTransport struct {
+	file string
+	mu   sync.Mutex
+	list []*interaction // unused interactions
+}
+
+func newReplayTransport(file string) (http.RoundTripper, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	t := &replayTransport{file: file}
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 256<<20)
+	for lineno := 1; s.Scan(); lineno++ {
+		if len(bytes.TrimSpace(s.Bytes())) == 0 {
+			continue
+		}
+		x := new(interaction)
+		if err := json.Unmarshal(s.Bytes(), x); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", file, lineno, err)
+		}
+		t.list = append(t.list, x)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *replayTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	reqBody, err := readRequestBody(r)
+	if err != nil {
+		return nil, err
+	}
+	reqText, _ := encodeBody(reqBody)
+	u := sanitize(r.URL.String())
+
+	t.mu.Lock()
+	x := t.take(r.Method, reqText, func(y string) bool { return y == u })
+	if x == nil {
+		ru := replayURL(u)
+		x = t.take(r.Method, reqText, func(y string) bool { return replayURL(y) == ru })
+	}
+	t.mu.Unlock()
+	if x == nil {
+		return nil, fmt.Errorf("%s: no recorded response for %s %s (a replayed run must make the same requests as the recorded one)", t.file, r.Method, u)
+	}
+
+	body, err := decodeBody(x.Body, x.Base64)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s %s: %v", t.file, r.Method, u, err)
+	}
+	header := x.Header
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", x.Status, http.StatusText(x.Status)),
+		StatusCode:    x.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       r,
+	}, nil
+}
+
+// take removes and returns the first unused interaction
+// with the given method and request body whose URL matches,
+// or returns nil if there is none. It must be called with t.mu locked.
+func (t *replayTransport) take(method, body string, match func(url string) bool) *interaction {
+	for i, y := range t.list {
+		if y.Method == method && y.RequestBody == body && match(y.URL) {
+			t.list = append(t.list[:i:i], t.list[i+1:]...)
+			return y
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// commitRefRE matches a reference to an issue in a commit message,
+// like "Fixes #1234", "Updates golang/go#1234", or "closes: #5".
+var commitRefRE = regexp.MustCompile(`(?i)\b(close[sd]?|fix(?:e[sd])?|resolve[sd]?|updates?)\s*:?\s+(?:([A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+))?#([0-9]+)\b`)
+
+// A commitRef is a reference to an issue in a commit message.
+type commitRef struct {
+	Commit  string // abbreviated commit hash
+	Fixes   bool   // a closing keyword, not "Updates"
+	Project string // the project named, or "" for the current one
+	Number  int
+}
+
+func (r *commitRef) String() string {
+	verb := "Updates"
+	if r.Fixes {
+		verb = "Fixes"
+	}
+	return fmt.Sprintf("%s %s %s#%d", r.Commit, verb, r.Project, r.Number)
+}
+
+// gitCommitRefs returns the issue references in the messages
+// of the commits listed by git log with the given arguments.
+func gitCommitRefs(args []string) ([]*commitRef, error) {
+	args = append([]string{"log", "--format=%h%x00%B%x00"}, args...)
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		if e, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("git log: %v\n%s", err, e.Stderr)
+		}
+		return nil, fmt.Errorf("git log: %v", err)
+	}
+	var refs []*commitRef
+	f := strings.Split(string(out), "\x00")
+	for i := 0; i+1 < len(f); i += 2 {
+		hash := strings.TrimSpace(f[i])
+		for _, m := range commitRefRE.FindAllStringSubmatch(f[i+1], -1) {
+			n, _ := strconv.Atoi(m[3])
+			verb := strings.ToLower(m[1])
+			refs = append(refs, &commitRef{hash, !strings.HasPrefix(verb, "update"), m[2], n})
+		}
+	}
+	return refs, nil
+}
+
+func runCheckRefs(project string, args []string) {
+	fs := lookupCommand("check-refs").flags()
+	since := fs.String("since", "", "check only commits more recent than `date`, as git log -since accepts")
+	parseFlags(fs, args)
+	logArgs := fs.Args()
+	if *since != "" {
+		logArgs = append([]string{"--since=" + *since}, logArgs...)
+	}
+	refs, err := gitCommitRefs(logArgs)
+	if err != nil {
+		fatal(err)
+	}
+
+	issues := make(map[int]*github.Issue)
+	problems := 0
+	report := func(ref *commitRef, format string, args ...interface{}) {
+		fmt.Printf("%s: %s\n", ref, fmt.Sprintf(format, args...))
+		problems++
+	}
+	for _, ref := range refs {
+		if ref.Project != "" && !strings.EqualFold(ref.Project, project) {
+			report(ref, "refers to another repository, not %s", project)
+			continue
+		}
+		ref.Project = ""
+		issue, ok := issues[ref.Number]
+		if !ok {
+			issue, err = getIssue(project, ref.Number)
+			if err != nil && !isNotFound(err) {
+				fatal(err)
+			}
+			issues[ref.Number] = issue
+		}
+		switch {
+		case issue == nil:
+			report(ref, "no such issue in %s", project)
+		case issue.IsPullRequest():
+			report(ref, "refers to a pull request, not an issue")
+		case ref.Fixes && getString(issue.State) == "open":
+			report(ref, "issue is still open: %s", getString(issue.Title))
+		}
+	}
+	if problems > 0 {
+		exit(exitError)
+	}
+}
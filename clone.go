@@ -0,0 +1,74 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+)
+
+func runClone(project string, args []string) {
+	fs := lookupCommand("clone").flags()
+	to := fs.String("to", "", "create the copy in `owner/repo`")
+	parseFlags(fs, args)
+	if fs.NArg() != 1 || *to == "" {
+		fs.Usage()
+	}
+	if f := strings.Split(*to, "/"); len(f) != 2 || f[0] == "" || f[1] == "" {
+		usageErrorf("invalid form for -to argument: must be owner/repo, like golang/go")
+	}
+	if *to == project {
+		usageErrorf("cannot clone an issue into its own repository")
+	}
+	n := issueArgs(fs, fs.Args())[0]
+
+	issue, _, err := client.Issues.Get(context.TODO(), projectOwner(project), projectRepo(project), n)
+	if err != nil {
+		fatal(err)
+	}
+
+	// Keep only the labels that exist in the target repository,
+	// since creating the issue would otherwise create them.
+	names, err := cachedNames(*to, "labels")
+	if err != nil {
+		fatal(err)
+	}
+	exists := make(map[string]bool)
+	for _, name := range names {
+		exists[name] = true
+	}
+	labels := []string{}
+	var dropped []string
+	for _, name := range getLabelNames(issue.Labels) {
+		if exists[name] {
+			labels = append(labels, name)
+		} else {
+			dropped = append(dropped, name)
+		}
+	}
+
+	title := getString(issue.Title)
+	body := fmt.Sprintf("_Cloned from %s#%d (%s), reported by %s on %s._\n\n%s",
+		project, n, issueURL(project, n), profileLink(issue.User), getTime(issue.CreatedAt).Format("2006-01-02"), getString(issue.Body))
+	body, err = gistBody(fmt.Sprintf("Attachment for new %s issue", *to), body)
+	if err != nil {
+		fatal(err)
+	}
+	clone, _, err := client.Issues.Create(context.TODO(), projectOwner(*to), projectRepo(*to), &github.IssueRequest{
+		Title:  &title,
+		Body:   &body,
+		Labels: &labels,
+	})
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Printf("%s\n", getString(clone.HTMLURL))
+	if len(dropped) > 0 {
+		fmt.Printf("labels not in %s: %s\n", *to, strings.Join(dropped, " "))
+	}
+}
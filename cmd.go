@@ -0,0 +1,467 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// A command is a subcommand of issue, such as "issue task".
+type command struct {
+	name  string
+	args  string // argument summary for usage messages
+	short string // one-line description
+	run   func(project string, args []string)
+
+	// noAuth means the command runs without loading credentials.
+	// Commands beginning with "__" are internal and omitted from usage.
+	noAuth bool
+}
+
+// commands is the list of subcommands, initialized in init
+// to avoid an initialization loop through usage.
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{name: "show", args: "[-json] [-raw|-both] <n>...", short: "print the full history of issues", run: runShow},
+		{name: "list", args: "[-json] [query]", short: "print the open issues matching a query", run: runList},
+		{name: "create", args: "[-f file] [-template name [-e]] [-title title] [-body text|-] [-labels l1,l2] [-assignee login] [-milestone name]", short: "create an issue", run: runCreate},
+		{name: "comment", args: "<n> [-m text | text]", short: "post a comment on an issue", run: runComment},
+		{name: "close", args: "[-m comment] <n>...", short: "close issues", run: runClose},
+		{name: "edit", args: "<n>|new|<query>", short: "edit issues in the system editor", run: runEdit},
+		{name: "assign", args: "<n> @me|@org/team|<login>...", short: "add assignees to an issue", run: runAssign},
+		{name: "attachments", args: "[-o dir] <n>", short: "download the files and images attached to an issue", run: runAttachments},
+		{name: "branch", args: "[-link [-remote name]] [-base ref] [-name name] <n>", short: "create and check out a working branch for an issue", run: runBranch},
+		{name: "check-refs", args: "[-since date] [revision-range]", short: "check the issues referenced by local git commits against the tracker", run: runCheckRefs},
+		{name: "clone", args: "<n> -to owner/repo", short: "copy an issue into another repository", run: runClone},
+		{name: "completion", args: "bash|zsh|fish", short: "print a shell completion script", run: runCompletion, noAuth: true},
+		{name: "dup", args: "<n> <m>", short: "close issue n as a duplicate of issue m", run: runDup},
+		{name: "epic", args: "<milestone>", short: "print a milestone's issues as a tree of umbrella issues", run: runEpic},
+		{name: "feed", args: "[-o file] <query>", short: "write an Atom feed of recently updated matching issues", run: runFeed},
+		{name: "fixmsg", args: "<n> | -hook [-f]", short: "print a commit message skeleton for fixing an issue", run: runFixmsg},
+		{name: "fs", args: "[-name name]", short: "serve issues as a 9P file system", run: runFS},
+		{name: "graph", args: "[-mermaid] [-comments] <query>", short: "print the dependency graph of matching issues", run: runGraph},
+		{name: "hide", args: "[-r reason] <comment-id>...", short: "hide comments as off-topic, outdated, resolved, and so on", run: runHide},
+		{name: "label", args: "<n> +<add> -<remove>...", short: "add and remove labels", run: runLabel},
+		{name: "merge", args: "<src> <dst>", short: "copy an issue's discussion into another and close it as a duplicate", run: runMerge},
+		{name: "milestone", args: "<n> <milestone-name>|none", short: "set or clear an issue's milestone", run: runMilestone},
+		{name: "milestones", args: "[-state open|closed|all] [-sort due|title] [-due-before date] [-due-after date] [-title regexp] [-ics|-mermaid]", short: "list milestones and their due dates", run: runMilestones},
+		{name: "pick", args: "[-o] [-refresh] [pattern...]", short: "choose a locally known issue with a fuzzy finder", run: runPick},
+		{name: "plumbing", args: "", short: "print plumbing rules that open issue references in acme", run: runPlumbing, noAuth: true},
+		{name: "policy", args: "[-n] list|run [name...]", short: "run the configured triage policies", run: runPolicy},
+		{name: "ratelimit", short: "print the remaining API rate limits and issue's usage", run: runRateLimit},
+		{name: "resolved-by", args: "<n>", short: "report the commit, release, and backports that fixed a closed issue", run: runResolvedBy},
+		{name: "resume", args: "[journal]", short: "finish an interrupted bulk edit", run: runResume},
+		{name: "retitle", args: "<n> <title>", short: "change an issue's title", run: runRetitle},
+		{name: "schedule", args: "[-once]", short: "run the configured reports on their schedules", run: runSchedule},
+		{name: "sendmail", args: "[sendmail-args...]", short: "post a mail reply read from standard input as a comment", run: runSendmail},
+		{name: "split", args: "[-n] <n>", short: "turn an issue's unchecked tasks into new issues", run: runSplit},
+		{name: "suggest-owner", args: "[-apply [-y]] <n>", short: "suggest assignees for an issue from CODEOWNERS", run: runSuggestOwner},
+		{name: "task", args: "<n> [check|uncheck|toggle <i>]", short: "list or update task list items", run: runTask},
+		{name: "todo", args: "[-o file] [query]", short: "print assigned issues in todo.txt format", run: runTodo},
+		{name: "trace", args: "[-a] [-ref ref] <n>", short: "link the stack frames in an issue to the repository's source", run: runTrace},
+		{name: "triage", args: "-apply-rules [-n] <query>", short: "apply the configured labeling rules to matching issues", run: runTriage},
+		{name: "tw-sync", args: "[-close] [-n] [query]", short: "export matching issues to Taskwarrior", run: runTWSync},
+		{name: "unassign", args: "<n> [@me|<login>...]", short: "remove assignees from an issue", run: runUnassign},
+		{name: "watch", args: "[-once] [-metrics addr]", short: "notify chat webhooks of changes to watched issues", run: runWatch},
+		{name: "__complete", args: "<line>", short: "print completions for a command line", run: runComplete, noAuth: true},
+	}
+}
+
+func lookupCommand(name string) *command {
+	for _, c := range commands {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// flags returns a new FlagSet for the command,
+// with a usage message derived from the command's argument summary.
+func (c *command) flags() *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ExitOnError)
+	if commandQuery {
+		fs.SetOutput(ioutil.Discard)
+	}
+	fs.Usage = func() {
+		if commandQuery {
+			panic(notCommand{})
+		}
+		fmt.Fprintf(os.Stderr, "usage: issue %s %s\n", c.name, c.args)
+		fs.PrintDefaults()
+		exit(exitUsage)
+	}
+	return fs
+}
+
+// A command name is also an ordinary search word, so while a command
+// runs from the command line, commandQuery is set, and if its arguments
+// do not parse, its usage function panics with notCommand instead of
+// exiting, letting runCommand report that they were not a command.
+var commandQuery bool
+
+type notCommand struct{}
+
+// runCommand runs c with args and reports whether it did; it returns
+// false if args are not valid arguments for c, in which case the
+// command line should be treated as a query.
+func runCommand(c *command, project string, args []string) (ok bool) {
+	commandQuery = true
+	defer func() {
+		commandQuery = false
+		if e := recover(); e != nil {
+			if _, isNot := e.(notCommand); !isNot {
+				panic(e)
+			}
+			ok = false
+		}
+	}()
+	c.run(project, args)
+	return true
+}
+
+// queryForced reports whether the arguments after the global flags
+// followed a "--", as in "issue -- todo", which searches for the words
+// even if the first is a command name.
+func queryForced() bool {
+	i := len(os.Args) - flag.NArg() - 1
+	return i > 0 && os.Args[i] == "--"
+}
+
+// parseFlags parses args with fs, allowing flags to follow the
+// positional arguments, as in "issue comment 123 -m text".
+// Afterward, fs.Args returns just the positional arguments.
+// An argument "--" ends flag parsing as usual.
+func parseFlags(fs *flag.FlagSet, args []string) {
+	var pos []string
+	for {
+		fs.Parse(args)
+		rest := fs.Args()
+		if len(rest) == 0 {
+			break
+		}
+		if n := len(args) - len(rest); n > 0 && args[n-1] == "--" {
+			pos = append(pos, rest...)
+			break
+		}
+		pos = append(pos, rest[0])
+		args = rest[1:]
+	}
+	fs.Parse(append([]string{"--"}, pos...))
+}
+
+// issueArgs parses args as a list of issue numbers,
+// calling fs.Usage if any is not a number.
+func issueArgs(fs *flag.FlagSet, args []string) []int {
+	var ids []int
+	for _, arg := range args {
+		n, err := strconv.Atoi(strings.TrimPrefix(arg, "#"))
+		if err != nil || n <= 0 {
+			fs.Usage()
+		}
+		ids = append(ids, n)
+	}
+	return ids
+}
+
+func runShow(project string, args []string) {
+	fs := lookupCommand("show").flags()
+	fs.Var(jsonFlag, "json", "write JSON output; -json=2 selects the extended schema")
+	fs.BoolVar(rawFlag, "raw", *rawFlag, "do no processing of markdown")
+	fs.BoolVar(bothFlag, "both", *bothFlag, "show the raw markdown of the body and comments beside the wrapped text")
+	parseFlags(fs, args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+	}
+	printIssues(project, issueArgs(fs, fs.Args()))
+}
+
+func runList(project string, args []string) {
+	fs := lookupCommand("list").flags()
+	fs.Var(jsonFlag, "json", "write JSON output; -json=2 selects the extended schema")
+	parseFlags(fs, args)
+	printQuery(project, strings.Join(fs.Args(), " "))
+}
+
+func runEdit(project string, args []string) {
+	fs := lookupCommand("edit").flags()
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+	}
+	editQuery(project, strings.Join(fs.Args(), " "))
+}
+
+func runCreate(project string, args []string) {
+	fs := lookupCommand("create").flags()
+	file := fs.String("f", "", "read the issue from Markdown `file` with YAML front matter; \"-\" reads standard input")
+	title := fs.String("title", "", "issue `title`")
+	body := fs.String("body", "", "issue `text`; \"-\" reads the text from standard input")
+	labels := fs.String("labels", "", "comma-separated `list` of labels")
+	assignee := fs.String("assignee", "", "assign the issue to `login`")
+	milestone := fs.String("milestone", "", "add the issue to milestone `name`")
+	tmpl := fs.String("template", "", "start from the repository's issue template `name`")
+	edit := fs.Bool("e", false, "with -template, fill in the template in the system editor")
+	parseFlags(fs, args)
+	if fs.NArg() != 0 || *file != "" && *body != "" || *file == "-" && *body == "-" {
+		fs.Usage()
+	}
+	if *tmpl != "" && (*file != "" || *body != "") || *edit && *tmpl == "" {
+		fs.Usage()
+	}
+
+	// Start with the file, if any, and then apply the flags.
+	meta := new(issueFile)
+	text := *body
+	if *file != "" {
+		var data []byte
+		var err error
+		if *file == "-" {
+			data, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			data, err = ioutil.ReadFile(*file)
+		}
+		if err != nil {
+			fatal(err)
+		}
+		meta, text, err = parseIssueFile(data)
+		if err != nil {
+			fatalf("%s: %v", *file, err)
+		}
+	}
+	if text == "-" {
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			fatal(err)
+		}
+		text = string(data)
+	}
+	if *title != "" {
+		meta.Title = *title
+	}
+	if *labels != "" {
+		meta.Labels = strings.Split(*labels, ",")
+	}
+	if *assignee != "" {
+		meta.Assignee, meta.Assignees = *assignee, nil
+	}
+	if *milestone != "" {
+		meta.Milestone = *milestone
+	}
+	if *tmpl != "" {
+		t, err := findTemplate(project, *tmpl)
+		if err != nil {
+			fatal(err)
+		}
+		if *edit || t.Body == nil {
+			createFromTemplate(project, t, meta)
+			return
+		}
+		if !canPrompt() {
+			usageErrorf("-template prompts for the fields in a terminal; use -e to fill them in an editor")
+		}
+		if text, err = promptTemplate(t, meta); err != nil {
+			fatal(err)
+		}
+	}
+	if meta.Title == "" {
+		usageErrorf("issue has no title")
+	}
+
+	text, err := gistBody(fmt.Sprintf("Attachment for new %s issue", project), strings.TrimSpace(text))
+	if err != nil {
+		fatal(err)
+	}
+	req := &github.IssueRequest{
+		Title: &meta.Title,
+		Body:  &text,
+	}
+	if len(meta.Labels) > 0 {
+		list := []string(meta.Labels)
+		req.Labels = &list
+	}
+	if meta.Assignee != "" {
+		meta.Assignees = append(stringList{meta.Assignee}, meta.Assignees...)
+	}
+	if len(meta.Assignees) > 0 {
+		list := []string(meta.Assignees)
+		req.Assignees = &list
+	}
+	if meta.Milestone != "" {
+		var errbuf strings.Builder
+		req.Milestone = findOrCreateMilestone(&errbuf, project, &meta.Milestone, canPrompt())
+		if req.Milestone == nil {
+			fatal(strings.TrimSpace(errbuf.String()))
+		}
+	}
+	issue, _, err := client.Issues.Create(context.TODO(), projectOwner(project), projectRepo(project), req)
+	if err != nil {
+		fatal(err)
+	}
+	change := &hookChange{
+		Project: project,
+		Number:  getInt(issue.Number),
+		URL:     issueURL(project, getInt(issue.Number)),
+		State:   getString(issue.State),
+		Title:   req.Title,
+		Labels:  req.Labels,
+	}
+	if meta.Milestone != "" {
+		change.Milestone = &meta.Milestone
+	}
+	runPostHook("post-create", change)
+	fmt.Println(issueURL(project, getInt(issue.Number)))
+}
+
+// postComment posts text as a new comment on issue n.
+func postComment(project string, n int, text string) error {
+	_, err := postCommentID(project, n, text)
+	return err
+}
+
+// postCommentID is like postComment but also returns the new comment's ID.
+func postCommentID(project string, n int, text string) (int64, error) {
+	text, err := gistBody(fmt.Sprintf("Attachment for %s#%d", project, n), text)
+	if err != nil {
+		return 0, err
+	}
+	com, _, err := client.Issues.CreateComment(context.TODO(), projectOwner(project), projectRepo(project), n, &github.IssueComment{
+		Body: &text,
+	})
+	return com.GetID(), err
+}
+
+func runComment(project string, args []string) {
+	fs := lookupCommand("comment").flags()
+	msg := fs.String("m", "", "use `text` as the comment")
+	parseFlags(fs, args)
+	if fs.NArg() == 0 || fs.NArg() > 1 && *msg != "" {
+		fs.Usage()
+	}
+	n := issueArgs(fs, fs.Args()[:1])[0]
+
+	text := *msg
+	switch {
+	case fs.NArg() > 1:
+		text = strings.Join(fs.Args()[1:], " ")
+	case text == "":
+		if isTerminal(os.Stdin) {
+			log.Print("reading comment from standard input")
+		}
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			fatal(err)
+		}
+		text = string(data)
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		usageErrorf("empty comment")
+	}
+	if err := commentIssue(project, n, text); err != nil {
+		fatal(err)
+	}
+}
+
+func runClose(project string, args []string) {
+	fs := lookupCommand("close").flags()
+	comment := fs.String("m", "", "post `text` as a comment before closing")
+	parseFlags(fs, args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+	}
+	failed := false
+	for _, n := range issueArgs(fs, fs.Args()) {
+		state := "closed"
+		err := putIssue(project, n, &hookChange{NewState: &state, Comment: *comment}, func() ([]string, error) {
+			var did []string
+			if *comment != "" {
+				if err := postComment(project, n, *comment); err != nil {
+					return did, err
+				}
+				did = append(did, "saved comment")
+			}
+			if _, _, err := client.Issues.Edit(context.TODO(), projectOwner(project), projectRepo(project), n, &github.IssueRequest{State: &state}); err != nil {
+				return did, err
+			}
+			return append(did, "closed"), nil
+		})
+		if err != nil {
+			log.Printf("#%d: %v", n, err)
+			failed = true
+		}
+	}
+	if failed {
+		exit(exitError)
+	}
+}
+
+func runDup(project string, args []string) {
+	fs := lookupCommand("dup").flags()
+	parseFlags(fs, args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+	}
+	ns := issueArgs(fs, fs.Args())
+	n, m := ns[0], ns[1]
+	if n == m {
+		usageErrorf("cannot mark #%d as a duplicate of itself", n)
+	}
+	// GitHub recognizes "Duplicate of #m" and marks n as a duplicate.
+	if err := closeWithComment(project, n, fmt.Sprintf("Duplicate of #%d", m)); err != nil {
+		fatal(err)
+	}
+	if err := commentIssue(project, m, fmt.Sprintf("#%d has been closed as a duplicate of this issue.", n)); err != nil {
+		fatal(err)
+	}
+}
+
+func runLabel(project string, args []string) {
+	fs := lookupCommand("label").flags()
+	// No flags: the label arguments begin with - and +.
+	fs.Parse(args)
+	if fs.NArg() < 2 {
+		fs.Usage()
+	}
+	issueArgs(fs, fs.Args()[:1])
+	if _, err := runBatchOp(project, append([]string{"label"}, fs.Args()...)); err != nil {
+		fatal(err)
+	}
+}
+
+func runRetitle(project string, args []string) {
+	fs := lookupCommand("retitle").flags()
+	parseFlags(fs, args)
+	if fs.NArg() < 2 {
+		fs.Usage()
+	}
+	issueArgs(fs, fs.Args()[:1])
+	if _, err := runBatchOp(project, append([]string{"retitle"}, fs.Args()...)); err != nil {
+		fatal(err)
+	}
+}
+
+func runMilestone(project string, args []string) {
+	fs := lookupCommand("milestone").flags()
+	parseFlags(fs, args)
+	if fs.NArg() < 2 {
+		fs.Usage()
+	}
+	issueArgs(fs, fs.Args()[:1])
+	if _, err := runBatchOp(project, append([]string{"milestone"}, fs.Args()...)); err != nil {
+		fatal(err)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// Threads can be dominated by comments from bots, so the comments
+// by the authors in the collapse-authors setting are collapsed
+// to one-line summaries, unless -expand is given or, in acme,
+// the issue window has been expanded by executing "Expand".
+
+// expanded records the issues whose acme windows show
+// collapsed comments in full.
+var expanded struct {
+	sync.Mutex
+	m map[projectAndNumber]bool
+}
+
+// toggleExpanded switches whether issue n shows collapsed comments
+// in full, reporting whether it now does.
+func toggleExpanded(project string, n int) bool {
+	expanded.Lock()
+	defer expanded.Unlock()
+	if expanded.m == nil {
+		expanded.m = make(map[projectAndNumber]bool)
+	}
+	k := projectAndNumber{project, n}
+	expanded.m[k] = !expanded.m[k]
+	return expanded.m[k]
+}
+
+// collapseComment reports whether com, on issue n,
+// is to be printed collapsed.
+func collapseComment(project string, n int, com *github.IssueComment) bool {
+	if *expandFlag {
+		return false
+	}
+	expanded.Lock()
+	full := expanded.m[projectAndNumber{project, n}]
+	expanded.Unlock()
+	if full {
+		return false
+	}
+	login := getUserLogin(com.User)
+	for _, a := range loadConfig().CollapseAuthors {
+		if strings.EqualFold(a, login) {
+			return true
+		}
+	}
+	return false
+}
+
+// maxSummary is the length, in runes, of a collapsed comment's summary.
+const maxSummary = 60
+
+// commentSummary returns the first non-blank line of text,
+// shortened to maxSummary runes.
+func commentSummary(text string) string {
+	var line string
+	for _, l := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(l); line != "" {
+			break
+		}
+	}
+	if utf8.RuneCountInString(line) > maxSummary {
+		line = string([]rune(line)[:maxSummary-3]) + "..."
+	}
+	if line == "" {
+		line = "(no text)"
+	}
+	return line
+}
+
+// printCollapsedComment prints com collapsed to its header line
+// and a one-line summary of its text.
+func printCollapsedComment(w io.Writer, com *github.IssueComment) {
+	fmt.Fprintf(w, "\n%s\n\n\t[collapsed: %s]\n", commentHeader(com), commentSummary(getString(com.Body)))
+}
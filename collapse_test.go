@@ -0,0 +1,64 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v45/github"
+)
+
+var commentSummaryTests = []struct {
+	text, out string
+}{
+	{"", "(no text)"},
+	{" \n\t\n", "(no text)"},
+	{"Change https://go.dev/cl/1 mentions this issue.", "Change https://go.dev/cl/1 mentions this issue."},
+	{"\n\n  Found new dashboard test flakes:\n\nmore", "Found new dashboard test flakes:"},
+	{strings.Repeat("x", 60), strings.Repeat("x", 60)},
+	{strings.Repeat("x", 61), strings.Repeat("x", 57) + "..."},
+	{strings.Repeat("é", 70), strings.Repeat("é", 57) + "..."},
+}
+
+func TestCommentSummary(t *testing.T) {
+	for _, tt := range commentSummaryTests {
+		if out := commentSummary(tt.text); out != tt.out {
+			t.Errorf("commentSummary(%q) = %q, want %q", tt.text, out, tt.out)
+		}
+	}
+}
+
+var collapseCommentTests = []struct {
+	login    string
+	expand   bool // -expand
+	expanded bool // toggled with Expand in acme
+	want     bool
+}{
+	{login: "gopherbot", want: true},
+	{login: "GopherBot", want: true},
+	{login: "rsc", want: false},
+	{login: "", want: false},
+	{login: "gopherbot", expand: true, want: false},
+	{login: "gopherbot", expanded: true, want: false},
+}
+
+func TestCollapseComment(t *testing.T) {
+	writeConfig(t, "collapse-authors: [gopherbot, gopls-bot]\n")
+	defer func(old bool) { *expandFlag = old }(*expandFlag)
+	for _, tt := range collapseCommentTests {
+		*expandFlag = tt.expand
+		if tt.expanded {
+			toggleExpanded("golang/go", 1)
+		}
+		com := &github.IssueComment{User: &github.User{Login: github.String(tt.login)}}
+		if got := collapseComment("golang/go", 1, com); got != tt.want {
+			t.Errorf("collapseComment by %q (expand %v, expanded %v) = %v, want %v", tt.login, tt.expand, tt.expanded, got, tt.want)
+		}
+		if tt.expanded {
+			toggleExpanded("golang/go", 1)
+		}
+	}
+}
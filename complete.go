@@ -0,0 +1,159 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const bashCompletion = `# bash completion for issue; load with: source <(issue completion bash)
+_issue() {
+	local line="${COMP_LINE:0:COMP_POINT}"
+	local word="${line##*[[:space:]]}"
+	local IFS=$'\n'
+	COMPREPLY=($(issue __complete "$line" 2>/dev/null))
+	# Bash splits words at colons, so complete only the text after the last one.
+	if [[ "$word" == *:* && "$COMP_WORDBREAKS" == *:* ]]; then
+		local colon="${word%:*}:"
+		COMPREPLY=("${COMPREPLY[@]#"$colon"}")
+	fi
+}
+complete -F _issue issue
+`
+
+const zshCompletion = `#compdef issue
+# zsh completion for issue; load with: source <(issue completion zsh)
+_issue() {
+	local -a completions
+	completions=("${(@f)$(issue __complete "$LBUFFER" 2>/dev/null)}")
+	compadd -Q -- $completions
+}
+compdef _issue issue
+`
+
+const fishCompletion = `# fish completion for issue; load with: issue completion fish | source
+complete -c issue -f -a '(issue __complete (commandline -cp) 2>/dev/null)'
+`
+
+func runCompletion(project string, args []string) {
+	c := lookupCommand("completion")
+	fs := c.flags()
+	fs.Parse(args)
+	switch fs.Arg(0) {
+	case "bash":
+		fmt.Print(bashCompletion)
+	case "zsh":
+		fmt.Print(zshCompletion)
+	case "fish":
+		fmt.Print(fishCompletion)
+	default:
+		fs.Usage()
+	}
+}
+
+// queryKeys are the search qualifiers offered when completing a query word.
+var queryKeys = []string{"assignee:", "author:", "label:", "mentions:", "milestone:", "no:", "sort:", "state:", "type:"}
+
+// runComplete prints the completions for the command line text
+// given as its single argument, one per line.
+// It is invoked by the scripts printed by the completion command.
+func runComplete(project string, args []string) {
+	line := strings.Join(args, " ")
+	words := strings.Fields(line)
+	if len(words) == 0 || strings.HasSuffix(line, " ") {
+		words = append(words, "")
+	}
+	if len(words) < 2 {
+		// Only the program name is being typed.
+		return
+	}
+	cur := words[len(words)-1]
+	words = words[1 : len(words)-1] // drop program name and current word
+
+	// Find the -p setting and whether a command has been named.
+	cmd := ""
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		if !strings.HasPrefix(w, "-") || cmd != "" {
+			if cmd == "" {
+				cmd = w
+			}
+			continue
+		}
+		name := strings.TrimLeft(w, "-")
+		if j := strings.Index(name, "="); j >= 0 {
+			if name[:j] == "p" {
+				project = name[j+1:]
+			}
+			continue
+		}
+		f := flag.Lookup(name)
+		if f == nil || isBoolFlag(f) || i+1 >= len(words) {
+			continue
+		}
+		i++
+		if name == "p" {
+			project = words[i]
+		}
+	}
+	if strings.Count(project, "/") != 1 {
+		return
+	}
+
+	var list []string
+	switch {
+	case strings.HasPrefix(cur, "-") && cmd == "":
+		flag.VisitAll(func(f *flag.Flag) {
+			list = append(list, "-"+f.Name)
+		})
+	case cmd == "" && !strings.Contains(cur, ":"):
+		for _, c := range commands {
+			if !strings.HasPrefix(c.name, "__") {
+				list = append(list, c.name)
+			}
+		}
+		list = append(list, queryKeys...)
+	case strings.Contains(cur, ":"):
+		i := strings.Index(cur, ":")
+		key := cur[:i+1]
+		var vals []string
+		switch key {
+		case "label:":
+			vals, _ = cachedNames(project, "labels")
+		case "milestone:":
+			vals, _ = cachedNames(project, "milestones")
+		case "assignee:", "author:", "mentions:":
+			vals, _ = cachedNames(project, "assignees")
+		case "state:":
+			vals = []string{"open", "closed", "all"}
+		case "type:":
+			vals, _ = cachedNames(project, "types")
+		case "no:":
+			vals = []string{"milestone"}
+		case "sort:":
+			vals = []string{"created", "updated", "comments"}
+		}
+		for _, v := range vals {
+			if !strings.Contains(v, " ") {
+				list = append(list, key+v)
+			}
+		}
+	default:
+		list = queryKeys
+	}
+	for _, s := range list {
+		if strings.HasPrefix(s, cur) {
+			fmt.Fprintln(os.Stdout, s)
+		}
+	}
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
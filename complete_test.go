@@ -0,0 +1,84 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// captureStdout returns what f prints on standard output.
+func captureStdout(t *testing.T, f func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	done := make(chan []byte)
+	go func() {
+		data, _ := ioutil.ReadAll(r)
+		done <- data
+	}()
+	f()
+	w.Close()
+	return string(<-done)
+}
+
+var completeTests = []struct {
+	line string
+	want []string // all of the completions, in order
+	has  []string // some of the completions
+}{
+	{line: ""},
+	{line: "issue"},
+	{line: "issue sta", want: []string{"state:"}},
+	{line: "issue state:", want: []string{"state:open", "state:closed", "state:all"}},
+	{line: "issue state:c", want: []string{"state:closed"}},
+	{line: "issue sort:c", want: []string{"sort:created", "sort:comments"}},
+	{line: "issue no:", want: []string{"no:milestone"}},
+	{line: "issue label:bug state:o", want: []string{"state:open"}},
+	{line: "issue com", want: []string{"comment", "completion"}},
+	{line: "issue ", has: []string{"show", "list", "label:", "state:"}},
+	{line: "issue -p other/repo ", has: []string{"show", "state:"}},
+	{line: "issue -p=other/repo sh", want: []string{"show"}},
+	{line: "issue -p", has: []string{"-p"}},
+	{line: "issue -json", want: []string{"-json"}},
+	{line: "issue show ", want: queryKeys},
+	{line: "issue show -", want: nil},
+	{line: "issue -p bad ", want: nil},
+	{line: "issue zzz", want: nil},
+}
+
+func TestComplete(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	for _, tt := range completeTests {
+		out := captureStdout(t, func() { runComplete("golang/go", []string{tt.line}) })
+		got := strings.Fields(out)
+		if tt.has != nil {
+			for _, s := range tt.has {
+				if !contains(got, s) {
+					t.Errorf("complete %q = %q, missing %q", tt.line, got, s)
+				}
+			}
+			continue
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("complete %q = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,268 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A config is the contents of the configuration file,
+// config.yaml in the issue subdirectory of the user's
+// configuration directory. All settings are optional.
+type config struct {
+	// Queries are saved queries, by name.
+	Queries map[string]string `yaml:"queries"`
+
+	// Watch lists the queries and issues that the watch command checks.
+	Watch struct {
+		Interval time.Duration `yaml:"interval"`
+		Queries  []string      `yaml:"queries"` // saved query names or queries
+		Issues   []int         `yaml:"issues"`
+	} `yaml:"watch"`
+
+	// Webhooks are the chat webhooks that the watch command notifies
+	// and scheduled reports can be sent to.
+	Webhooks []webhookConfig `yaml:"webhooks"`
+
+	// PathLabels are rules in CODEOWNERS syntax mapping file paths
+	// to the labels that the suggest-owner command suggests.
+	PathLabels []string `yaml:"path-labels"`
+
+	// Rules are the labeling rules applied by the triage command.
+	Rules []*triageRule `yaml:"rules"`
+
+	// Policies are the triage policies run by the policy command.
+	Policies []*policy `yaml:"policies"`
+
+	// Reports are the reports run by the schedule command.
+	Reports []*report `yaml:"reports"`
+
+	// TimeFormat is the layout for printed times, either a Go time
+	// layout or, if it contains a %, a strftime format.
+	TimeFormat string `yaml:"time-format"`
+	timeLayout string // TimeFormat as a Go layout
+
+	// CollapseAuthors are the logins of the users, such as bots,
+	// whose comments are collapsed to one-line summaries.
+	CollapseAuthors []string `yaml:"collapse-authors"`
+
+	// CommentFilter is a command that each comment's text is piped
+	// through before it is printed.
+	CommentFilter string `yaml:"comment-filter"`
+
+	// Hooks are the commands run before and after changes to issues,
+	// by hook name: pre-put, post-put, or post-create.
+	Hooks map[string]string `yaml:"hooks"`
+
+	// API and UploadAPI are the URLs of the GitHub API and its
+	// upload host, as set by the -api and -upload-api flags.
+	API       string `yaml:"api"`
+	UploadAPI string `yaml:"upload-api"`
+
+	// Transport is how to connect to the API host,
+	// as set by the -transport flag.
+	Transport string `yaml:"transport"`
+
+	// HTTPHeaders are header lines, like "X-Key: value",
+	// added to GitHub API requests, as by -http-header.
+	HTTPHeaders []string `yaml:"http-headers"`
+
+	// TokenCommand is a command that prints a new GitHub token,
+	// run when the token expires.
+	TokenCommand string `yaml:"token-command"`
+
+	// GitHubApp is a GitHub App installation for which
+	// to mint tokens instead, when the token expires.
+	GitHubApp struct {
+		ID           int64  `yaml:"id"`
+		Installation int64  `yaml:"installation"`
+		KeyFile      string `yaml:"key-file"` // the app's private key, in PEM format
+	} `yaml:"github-app"`
+
+	// TUIKeys maps single keys to the batch operations, without the
+	// issue number, that -tui applies to the selected issue.
+	TUIKeys map[string]string `yaml:"tui-keys"`
+}
+
+// A triageRule adds labels, a milestone, or an assignee to the issues
+// whose title or body matches a regular expression.
+type triageRule struct {
+	Match     string   `yaml:"match"`
+	In        string   `yaml:"in"` // title, body, or empty for either
+	Labels    []string `yaml:"labels"`
+	Milestone string   `yaml:"milestone"`
+	Assignee  string   `yaml:"assignee"`
+
+	re *regexp.Regexp
+}
+
+type webhookConfig struct {
+	URL  string `yaml:"url"`
+	Kind string `yaml:"kind"` // slack (the default), mattermost, or matrix
+}
+
+var cfg struct {
+	once sync.Once
+	c    *config
+}
+
+// configFile returns the name of the configuration file.
+func configFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "issue", "config.yaml"), nil
+}
+
+// loadConfig returns the configuration, reading the file on first use.
+// A missing file is the same as an empty one; an invalid one is fatal.
+func loadConfig() *config {
+	cfg.once.Do(func() {
+		cfg.c = new(config)
+		file, err := configFile()
+		if err != nil {
+			return
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				fatal(err)
+			}
+			return
+		}
+		if err := yaml.Unmarshal(data, cfg.c); err != nil {
+			fatalf("%s: %v", file, err)
+		}
+		for i, r := range cfg.c.Rules {
+			re, err := regexp.Compile(r.Match)
+			if err != nil {
+				fatalf("%s: rule %d: %v", file, i+1, err)
+			}
+			r.re = re
+			switch r.In {
+			case "", "title", "body":
+			default:
+				fatalf("%s: rule %d: unknown in: %q (want title or body)", file, i+1, r.In)
+			}
+		}
+		for _, p := range cfg.c.Policies {
+			if err := p.check(); err != nil {
+				fatalf("%s: %v", file, err)
+			}
+		}
+		for _, r := range cfg.c.Reports {
+			if err := r.check(); err != nil {
+				fatalf("%s: %v", file, err)
+			}
+		}
+		if f := cfg.c.TimeFormat; strings.Contains(f, "%") {
+			layout, err := strftimeLayout(f)
+			if err != nil {
+				fatalf("%s: time-format: %v", file, err)
+			}
+			cfg.c.timeLayout = layout
+		} else {
+			cfg.c.timeLayout = f
+		}
+		for k, op := range cfg.c.TUIKeys {
+			if utf8.RuneCountInString(k) != 1 || k == "q" || k == "u" {
+				fatalf("%s: tui-keys: invalid key %q: want a single character other than q or u", file, k)
+			}
+			words, err := splitBatchLine(op)
+			if err != nil || len(words) == 0 {
+				fatalf("%s: tui-keys: %s: invalid operation %q", file, k, op)
+			}
+			// Retitling is not offered, since undo cannot restore a title.
+			switch words[0] {
+			case "comment", "label", "milestone", "close", "reopen":
+			default:
+				fatalf("%s: tui-keys: %s: unknown operation %q", file, k, words[0])
+			}
+		}
+		if a := cfg.c.GitHubApp; a.ID != 0 && (a.Installation == 0 || a.KeyFile == "") {
+			fatalf("%s: github-app: want id, installation, and key-file", file)
+		}
+		if t := cfg.c.Transport; t != "" {
+			if err := checkTransport(t); err != nil {
+				fatalf("%s: %v", file, err)
+			}
+		}
+		for _, h := range cfg.c.HTTPHeaders {
+			if _, _, err := parseHeader(h); err != nil {
+				fatalf("%s: http-headers: %v", file, err)
+			}
+		}
+		for name := range cfg.c.Hooks {
+			switch name {
+			case "pre-put", "post-put", "post-create":
+			default:
+				fatalf("%s: hooks: unknown hook %q: want %s", file, name, strings.Join(hookNames, ", "))
+			}
+		}
+		for _, h := range cfg.c.Webhooks {
+			switch h.Kind {
+			case "", "slack", "mattermost", "matrix":
+			default:
+				fatalf("%s: unknown webhook kind %q", file, h.Kind)
+			}
+		}
+	})
+	return cfg.c
+}
+
+// strftimeLayout converts the strftime format f to a Go time layout.
+func strftimeLayout(f string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(f); i++ {
+		if f[i] != '%' {
+			b.WriteByte(f[i])
+			continue
+		}
+		if i++; i == len(f) {
+			return "", fmt.Errorf("%q ends with %%", f)
+		}
+		layout, ok := map[byte]string{
+			'Y': "2006", 'y': "06", 'm': "01", 'd': "02", 'e': "_2",
+			'H': "15", 'I': "03", 'M': "04", 'S': "05", 'p': "PM",
+			'b': "Jan", 'h': "Jan", 'B': "January", 'a': "Mon", 'A': "Monday",
+			'Z': "MST", 'z': "-0700", 'F': "2006-01-02", 'T': "15:04:05",
+			'R': "15:04", 'D': "01/02/06", '%': "%",
+		}[f[i]]
+		if !ok {
+			return "", fmt.Errorf("unsupported directive %%%c in %q", f[i], f)
+		}
+		b.WriteString(layout)
+	}
+	return b.String(), nil
+}
+
+// savedQuery returns the query saved in the configuration as name,
+// or name itself if there is no such saved query.
+func savedQuery(name string) string {
+	if q, ok := loadConfig().Queries[name]; ok {
+		return q
+	}
+	return name
+}
+
+// describeQuery returns a description of the query q
+// for use in messages: its saved name and, if different, the query.
+func describeQuery(q string) string {
+	if saved := savedQuery(q); saved != q {
+		return fmt.Sprintf("%s (%s)", q, saved)
+	}
+	return q
+}
@@ -0,0 +1,110 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// writeConfig makes text the configuration file, or removes
+// the file if text is "-", and clears the loaded configuration.
+func writeConfig(t *testing.T, text string) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	cfg.once, cfg.c = sync.Once{}, nil
+	t.Cleanup(func() { cfg.once, cfg.c = sync.Once{}, nil })
+	if text == "-" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "issue"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "issue", "config.yaml"), []byte(text), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+var loadConfigTests = []struct {
+	name   string
+	text   string
+	layout string
+	api    string
+	keys   int
+}{
+	{name: "missing", text: "-"},
+	{name: "empty", text: ""},
+	{name: "go layout", text: "time-format: Jan 2 15:04\n", layout: "Jan 2 15:04"},
+	{name: "strftime", text: "time-format: \"%Y-%m-%d %H:%M\"\n", layout: "2006-01-02 15:04"},
+	{name: "strftime percent", text: "time-format: \"%d%% %T\"\n", layout: "02% 15:04:05"},
+	{name: "api", text: "api: https://github.example.com/api/v3/\n", api: "https://github.example.com/api/v3/"},
+	{name: "tui keys", text: "tui-keys:\n  l: label NeedsInfo\n  c: comment \"Thanks!\"\n", keys: 2},
+}
+
+func TestLoadConfig(t *testing.T) {
+	for _, tt := range loadConfigTests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeConfig(t, tt.text)
+			c := loadConfig()
+			if c.timeLayout != tt.layout {
+				t.Errorf("timeLayout = %q, want %q", c.timeLayout, tt.layout)
+			}
+			if c.API != tt.api {
+				t.Errorf("API = %q, want %q", c.API, tt.api)
+			}
+			if len(c.TUIKeys) != tt.keys {
+				t.Errorf("len(TUIKeys) = %d, want %d", len(c.TUIKeys), tt.keys)
+			}
+		})
+	}
+}
+
+var loadConfigErrorTests = []struct {
+	name string
+	text string
+	err  string
+}{
+	{"yaml", "queries: [\n", "config.yaml: yaml:"},
+	{"rule regexp", "rules:\n  - match: \"(\"\n", "rule 1: error parsing regexp"},
+	{"rule in", "rules:\n  - match: x\n    in: labels\n", `rule 1: unknown in: "labels"`},
+	{"strftime", "time-format: \"%Q\"\n", "time-format:"},
+	{"tui key", "tui-keys:\n  q: close\n", `invalid key "q"`},
+	{"tui operation", "tui-keys:\n  t: retitle x\n", `unknown operation "retitle"`},
+	{"github app", "github-app:\n  id: 1\n", "want id, installation, and key-file"},
+	{"hook", "hooks:\n  pre-commit: true\n", `unknown hook "pre-commit"`},
+	{"webhook", "webhooks:\n  - url: https://example.com/\n    kind: irc\n", `unknown webhook kind "irc"`},
+}
+
+// TestLoadConfigErrors checks that invalid configurations are fatal,
+// by loading each in a child process.
+func TestLoadConfigErrors(t *testing.T) {
+	if os.Getenv("ISSUE_TEST_LOADCONFIG") != "" {
+		loadConfig()
+		return
+	}
+	for _, tt := range loadConfigErrorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeConfig(t, tt.text)
+			cmd := exec.Command(os.Args[0], "-test.run=^TestLoadConfigErrors$")
+			cmd.Env = append(os.Environ(), "ISSUE_TEST_LOADCONFIG=1")
+			var stderr bytes.Buffer
+			cmd.Stderr = &stderr
+			err := cmd.Run()
+			if e, ok := err.(*exec.ExitError); !ok || e.ExitCode() != exitError {
+				t.Fatalf("loadConfig: %v, want exit status %d\n%s", err, exitError, stderr.Bytes())
+			}
+			if !strings.Contains(stderr.String(), tt.err) {
+				t.Errorf("loadConfig error:\n%s\nwant %q", stderr.Bytes(), tt.err)
+			}
+		})
+	}
+}
@@ -5,8 +5,10 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -14,8 +16,11 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/go-github/v45/github"
@@ -30,28 +35,28 @@ func editIssue(project string, original []byte, issue *github.Issue) {
 
 	newIssue, _, err := writeIssue(project, issue, updated, false)
 	if err != nil {
-		log.Fatal(err)
+		fatal(err)
 	}
 	if newIssue != nil {
 		issue = newIssue
 	}
-	log.Printf("https://github.com/%s/issues/%d updated", project, getInt(issue.Number))
+	log.Printf("%s updated", issueURL(project, getInt(issue.Number)))
 }
 
 func editText(original []byte) []byte {
 	f, err := ioutil.TempFile("", "issue-edit-")
 	if err != nil {
-		log.Fatal(err)
+		fatal(err)
 	}
 	if err := ioutil.WriteFile(f.Name(), original, 0600); err != nil {
-		log.Fatal(err)
+		fatal(err)
 	}
 	if err := runEditor(f.Name()); err != nil {
-		log.Fatal(err)
+		fatal(err)
 	}
 	updated, err := ioutil.ReadFile(f.Name())
 	if err != nil {
-		log.Fatal(err)
+		fatal(err)
 	}
 	name := f.Name()
 	f.Close()
@@ -92,6 +97,32 @@ func runEditor(filename string) error {
 const bulkHeader = "\nBulk editing these issues:"
 
 func writeIssue(project string, old *github.Issue, updated []byte, isBulk bool) (issue *github.Issue, rate *github.Rate, err error) {
+	issue, rate, _, err = writeIssueChanges(project, old, updated, isBulk, nil)
+	return issue, rate, err
+}
+
+// An issueSteps records the steps of an issue's update that a bulk
+// edit has done, so that resuming an interrupted edit skips them.
+type issueSteps struct {
+	Edited  bool  `json:",omitempty"` // metadata and type changed
+	Labels  bool  `json:",omitempty"` // labels added and removed
+	Comment int64 `json:",omitempty"` // ID of the comment posted
+
+	save func(*issueSteps) // if not nil, records the steps done
+}
+
+// done records the steps done so far.
+func (s *issueSteps) done() {
+	if s.save != nil {
+		s.save(s)
+	}
+}
+
+// writeIssueChanges is like writeIssue but also returns
+// a description of each change it made successfully.
+// If steps is not nil, the steps it records as done are skipped,
+// and those done now are added to it.
+func writeIssueChanges(project string, old *github.Issue, updated []byte, isBulk bool, steps *issueSteps) (issue *github.Issue, rate *github.Rate, did []string, err error) {
 	var errbuf bytes.Buffer
 	defer func() {
 		if errbuf.Len() > 0 {
@@ -103,7 +134,17 @@ func writeIssue(project string, old *github.Issue, updated []byte, isBulk bool)
 	off := 0
 	var edit github.IssueRequest
 	var addLabels, removeLabels []string
-	for _, line := range strings.SplitAfter(sdata, "\n") {
+	var typ *string       // new issue type, which IssueRequest cannot hold
+	var milestone *string // new milestone title, for hooks
+	// Check every header line before making any changes, reporting
+	// each problem with its line number and the values expected.
+	// Only look things up here; the team member to assign and the
+	// missing labels and milestone to create are asked about after.
+	var roster *teamRoster
+	var missingLabels []string
+	var missingMilestone bool
+	for i, line := range strings.SplitAfter(sdata, "\n") {
+		lineno := i + 1
 		off += len(line)
 		line = strings.TrimSpace(line)
 		if line == "" {
@@ -115,40 +156,129 @@ func writeIssue(project string, old *github.Issue, updated []byte, isBulk bool)
 
 		case strings.HasPrefix(line, "Title:"):
 			edit.Title = diff(line, "Title:", getString(old.Title))
+			if edit.Title != nil && *edit.Title == "" {
+				fmt.Fprintf(&errbuf, "line %d: empty Title\n", lineno)
+			}
 
 		case strings.HasPrefix(line, "State:"):
 			edit.State = diff(line, "State:", getString(old.State))
+			if edit.State != nil && *edit.State != "open" && *edit.State != "closed" {
+				fmt.Fprintf(&errbuf, "line %d: invalid State %q (want open or closed)\n", lineno, *edit.State)
+			}
 
 		case strings.HasPrefix(line, "Assignee:"):
+			// A team is replaced by one of its members below,
+			// except when only checking the syntax of a bulk edit.
+			if login := strings.TrimSpace(strings.TrimPrefix(line, "Assignee:")); isTeam(login) && getInt(old.Number) != -1 {
+				r, err := loadTeamRoster(project, login)
+				if err != nil {
+					fmt.Fprintf(&errbuf, "%v\n", err)
+				}
+				roster = r
+				continue
+			}
 			edit.Assignee = diff(line, "Assignee:", getUserLogin(old.Assignee))
+			if edit.Assignee != nil && *edit.Assignee != "" && !checkAssignee(&errbuf, project, *edit.Assignee) {
+				edit.Assignee = nil
+			}
 
 		case strings.HasPrefix(line, "Closed:"):
+			// The closing time cannot be edited, but check
+			// that it has not been garbled into something else.
+			// It may be printed as relative time, or, in acme,
+			// as "2006-01-02 15:04:05, 3 days ago".
+			if t := strings.TrimSpace(strings.TrimPrefix(line, "Closed:")); t != "" && !relativeTimeRE.MatchString(t) {
+				_, err := time.Parse(timeLayout(), t)
+				if i := strings.LastIndex(t, ", "); err != nil && i >= 0 {
+					_, err = time.Parse(timeLayout(), t[:i])
+				}
+				if err != nil {
+					fmt.Fprintf(&errbuf, "line %d: invalid Closed time %q (want %s)\n", lineno, t, timeLayout())
+				}
+			}
+
+		case strings.HasPrefix(line, "Tasks:"):
 			continue
 
 		case strings.HasPrefix(line, "Labels:"):
+			var added []string
 			if isBulk {
 				addLabels, removeLabels = diffList2(line, "Labels:", getLabelNames(old.Labels))
+				added = addLabels
 			} else {
 				edit.Labels = diffList(line, "Labels:", getLabelNames(old.Labels))
+				added, _ = diffList2(line, "Labels:", getLabelNames(old.Labels))
+			}
+			var ok bool
+			if missingLabels, ok = checkLabels(&errbuf, project, added, canPrompt() && !isBulk); !ok {
+				edit.Labels, addLabels, removeLabels = nil, nil, nil
 			}
 
+		case strings.HasPrefix(line, "Type:"):
+			// The type is not part of the bulk edit header,
+			// so a Type line there sets the type of every issue.
+			oldType := ""
+			if !isBulk && getInt(old.Number) > 0 {
+				oldType = getIssueType(project, old)
+			}
+			typ = diff(line, "Type:", oldType)
+
 		case strings.HasPrefix(line, "Milestone:"):
-			edit.Milestone = findMilestone(&errbuf, project, diff(line, "Milestone:", getMilestoneTitle(old.Milestone)))
+			milestone = diff(line, "Milestone:", getMilestoneTitle(old.Milestone))
+			edit.Milestone, missingMilestone = matchMilestone(&errbuf, project, milestone, canPrompt() && !isBulk)
 
 		case strings.HasPrefix(line, "URL:"):
 			continue
 
+		case strings.HasPrefix(line, "PR:"):
+			continue
+
+		case strings.HasPrefix(line, "Branch:"):
+			continue
+
+		case strings.HasPrefix(line, "CLs:"):
+			continue
+
+		case strings.HasPrefix(line, "Participants:"):
+			continue
+
 		default:
-			fmt.Fprintf(&errbuf, "unknown summary line: %s\n", line)
+			fmt.Fprintf(&errbuf, "line %d: unknown header line: %s (want Title, State, Assignee, Labels, Type, or Milestone)\n", lineno, line)
 		}
 	}
 
 	if errbuf.Len() > 0 {
-		return nil, nil, nil
+		return nil, nil, nil, nil
+	}
+
+	// Every line checks out, so now create what is missing
+	// and choose the team member to assign.
+	if len(missingLabels) > 0 && !offerLabels(&errbuf, project, missingLabels) {
+		return nil, nil, nil, nil
+	}
+	if missingMilestone {
+		if edit.Milestone = offerMilestone(&errbuf, project, *milestone); edit.Milestone == nil {
+			return nil, nil, nil, nil
+		}
+	}
+	if roster != nil {
+		member, err := roster.choose(canPrompt() && !isBulk)
+		if err != nil {
+			fmt.Fprintf(&errbuf, "%v\n", err)
+			return nil, nil, nil, nil
+		}
+		edit.Assignee = diff("Assignee: "+member, "Assignee:", getUserLogin(old.Assignee))
+		if edit.Assignee != nil && !checkAssignee(&errbuf, project, *edit.Assignee) {
+			return nil, nil, nil, nil
+		}
 	}
 
 	if getInt(old.Number) == 0 {
-		comment := strings.TrimSpace(sdata[off:])
+		comment, err := gistBody(fmt.Sprintf("Attachment for new %s issue", project), strings.TrimSpace(sdata[off:]))
+		if err != nil {
+			fmt.Fprintf(&errbuf, "%v\n", err)
+			return nil, nil, nil, nil
+		}
 		edit.Body = &comment
 		issue, resp, err := client.Issues.Create(context.TODO(), projectOwner(project), projectRepo(project), &edit)
 		if resp != nil {
@@ -156,14 +286,30 @@ func writeIssue(project string, old *github.Issue, updated []byte, isBulk bool)
 		}
 		if err != nil {
 			fmt.Fprintf(&errbuf, "error creating issue: %v\n", err)
-			return nil, rate, nil
+			return nil, rate, nil, nil
+		}
+		if typ != nil && *typ != "" {
+			if err := setIssueType(project, getInt(issue.Number), *typ); err != nil {
+				fmt.Fprintf(&errbuf, "created issue #%d but could not set type: %v\n", getInt(issue.Number), err)
+			}
 		}
-		return issue, rate, nil
+		runPostHook("post-create", &hookChange{
+			Project:   project,
+			Number:    getInt(issue.Number),
+			URL:       issueURL(project, getInt(issue.Number)),
+			State:     getString(issue.State),
+			Title:     edit.Title,
+			Assignee:  edit.Assignee,
+			Labels:    edit.Labels,
+			Milestone: milestone,
+			Type:      typ,
+		})
+		return issue, rate, nil, nil
 	}
 
 	if getInt(old.Number) == -1 {
 		// Asking to just sanity check the text parsing.
-		return nil, nil, nil
+		return nil, nil, nil, nil
 	}
 
 	marker := "\nReported by "
@@ -175,57 +321,114 @@ func writeIssue(project string, old *github.Issue, updated []byte, isBulk bool)
 		comment = strings.TrimSpace(sdata[off:i])
 	}
 
-	if comment == "<optional comment here>" {
+	if comment == "<optional comment here>" || isBareReply(comment) {
 		comment = ""
 	}
 
-	var failed bool
-	var did []string
-	if comment != "" {
-		_, resp, err := client.Issues.CreateComment(context.TODO(), projectOwner(project), projectRepo(project), getInt(old.Number), &github.IssueComment{
-			Body: &comment,
-		})
-		if resp != nil {
-			rate = &resp.Rate
-		}
-		if err != nil {
-			fmt.Fprintf(&errbuf, "error saving comment: %v\n", err)
-			failed = true
-		} else {
-			did = append(did, "saved comment")
+	change := &hookChange{
+		Project:      project,
+		Number:       getInt(old.Number),
+		URL:          issueURL(project, getInt(old.Number)),
+		State:        getString(old.State),
+		Title:        edit.Title,
+		NewState:     edit.State,
+		Assignee:     edit.Assignee,
+		Labels:       edit.Labels,
+		AddLabels:    addLabels,
+		RemoveLabels: removeLabels,
+		Milestone:    milestone,
+		Type:         typ,
+		Comment:      comment,
+	}
+	if comment != "" || edit.Title != nil || edit.State != nil || edit.Assignee != nil || edit.Labels != nil || milestone != nil || typ != nil || len(addLabels) > 0 || len(removeLabels) > 0 {
+		if err := runHook("pre-put", change); err != nil {
+			fmt.Fprintf(&errbuf, "%v\n", err)
+			return nil, nil, nil, nil
 		}
 	}
 
-	if edit.Title != nil || edit.State != nil || edit.Assignee != nil || edit.Labels != nil || edit.Milestone != nil {
-		_, resp, err := client.Issues.Edit(context.TODO(), projectOwner(project), projectRepo(project), getInt(old.Number), &edit)
-		if resp != nil {
-			rate = &resp.Rate
+	// Post the comment only after the other changes have been made,
+	// so that a failed edit leaves no comment describing it.
+	// In a bulk edit, steps records what has been done,
+	// so that resuming an interrupted edit does not repeat it.
+	if steps == nil {
+		steps = new(issueSteps)
+	}
+	// Check the -max-requests budget for all the writes at once,
+	// so that it cannot run out partway through them.
+	writes := 0
+	if !steps.Edited {
+		if edit.Title != nil || edit.State != nil || edit.Assignee != nil || edit.Labels != nil || edit.Milestone != nil {
+			writes++
 		}
-		if err != nil {
-			fmt.Fprintf(&errbuf, "error changing metadata: %v\n", err)
-			failed = true
-		} else {
-			did = append(did, "updated metadata")
+		if typ != nil {
+			writes++
 		}
 	}
-	if len(addLabels) > 0 {
-		_, resp, err := client.Issues.AddLabelsToIssue(context.TODO(), projectOwner(project), projectRepo(project), getInt(old.Number), addLabels)
-		if resp != nil {
-			rate = &resp.Rate
+	if !steps.Labels {
+		if len(addLabels) > 0 {
+			writes++
 		}
-		if err != nil {
-			fmt.Fprintf(&errbuf, "error adding labels: %v\n", err)
-			failed = true
-		} else {
-			if len(addLabels) == 1 {
-				did = append(did, "added label "+addLabels[0])
+		writes += len(removeLabels)
+	}
+	if comment != "" && steps.Comment == 0 {
+		writes++
+	}
+	if writes > 0 {
+		if err := beginOperation(writes); err != nil {
+			fmt.Fprintf(&errbuf, "%v\n", err)
+			return nil, nil, nil, nil
+		}
+		defer endOperation()
+	}
+	var failed bool
+	if !steps.Edited && (edit.Title != nil || edit.State != nil || edit.Assignee != nil || edit.Labels != nil || edit.Milestone != nil || typ != nil) {
+		if edit.Title != nil || edit.State != nil || edit.Assignee != nil || edit.Labels != nil || edit.Milestone != nil {
+			_, resp, err := client.Issues.Edit(context.TODO(), projectOwner(project), projectRepo(project), getInt(old.Number), &edit)
+			if resp != nil {
+				rate = &resp.Rate
+			}
+			if err != nil {
+				fmt.Fprintf(&errbuf, "error changing metadata: %v\n", err)
+				failed = true
 			} else {
-				did = append(did, "added labels")
+				did = append(did, "updated metadata")
 			}
 		}
+		if typ != nil && !failed {
+			if err := setIssueType(project, getInt(old.Number), *typ); err != nil {
+				fmt.Fprintf(&errbuf, "error changing type: %v\n", err)
+				failed = true
+			} else {
+				did = append(did, "updated type")
+			}
+		}
+		if !failed {
+			steps.Edited = true
+			steps.done()
+		}
 	}
-	if len(removeLabels) > 0 {
+	if !steps.Labels && !failed && (len(addLabels) > 0 || len(removeLabels) > 0) {
+		if len(addLabels) > 0 {
+			_, resp, err := client.Issues.AddLabelsToIssue(context.TODO(), projectOwner(project), projectRepo(project), getInt(old.Number), addLabels)
+			if resp != nil {
+				rate = &resp.Rate
+			}
+			if err != nil {
+				fmt.Fprintf(&errbuf, "error adding labels: %v\n", err)
+				failed = true
+			} else {
+				if len(addLabels) == 1 {
+					did = append(did, "added label "+addLabels[0])
+				} else {
+					did = append(did, "added labels")
+				}
+			}
+		}
 		for _, label := range removeLabels {
+			if failed {
+				break
+			}
 			resp, err := client.Issues.RemoveLabelForIssue(context.TODO(), projectOwner(project), projectRepo(project), getInt(old.Number), label)
 			if resp != nil {
 				rate = &resp.Rate
@@ -237,6 +440,38 @@ func writeIssue(project string, old *github.Issue, updated []byte, isBulk bool)
 				did = append(did, "removed label "+label)
 			}
 		}
+		if !failed {
+			steps.Labels = true
+			steps.done()
+		}
+	}
+	if comment != "" && steps.Comment == 0 && !failed {
+		comment, err = gistBody(fmt.Sprintf("Attachment for %s#%d", project, getInt(old.Number)), comment)
+		if err != nil {
+			fmt.Fprintf(&errbuf, "%v\n", err)
+			failed = true
+		}
+	}
+	if comment != "" && steps.Comment == 0 && !failed {
+		com, resp, err := client.Issues.CreateComment(context.TODO(), projectOwner(project), projectRepo(project), getInt(old.Number), &github.IssueComment{
+			Body: &comment,
+		})
+		if resp != nil {
+			rate = &resp.Rate
+		}
+		if err != nil {
+			fmt.Fprintf(&errbuf, "error saving comment: %v\n", err)
+			failed = true
+		} else {
+			did = append(did, "saved comment")
+			steps.Comment = com.GetID()
+			steps.done()
+		}
+	}
+
+	if len(did) > 0 {
+		change.Changes = did
+		runPostHook("post-put", change)
 	}
 
 	if failed && len(did) > 0 {
@@ -307,26 +542,277 @@ func diffList2(line, field string, old []string) (added, removed []string) {
 }
 
 func findMilestone(w io.Writer, project string, name *string) *int {
+	id, _ := matchMilestone(w, project, name, false)
+	return id
+}
+
+// findOrCreateMilestone returns the number of the open milestone named
+// by name: the one with that exact title, or else the only one whose
+// title matches it ignoring case, or begins with it ignoring case.
+// If no milestone matches, and create is set and the user can push to
+// the repository, it offers to create the milestone, asking for its
+// due date. Otherwise it writes an error to w and returns nil.
+func findOrCreateMilestone(w io.Writer, project string, name *string, create bool) *int {
+	id, missing := matchMilestone(w, project, name, create)
+	if missing {
+		return offerMilestone(w, project, *name)
+	}
+	return id
+}
+
+// matchMilestone is like findOrCreateMilestone but only looks:
+// when it would offer to create the milestone, it reports it missing
+// instead, leaving offerMilestone to ask.
+func matchMilestone(w io.Writer, project string, name *string, create bool) (id *int, missing bool) {
 	if name == nil {
-		return nil
+		return nil, false
 	}
 
 	all, err := loadMilestones(project)
 	if err != nil {
 		fmt.Fprintf(w, "Error loading milestone list: %v\n\tIgnoring milestone change.\n", err)
-		return nil
+		return nil, false
+	}
+
+	var titles []string
+	var folded, prefix []*github.Milestone
+	for _, m := range all {
+		title := getString(m.Title)
+		titles = append(titles, title)
+		switch {
+		case title == *name:
+			return m.Number, false
+		case *name == "":
+		case strings.EqualFold(title, *name):
+			folded = append(folded, m)
+		case strings.HasPrefix(strings.ToLower(title), strings.ToLower(*name)):
+			prefix = append(prefix, m)
+		}
+	}
+	matches := folded
+	if len(matches) == 0 {
+		matches = prefix
+	}
+	if len(matches) == 1 {
+		return matches[0].Number, false
+	}
+	if len(matches) > 1 {
+		var list []string
+		for _, m := range matches {
+			list = append(list, getString(m.Title))
+		}
+		fmt.Fprintf(w, "Ambiguous milestone: %s (matches %s)\n", *name, strings.Join(list, ", "))
+		return nil, false
 	}
 
+	if create && *name != "" && canPush(project) {
+		return nil, true
+	}
+	fmt.Fprintf(w, "Unknown milestone: %s%s\n", *name, didYouMean(*name, titles))
+	return nil, false
+}
+
+// offerMilestone asks whether to create the milestone name,
+// which matchMilestone reported missing, and creates it if so.
+// Otherwise it writes an error to w and returns nil.
+func offerMilestone(w io.Writer, project, name string) *int {
+	var titles []string
+	all, _ := loadMilestones(project)
 	for _, m := range all {
-		if getString(m.Title) == *name {
-			return m.Number
+		titles = append(titles, getString(m.Title))
+	}
+	fmt.Fprintf(os.Stderr, "milestone %s does not exist%s; create it? [y/N] ", name, didYouMean(name, titles))
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if ans := strings.ToLower(strings.TrimSpace(line)); ans == "y" || ans == "yes" {
+		m, err := createMilestone(project, name)
+		if err != nil {
+			fmt.Fprintf(w, "Creating milestone %s: %v\n", name, err)
+			return nil
+		}
+		return m.Number
+	}
+	fmt.Fprintf(w, "Unknown milestone: %s%s\n", name, didYouMean(name, titles))
+	return nil
+}
+
+// createMilestone creates the milestone title in project,
+// asking on standard error for its optional due date.
+func createMilestone(project, title string) (*github.Milestone, error) {
+	m := &github.Milestone{Title: &title}
+	for {
+		fmt.Fprintf(os.Stderr, "due date for %s (YYYY-MM-DD, or empty for none): ", title)
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		line = strings.TrimSpace(line)
+		if line == "" || err != nil {
+			break
+		}
+		if due, err := time.Parse("2006-01-02", line); err == nil {
+			m.DueOn = &due
+			break
+		}
+		fmt.Fprintf(os.Stderr, "want a date like 2025-08-01\n")
+	}
+	m, _, err := client.Issues.CreateMilestone(context.TODO(), projectOwner(project), projectRepo(project), m)
+	if err != nil {
+		return nil, err
+	}
+	loadNames(project, "milestones")
+	return m, nil
+}
+
+// relativeTimeRE matches the times printed by relativeTime.
+var relativeTimeRE = regexp.MustCompile(`^(just now|in [0-9]+ [a-z]+|[0-9]+ [a-z]+ ago)$`)
+
+// checkAssignee reports whether login can be assigned issues in project.
+// If not, it writes an error to w suggesting the closest assignable logins,
+// so that a misspelled name fails before GitHub rejects the update.
+func checkAssignee(w io.Writer, project, login string) bool {
+	ok, names := checkName(project, "assignees", login)
+	if !ok {
+		fmt.Fprintf(w, "Unknown assignee: %s%s\n", login, didYouMean(login, names))
+	}
+	return ok
+}
+
+// checkLabels reports whether the labels added to an issue all exist
+// in project. Those that do not are returned as missing, to be offered
+// to offerLabels, if prompt is set and the user can push to the
+// repository; otherwise checkLabels writes an error to w suggesting
+// the closest existing labels.
+func checkLabels(w io.Writer, project string, added []string, prompt bool) (missing []string, ok bool) {
+	ok = true
+	for _, name := range added {
+		found, names := checkName(project, "labels", name)
+		if found {
+			continue
+		}
+		if prompt && canPush(project) {
+			missing = append(missing, name)
+			continue
 		}
+		fmt.Fprintf(w, "Unknown label: %s%s\n", name, didYouMean(name, names))
+		ok = false
 	}
+	return missing, ok
+}
 
-	fmt.Fprintf(w, "Unknown milestone: %s\n", *name)
+// offerLabels asks whether to create each of the missing labels,
+// asking for the color of each created, and reports whether all were.
+// For each not created, it writes an error to w.
+func offerLabels(w io.Writer, project string, missing []string) bool {
+	ok := true
+	for _, name := range missing {
+		_, names := checkName(project, "labels", name)
+		fmt.Fprintf(os.Stderr, "label %s does not exist%s; create it? [y/N] ", name, didYouMean(name, names))
+		line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if ans := strings.ToLower(strings.TrimSpace(line)); ans == "y" || ans == "yes" {
+			if err := createLabel(project, name); err != nil {
+				fmt.Fprintf(w, "Creating label %s: %v\n", name, err)
+				ok = false
+			}
+			continue
+		}
+		fmt.Fprintf(w, "Unknown label: %s%s\n", name, didYouMean(name, names))
+		ok = false
+	}
+	return ok
+}
+
+// createLabel creates the label name in project,
+// asking on standard error for its color.
+func createLabel(project, name string) error {
+	color := "ededed" // GitHub's default
+	for {
+		fmt.Fprintf(os.Stderr, "color for %s [%s]: ", name, color)
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		line = strings.TrimPrefix(strings.TrimSpace(line), "#")
+		if line == "" || err != nil {
+			break
+		}
+		if _, err := strconv.ParseUint(line, 16, 32); err == nil && len(line) == 6 {
+			color = strings.ToLower(line)
+			break
+		}
+		fmt.Fprintf(os.Stderr, "want a hex color like d73a4a\n")
+	}
+	if _, _, err := client.Issues.CreateLabel(context.TODO(), projectOwner(project), projectRepo(project), &github.Label{Name: &name, Color: &color}); err != nil {
+		return err
+	}
+	loadNames(project, "labels")
 	return nil
 }
 
+// canPush reports whether the authenticated user can push to project,
+// which is required to create labels and milestones.
+func canPush(project string) bool {
+	repo, _, err := client.Repositories.Get(context.TODO(), projectOwner(project), projectRepo(project))
+	return err == nil && repo.GetPermissions()["push"]
+}
+
+// didYouMean returns a suggestion of the names similar to name,
+// like " (did you mean bradfitz?)", or the empty string if there are none.
+func didYouMean(name string, names []string) string {
+	similar := similarNames(name, names)
+	if len(similar) == 0 {
+		return ""
+	}
+	return " (did you mean " + strings.Join(similar, " or ") + "?)"
+}
+
+// similarNames returns the names closest to name, at most three,
+// that are within a few single-letter edits of it, ignoring case.
+func similarNames(name string, names []string) []string {
+	max := len(name) / 3
+	if max < 1 {
+		max = 1
+	}
+	type match struct {
+		name string
+		dist int
+	}
+	var matches []match
+	for _, n := range names {
+		if d := editDistance(strings.ToLower(name), strings.ToLower(n)); d <= max {
+			matches = append(matches, match{n, d})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].dist < matches[j].dist })
+	var out []string
+	for i := 0; i < len(matches) && i < 3; i++ {
+		out = append(out, matches[i].name)
+	}
+	return out
+}
+
+// editDistance returns the Levenshtein distance between a and b:
+// the number of single-byte insertions, deletions, and substitutions
+// that turn one into the other.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
 func readBulkIDs(text []byte) []int {
 	var ids []int
 	for _, line := range strings.Split(string(text), "\n") {
@@ -345,17 +831,17 @@ func readBulkIDs(text []byte) []int {
 	return ids
 }
 
-func bulkEditStartFromText(project string, content []byte) (base *github.Issue, original []byte, err error) {
+func bulkEditStartFromText(project string, content []byte) (base *github.Issue, original []byte, shown map[int]time.Time, err error) {
 	ids := readBulkIDs(content)
 	if len(ids) == 0 {
-		return nil, nil, fmt.Errorf("found no issues in selection")
+		return nil, nil, nil, fmt.Errorf("found no issues in selection")
 	}
 	issues, err := bulkReadIssuesCached(project, ids)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
-	base, original = bulkEditStart(issues)
-	return base, original, nil
+	base, original, shown = bulkEditStart(issues)
+	return base, original, shown, nil
 }
 
 func suffix(n int) string {
@@ -366,19 +852,22 @@ func suffix(n int) string {
 }
 
 func bulkEditIssues(project string, issues []*github.Issue) {
-	base, original := bulkEditStart(issues)
+	base, original, shown := bulkEditStart(issues)
 	updated := editText(original)
 	if bytes.Equal(original, updated) {
 		log.Print("no changes made")
 		return
 	}
-	ids, err := bulkWriteIssue(project, base, updated, func(s string) { log.Print(s) })
+	status, progress, finish := bulkProgress()
+	ids, results, err := bulkWriteIssue(project, base, updated, shown, status, progress)
+	finish()
+	printBulkResults(os.Stdout, results)
 	if err != nil {
 		errText := strings.Replace(err.Error(), "\n", "\t\n", -1)
 		if len(ids) > 0 {
-			log.Fatalf("updated %d issue%s with errors:\n\t%v", len(ids), suffix(len(ids)), errText)
+			fatalf("updated %d issue%s with errors:\n\t%v", len(ids), suffix(len(ids)), errText)
 		}
-		log.Fatal(errText)
+		fatal(errText)
 	}
 	suffix := ""
 	if len(ids) > 1 {
@@ -387,9 +876,14 @@ func bulkEditIssues(project string, issues []*github.Issue) {
 	log.Printf("updated %d issue%s", len(ids), suffix)
 }
 
-func bulkEditStart(issues []*github.Issue) (*github.Issue, []byte) {
+// bulkEditStart returns the common state of issues, the text of
+// a bulk edit of them, and the times they were last updated,
+// by issue number, for bulkWriteIssue to detect later changes.
+func bulkEditStart(issues []*github.Issue) (*github.Issue, []byte, map[int]time.Time) {
 	common := new(github.Issue)
+	shown := make(map[int]time.Time)
 	for i, issue := range issues {
+		shown[getInt(issue.Number)] = getTime(issue.UpdatedAt)
 		if i == 0 {
 			common.State = issue.State
 			common.Assignee = issue.Assignee
@@ -420,7 +914,7 @@ func bulkEditStart(issues []*github.Issue) (*github.Issue, []byte) {
 		fmt.Fprintf(&buf, "%d\t%s\n", getInt(issue.Number), getString(issue.Title))
 	}
 
-	return common, buf.Bytes()
+	return common, buf.Bytes(), shown
 }
 
 func commonString(x, y string) string {
@@ -447,14 +941,25 @@ func commonLabels(x, y []*github.Label) []*github.Label {
 	return out
 }
 
-func bulkWriteIssue(project string, old *github.Issue, updated []byte, status func(string)) (ids []int, err error) {
+// bulkWorkers is the number of issues a bulk edit updates at once.
+// GitHub discourages many concurrent requests, so it is kept small.
+const bulkWorkers = 4
+
+// bulkWriteIssue applies the bulk edit text updated, made from the
+// header old, to the issues it lists, reporting progress through status
+// or, if it is not nil, progress. It returns the issue numbers
+// and the result of the edit for each.
+// The issues that were updated after the times in shown, the update
+// times when the edit was started, are skipped and reported,
+// so that the edit does not overwrite changes made meanwhile.
+func bulkWriteIssue(project string, old *github.Issue, updated []byte, shown map[int]time.Time, status func(string), progress func(done, total int)) (ids []int, results []*BulkResult, err error) {
 	i := bytes.Index(updated, []byte(bulkHeader))
 	if i < 0 {
-		return nil, fmt.Errorf("cannot find bulk edit issue list")
+		return nil, nil, fmt.Errorf("cannot find bulk edit issue list")
 	}
 	ids = readBulkIDs(updated[i:])
 	if len(ids) == 0 {
-		return nil, fmt.Errorf("found no issues in bulk edit issue list")
+		return nil, nil, fmt.Errorf("found no issues in bulk edit issue list")
 	}
 
 	// Make a copy of the issue to modify.
@@ -464,9 +969,15 @@ func bulkWriteIssue(project string, old *github.Issue, updated []byte, status fu
 	// Try a write to issue -1, checking for formatting only.
 	old.Number = new(int)
 	*old.Number = -1
-	_, rate, err := writeIssue(project, old, updated, true)
+	if _, _, err := writeIssue(project, old, updated, true); err != nil {
+		return nil, nil, err
+	}
+
+	// Record the edit in a journal, so that it can be resumed if interrupted.
+	j, err := createJournal(project, old, updated, ids, shown)
 	if err != nil {
-		return nil, err
+		status(fmt.Sprintf("cannot write journal: %v", err))
+		j = nil
 	}
 
 	// Apply to all issues in list.
@@ -475,41 +986,238 @@ func bulkWriteIssue(project string, old *github.Issue, updated []byte, status fu
 		suffix = "s"
 	}
 	status(fmt.Sprintf("updating %d issue%s", len(ids), suffix))
+	results, err = applyBulkEdit(project, old, updated, ids, shown, j, status, progress)
+	return ids, results, err
+}
 
-	failed := false
-	for index, number := range ids {
-		if index%10 == 0 && index > 0 {
-			status(fmt.Sprintf("updated %d/%d issues", index, len(ids)))
-		}
-		// Check rate limits here (in contrast to everywhere else in this program)
-		// to avoid needless failure halfway through the loop.
-		for rate != nil && rate.Limit > 0 && rate.Remaining == 0 {
-			delta := (rate.Reset.Sub(time.Now())/time.Minute + 2) * time.Minute
-			if delta < 0 {
-				delta = 2 * time.Minute
-			}
-			status(fmt.Sprintf("updated %d/%d issues; pausing %d minutes to respect GitHub rate limit", index, len(ids), int(delta/time.Minute)))
-			time.Sleep(delta)
-			limits, _, err := client.RateLimits(context.TODO())
-			if err != nil {
-				status(fmt.Sprintf("reading rate limit: %v", err))
-			}
-			rate = nil
-			if limits != nil {
-				rate = limits.Core
-			}
+// A BulkResult is the outcome of a bulk edit for one issue.
+type BulkResult struct {
+	Number     int
+	Changes    []string // changes made successfully
+	Error      string   `json:",omitempty"`
+	Skipped    bool     `json:",omitempty"` // changed since loaded, so not updated
+	NotTried   bool     `json:",omitempty"` // not updated after an atomic edit failed
+	RolledBack bool     `json:",omitempty"` // changes undone after an atomic edit failed
+}
+
+// printBulkResults prints a table of the results of a bulk edit,
+// one issue per line, with the changes made and any error,
+// or, with -json, the results as JSON.
+func printBulkResults(w io.Writer, results []*BulkResult) {
+	if len(results) == 0 {
+		return
+	}
+	if *jsonFlag != 0 {
+		data, err := json.MarshalIndent(results, "", "\t")
+		if err != nil {
+			fatal(err)
+		}
+		w.Write(append(data, '\n'))
+		return
+	}
+	for _, r := range results {
+		changes := strings.Join(r.Changes, ", ")
+		if changes == "" {
+			changes = "no changes"
 		}
-		*old.Number = number
-		if _, rate, err = writeIssue(project, old, updated, true); err != nil {
-			status(fmt.Sprintf("writing #%d: %s", number, strings.Replace(err.Error(), "\n", "\n\t", -1)))
+		result := "ok"
+		switch {
+		case r.NotTried:
+			result = "not attempted"
+		case r.Skipped:
+			result = "skipped: " + r.Error
+		case r.Error != "":
+			result = "error: " + strings.Replace(r.Error, "\n", "; ", -1)
+		}
+		if r.RolledBack {
+			result += " (rolled back)"
+		}
+		fmt.Fprintf(w, "#%d\t%s\t%s\n", r.Number, changes, result)
+	}
+}
+
+// applyBulkEdit applies the bulk edit text updated, made from the
+// header old, to the issues ids, recording each update in the journal j,
+// if not nil. It checks for changes and reports progress as described
+// for bulkWriteIssue.
+func applyBulkEdit(project string, old *github.Issue, updated []byte, ids []int, shown map[int]time.Time, j *bulkJournal, status func(string), progress func(done, total int)) ([]*BulkResult, error) {
+	// Update the issues concurrently, bounded by bulkWorkers.
+	// The results are reported afterward, in the order of the list.
+	// With -atomic, the first failure stops the edit,
+	// and the issues already changed are then restored.
+	var rate *github.Rate
+	results := make([]*BulkResult, len(ids))
+	for i, id := range ids {
+		results[i] = &BulkResult{Number: id, NotTried: true}
+	}
+	var saved []*issueState
+	if *atomicFlag {
+		saved = make([]*issueState, len(ids))
+	}
+	var (
+		mu      sync.Mutex
+		next    int  // index of next issue to update
+		done    int  // number of issues updated
+		stopped bool // an atomic edit has failed
+		wg      sync.WaitGroup
+	)
+	for w := 0; w < bulkWorkers && w < len(ids); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			issue := *old
+			issue.Number = new(int)
+			for {
+				mu.Lock()
+				// Stop between issues when the -max-requests
+				// budget has run out, leaving the rest to resume.
+				if next == len(ids) || stopped || budgetStopped() {
+					mu.Unlock()
+					return
+				}
+				i := next
+				next++
+				// Check rate limits here (in contrast to everywhere else in this program)
+				// to avoid needless failure halfway through the loop.
+				// Holding mu makes the other workers wait out the pause too.
+				rate = waitRateLimit(rate, fmt.Sprintf("updated %d/%d issues", done, len(ids)), status)
+				loaded := shown[ids[i]]
+				mu.Unlock()
+
+				// An issue whose update an earlier run began has been
+				// changed by that run, not by others, so resume its
+				// update where it stopped, without checking for changes.
+				steps := j.progress(ids[i])
+				resumed := steps != nil
+				if steps == nil {
+					steps = new(issueSteps)
+				}
+				n := ids[i]
+				steps.save = func(s *issueSteps) { j.step(n, s) }
+
+				*issue.Number = ids[i]
+				var r *github.Rate
+				var st *issueState
+				var did []string
+				var err error
+				skip := false
+				if saved != nil || shown != nil {
+					st, err = saveIssueState(project, ids[i])
+				}
+				if err == nil && !resumed && !loaded.IsZero() && st.updated.After(loaded) {
+					err = fmt.Errorf("skipped: updated at %s, after it was loaded", formatTime(st.updated))
+					skip = true
+				}
+				if err == nil {
+					if saved != nil {
+						saved[i] = st
+					}
+					if !resumed {
+						steps.done() // the update has begun
+					}
+					_, r, did, err = writeIssueChanges(project, &issue, updated, true, steps)
+					if st != nil {
+						st.comment = steps.Comment
+					}
+				}
+
+				mu.Lock()
+				if r != nil {
+					rate = r
+				}
+				results[i] = &BulkResult{Number: ids[i], Changes: did, Skipped: skip}
+				if err != nil {
+					results[i].Error = err.Error()
+				}
+				if err == nil {
+					// Putting the edit again must not skip this issue
+					// for having been changed by the edit itself.
+					delete(shown, ids[i])
+				}
+				if err == nil || skip {
+					// A skipped issue is not retried by resume either:
+					// it needs a new edit, made with its changes in view.
+					j.record(ids[i])
+				}
+				if err != nil && saved != nil {
+					stopped = true
+				}
+				done++
+				if progress != nil {
+					progress(done, len(ids))
+				} else if done%10 == 0 && done < len(ids) {
+					status(fmt.Sprintf("updated %d/%d issues", done, len(ids)))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	failed := false
+	for _, r := range results {
+		if r.Error != "" {
 			failed = true
 		}
 	}
+	if failed && saved != nil {
+		status("rolling back changes")
+		n := 0
+		for i, ok := range rollback(project, saved, updated, status) {
+			if ok {
+				results[i].RolledBack = true
+				n++
+			}
+		}
+		j.discard()
+		return results, fmt.Errorf("failed to update all issues; restored %d issue%s", n, suffix(n))
+	}
+	if !j.finish() {
+		status(fmt.Sprintf("run 'issue resume %s' to retry the failed updates", j.file))
+	}
 
 	if failed {
-		return ids, fmt.Errorf("failed to update all issues")
+		return results, fmt.Errorf("failed to update all issues")
+	}
+	return results, nil
+}
+
+// bulkProgress returns the functions with which a bulk edit run from
+// the command line reports its status and progress, and a function to
+// call when it is done. On a terminal, the progress is shown in a bar,
+// with any messages above it.
+func bulkProgress() (status func(string), progress func(done, total int), finish func()) {
+	status = func(s string) { log.Print(s) }
+	finish = func() {}
+	if isTerminal(os.Stderr) {
+		bar := newProgressBar(os.Stderr, "updating")
+		status, progress, finish = bar.log, bar.update, bar.finish
+	}
+	return status, progress, finish
+}
+
+// waitRateLimit sleeps until GitHub's rate limit resets if rate shows
+// that no requests remain, reporting the pause through status
+// prefixed by a description of the progress so far.
+// It returns the rate limit in effect after the pause.
+func waitRateLimit(rate *github.Rate, progress string, status func(string)) *github.Rate {
+	for rate != nil && rate.Limit > 0 && rate.Remaining == 0 {
+		delta := (rate.Reset.Sub(time.Now())/time.Minute + 2) * time.Minute
+		if delta < 0 {
+			delta = 2 * time.Minute
+		}
+		status(fmt.Sprintf("%s; pausing %d minutes to respect GitHub rate limit", progress, int(delta/time.Minute)))
+		time.Sleep(delta)
+		limits, _, err := client.RateLimits(context.TODO())
+		if err != nil {
+			status(fmt.Sprintf("reading rate limit: %v", err))
+		}
+		rate = nil
+		if limits != nil {
+			rate = limits.Core
+		}
 	}
-	return ids, nil
+	return rate
 }
 
 func projectOwner(project string) string {
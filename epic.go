@@ -0,0 +1,150 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// An epic is the tree of umbrella issues in a milestone.
+// An umbrella issue is one whose body has a task list;
+// task items that refer to other issues make those issues its children.
+type epic struct {
+	project string
+	issues  map[int]*github.Issue
+	child   map[int]bool // issues that appear as a task of some umbrella
+}
+
+func newEpic(project string, issues []*github.Issue) *epic {
+	e := &epic{
+		project: project,
+		issues:  make(map[int]*github.Issue),
+		child:   make(map[int]bool),
+	}
+	for _, issue := range issues {
+		e.issues[getInt(issue.Number)] = issue
+	}
+	for _, issue := range issues {
+		for _, t := range parseTasks(getString(issue.Body)) {
+			if ref := e.taskRef(t); ref != 0 && ref != getInt(issue.Number) {
+				e.child[ref] = true
+			}
+		}
+	}
+	return e
+}
+
+// taskRef returns the issue number that task t refers to, or 0.
+func (e *epic) taskRef(t *Task) int {
+	if refs := issueRefs(e.project, t.Text); len(refs) > 0 {
+		return refs[0]
+	}
+	return 0
+}
+
+// taskDone reports whether t is complete: either checked off,
+// or referring to an issue in the milestone that has been closed.
+func (e *epic) taskDone(t *Task) bool {
+	if t.Done {
+		return true
+	}
+	issue := e.issues[e.taskRef(t)]
+	return issue != nil && getString(issue.State) == "closed"
+}
+
+func (e *epic) progress(issue *github.Issue) string {
+	tasks := parseTasks(getString(issue.Body))
+	if len(tasks) == 0 {
+		return ""
+	}
+	done := 0
+	for _, t := range tasks {
+		if e.taskDone(t) {
+			done++
+		}
+	}
+	return fmt.Sprintf("\t%d/%d (%d%%)", done, len(tasks), done*100/len(tasks))
+}
+
+func (e *epic) print(w io.Writer, issue *github.Issue, indent string, seen map[int]bool) {
+	n := getInt(issue.Number)
+	seen[n] = true
+	for _, t := range parseTasks(getString(issue.Body)) {
+		box := "[ ]"
+		if e.taskDone(t) {
+			box = "[x]"
+		}
+		ref := e.taskRef(t)
+		child := e.issues[ref]
+		if child == nil || seen[ref] {
+			fmt.Fprintf(w, "%s%s %s\n", indent, box, t.Text)
+			continue
+		}
+		fmt.Fprintf(w, "%s%s #%d\t%s%s\n", indent, box, ref, getString(child.Title), e.progress(child))
+		e.print(w, child, indent+"\t", seen)
+	}
+	delete(seen, n)
+}
+
+func (e *epic) write(w io.Writer) {
+	var roots, rest []*github.Issue
+	for _, issue := range e.issues {
+		switch {
+		case e.child[getInt(issue.Number)]:
+			// printed beneath its umbrella
+		case len(parseTasks(getString(issue.Body))) > 0:
+			roots = append(roots, issue)
+		default:
+			rest = append(rest, issue)
+		}
+	}
+	sort.Sort(issuesByTitle(roots))
+	sort.Sort(issuesByTitle(rest))
+	for _, issue := range roots {
+		fmt.Fprintf(w, "#%d\t%s%s\n", getInt(issue.Number), getString(issue.Title), e.progress(issue))
+		e.print(w, issue, "\t", make(map[int]bool))
+	}
+	if len(rest) > 0 {
+		if len(roots) > 0 {
+			fmt.Fprintf(w, "\n")
+		}
+		for _, issue := range rest {
+			state := ""
+			if getString(issue.State) == "closed" {
+				state = "\t(closed)"
+			}
+			fmt.Fprintf(w, "#%d\t%s%s\n", getInt(issue.Number), getString(issue.Title), state)
+		}
+	}
+}
+
+func runEpic(project string, args []string) {
+	c := lookupCommand("epic")
+	fs := c.flags()
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+	}
+	name := strings.Join(fs.Args(), " ")
+	id := findMilestone(ioutil.Discard, project, &name)
+	if id == nil {
+		fatalf("unknown milestone: %s", name)
+	}
+	issues, err := listRepoIssues(project, github.IssueListByRepoOptions{
+		Milestone: fmt.Sprint(*id),
+		State:     "all",
+	})
+	if err != nil {
+		fatal(err)
+	}
+	newEpic(project, issues).write(os.Stdout)
+}
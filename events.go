@@ -0,0 +1,196 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// An eventPrinter prints a timeline event in an issue's text.
+type eventPrinter func(w io.Writer, project string, ev *github.IssueEvent)
+
+// eventPrinters maps each timeline event type to its printer.
+// A nil printer means the event is not shown at all.
+// Event types missing from the map are printed by printEventDefault,
+// so that events GitHub adds later still appear, if less nicely.
+var eventPrinters = map[string]eventPrinter{
+	"mentioned":    nil,
+	"subscribed":   nil,
+	"unsubscribed": nil,
+
+	"closed":     printCommitEvent,
+	"referenced": printCommitEvent,
+	"merged":     printCommitEvent,
+
+	"assigned":     printAssigneeEvent,
+	"unassigned":   printAssigneeEvent,
+	"labeled":      printLabelEvent,
+	"unlabeled":    printLabelEvent,
+	"milestoned":   printMilestoneEvent,
+	"demilestoned": printMilestoneEvent,
+	"renamed":      printRenameEvent,
+	"locked":       printLockEvent,
+
+	"review_requested":       printReviewRequestEvent,
+	"review_request_removed": printReviewRequestEvent,
+	"review_dismissed":       printReviewDismissedEvent,
+
+	"added_to_project":         printProjectEvent,
+	"moved_columns_in_project": printProjectEvent,
+	"removed_from_project":     printProjectEvent,
+
+	"base_ref_changed":        eventText("changed the base branch"),
+	"comment_deleted":         eventText("deleted a comment"),
+	"connected":               eventText("linked a pull request or issue"),
+	"convert_to_draft":        eventText("converted the pull request to a draft"),
+	"converted_note_to_issue": eventText("created the issue from a project note"),
+	"converted_to_discussion": eventText("converted the issue to a discussion"),
+	"deployed":                eventText("deployed the pull request"),
+	"disconnected":            eventText("unlinked a pull request or issue"),
+	"head_ref_deleted":        eventText("deleted the head branch"),
+	"head_ref_force_pushed":   eventText("force-pushed the head branch"),
+	"head_ref_restored":       eventText("restored the head branch"),
+	"marked_as_duplicate":     eventText("marked the issue as a duplicate"),
+	"pinned":                  eventText("pinned the issue"),
+	"ready_for_review":        eventText("marked the pull request ready for review"),
+	"transferred":             eventText("transferred the issue from another repository"),
+	"unmarked_as_duplicate":   eventText("unmarked the issue as a duplicate"),
+	"unpinned":                eventText("unpinned the issue"),
+	"user_blocked":            eventText("blocked a user"),
+}
+
+// printEvent prints the timeline event using its printer in eventPrinters.
+func printEvent(w io.Writer, project string, ev *github.IssueEvent) {
+	p, ok := eventPrinters[getString(ev.Event)]
+	if !ok {
+		p = printEventDefault
+	}
+	if p != nil {
+		p(w, project, ev)
+	}
+}
+
+// printEventLine prints the usual line for an event:
+// the actor, a description of what happened, and the time.
+func printEventLine(w io.Writer, ev *github.IssueEvent, what string) {
+	fmt.Fprintf(w, "\n* %s %s (%s)\n", getUserLogin(ev.Actor), what, formatTime(getTime(ev.CreatedAt)))
+}
+
+// printEventDefault prints an event with no printer of its own,
+// spelling out the event type, as in "reopened" or "auto merge enabled".
+func printEventDefault(w io.Writer, project string, ev *github.IssueEvent) {
+	printEventLine(w, ev, strings.Replace(getString(ev.Event), "_", " ", -1))
+}
+
+// eventText returns a printer describing an event with fixed text.
+func eventText(what string) eventPrinter {
+	return func(w io.Writer, project string, ev *github.IssueEvent) {
+		printEventLine(w, ev, what)
+	}
+}
+
+// printCommitEvent prints a closed, referenced, or merged event,
+// followed by the commit involved, if any.
+func printCommitEvent(w io.Writer, project string, ev *github.IssueEvent) {
+	event := getString(ev.Event)
+	id := getString(ev.CommitID)
+	if id == "" {
+		printEventLine(w, ev, event)
+		return
+	}
+	short := id
+	if len(short) > 7 {
+		short = short[:7]
+	}
+	printEventLine(w, ev, event+" in commit "+short)
+	commit, _, err := client.Git.GetCommit(context.TODO(), projectOwner(project), projectRepo(project), id)
+	if err == nil {
+		fmt.Fprintf(w, "\n\tAuthor: %s <%s> %s\n\tCommitter: %s <%s> %s\n\n\t%s\n",
+			getString(commit.Author.Name), getString(commit.Author.Email), formatTime(getTime(commit.Author.Date)),
+			getString(commit.Committer.Name), getString(commit.Committer.Email), formatTime(getTime(commit.Committer.Date)),
+			wrap(getString(commit.Message), "\t"))
+	}
+}
+
+func printAssigneeEvent(w io.Writer, project string, ev *github.IssueEvent) {
+	printEventLine(w, ev, getString(ev.Event)+" "+getUserLogin(ev.Assignee))
+}
+
+func printLabelEvent(w io.Writer, project string, ev *github.IssueEvent) {
+	printEventLine(w, ev, getString(ev.Event)+" "+ev.GetLabel().GetName())
+}
+
+func printMilestoneEvent(w io.Writer, project string, ev *github.IssueEvent) {
+	what := "added to milestone"
+	if getString(ev.Event) == "demilestoned" {
+		what = "removed from milestone"
+	}
+	printEventLine(w, ev, what+" "+ev.GetMilestone().GetTitle())
+}
+
+func printRenameEvent(w io.Writer, project string, ev *github.IssueEvent) {
+	printEventLine(w, ev, "changed title")
+	fmt.Fprintf(w, "  - %s\n  + %s\n", ev.GetRename().GetFrom(), ev.GetRename().GetTo())
+}
+
+func printLockEvent(w io.Writer, project string, ev *github.IssueEvent) {
+	what := "locked the conversation"
+	if reason := ev.GetLockReason(); reason != "" {
+		what += " as " + reason
+	}
+	printEventLine(w, ev, what)
+}
+
+// printReviewRequestEvent prints a review_requested or
+// review_request_removed event. Requests for a team's review
+// have no RequestedReviewer.
+func printReviewRequestEvent(w io.Writer, project string, ev *github.IssueEvent) {
+	who := "a team"
+	if ev.RequestedReviewer != nil {
+		who = getUserLogin(ev.RequestedReviewer)
+	}
+	what := "requested review from " + who
+	if getString(ev.Event) == "review_request_removed" {
+		what = "removed review request for " + who
+	}
+	printEventLine(w, ev, what)
+}
+
+func printReviewDismissedEvent(w io.Writer, project string, ev *github.IssueEvent) {
+	printEventLine(w, ev, "dismissed a review")
+	if msg := ev.GetDismissedReview().GetDismissalMessage(); msg != "" {
+		fmt.Fprintf(w, "\n\t%s\n", wrap(msg, "\t"))
+	}
+}
+
+// printProjectEvent prints a project board event,
+// naming the column when GitHub reports it.
+func printProjectEvent(w io.Writer, project string, ev *github.IssueEvent) {
+	card := ev.GetProjectCard()
+	var what string
+	switch getString(ev.Event) {
+	case "added_to_project":
+		what = "added to a project"
+		if col := card.GetColumnName(); col != "" {
+			what += " in column " + col
+		}
+	case "removed_from_project":
+		what = "removed from a project"
+	case "moved_columns_in_project":
+		what = "moved to another project column"
+		if col := card.GetColumnName(); col != "" {
+			what = "moved to project column " + col
+			if prev := card.GetPreviousColumnName(); prev != "" {
+				what += " from " + prev
+			}
+		}
+	}
+	printEventLine(w, ev, what)
+}
@@ -0,0 +1,65 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// Exit statuses, documented for use by scripts.
+const (
+	exitNoMatch = 1 // the query matched no issues
+	exitUsage   = 2 // invalid flags or arguments
+	exitError   = 3 // GitHub API or other failure
+	exitAuth    = 4 // missing, invalid, or rejected credentials
+)
+
+// fatal is like log.Fatal but exits with exitAuth if any of its
+// arguments is an authentication failure from the GitHub API,
+// and exitError otherwise.
+func fatal(v ...interface{}) {
+	log.Print(v...)
+	exit(exitStatus(v))
+}
+
+// fatalf is like log.Fatalf, with the exit status chosen as in fatal.
+func fatalf(format string, v ...interface{}) {
+	log.Print(fmt.Sprintf(format, v...))
+	exit(exitStatus(v))
+}
+
+// usageErrorf reports an invalid command line and exits with exitUsage.
+func usageErrorf(format string, v ...interface{}) {
+	log.Print(fmt.Sprintf(format, v...))
+	exit(exitUsage)
+}
+
+// exit prints the API request summary, if -max-requests is set,
+// saves the rate limit usage counts, and exits with the given status.
+func exit(code int) {
+	printRequestSummary()
+	saveUsage()
+	os.Exit(code)
+}
+
+func exitStatus(v []interface{}) int {
+	for _, x := range v {
+		err, ok := x.(error)
+		if !ok {
+			continue
+		}
+		var e *github.ErrorResponse
+		if errors.As(err, &e) && e.Response != nil && e.Response.StatusCode == http.StatusUnauthorized {
+			return exitAuth
+		}
+	}
+	return exitError
+}
@@ -0,0 +1,159 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// feedEntries is the maximum number of entries in a feed.
+const feedEntries = 50
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Link    atomLink    `xml:"link"`
+	Updated string      `xml:"updated"`
+	Author  atomPerson  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Link       atomLink       `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Author     atomPerson     `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Content    atomText       `xml:"content"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// writeFeed writes an Atom feed of the most recently updated issues
+// in the list, which are the results of the query q.
+func writeFeed(w io.Writer, project, q string, issues []*Issue2) error {
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Updated.After(issues[j].Updated) })
+	if len(issues) > feedEntries {
+		issues = issues[:feedEntries]
+	}
+	search := webURL() + project + "/issues?q=" + url.QueryEscape(q)
+	feed := &atomFeed{
+		Title:   project + " issues",
+		ID:      search,
+		Link:    atomLink{Href: search},
+		Updated: time.Now().UTC().Format(time.RFC3339),
+		Author:  atomPerson{Name: project},
+	}
+	if q != "" {
+		feed.Title += ": " + q
+	}
+	if len(issues) > 0 {
+		feed.Updated = issues[0].Updated.UTC().Format(time.RFC3339)
+	}
+	for _, issue := range issues {
+		u := strings.TrimSpace(issue.URL)
+		e := atomEntry{
+			Title:     fmt.Sprintf("#%d %s", issue.Number, issue.Title),
+			ID:        u,
+			Link:      atomLink{Href: u},
+			Published: issue.Created.UTC().Format(time.RFC3339),
+			Updated:   issue.Updated.UTC().Format(time.RFC3339),
+			Author:    atomPerson{Name: issue.Reporter},
+			Content:   atomText{Type: "text", Text: issue.Text},
+		}
+		for _, label := range issue.Labels {
+			e.Categories = append(e.Categories, atomCategory{Term: label})
+		}
+		feed.Entries = append(feed.Entries, e)
+	}
+	io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "\t")
+	if err := enc.Encode(feed); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func runFeed(project string, args []string) {
+	fs := lookupCommand("feed").flags()
+	file := fs.String("o", "", "write the feed to `file`")
+	parseFlags(fs, args)
+
+	q := strings.Join(fs.Args(), " ")
+	all, err := searchIssues(project, q)
+	if err != nil {
+		fatal(err)
+	}
+	list, err := toJSON2List(project, all)
+	if err != nil {
+		fatal(err)
+	}
+
+	if *file == "" {
+		if err := writeFeed(os.Stdout, project, q, list); err != nil {
+			fatal(err)
+		}
+		return
+	}
+	f, err := os.Create(*file)
+	if err != nil {
+		fatal(err)
+	}
+	if err := writeFeed(f, project, q, list); err != nil {
+		fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		fatal(err)
+	}
+}
+
+// serveFeed serves /feed?q=query, an Atom feed of the issues matching query.
+func (s *server) serveFeed(w http.ResponseWriter, r *http.Request) {
+	q := r.FormValue("q")
+	data, stale, err := s.search(q, 2)
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+	var list []*Issue2
+	if err := json.Unmarshal(data, &list); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if stale {
+		w.Header().Set("Warning", staleWarning)
+	}
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	writeFeed(w, s.project, q, list)
+}
@@ -0,0 +1,122 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// parseFieldList parses the -field flag, a comma-separated list of
+// field paths like "Number" or "Comments.Author", returning each path
+// as a list of exact field names.
+func parseFieldList(list string) ([][]string, error) {
+	var paths [][]string
+	for _, f := range strings.Split(list, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		t := reflect.TypeOf(Issue{})
+		var path []string
+		for _, name := range strings.Split(f, ".") {
+			for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+				t = t.Elem()
+			}
+			if t.Kind() != reflect.Struct {
+				return nil, fmt.Errorf("invalid field %s: %s has no fields", f, strings.Join(path, "."))
+			}
+			sf, ok := t.FieldByNameFunc(func(s string) bool { return strings.EqualFold(s, name) })
+			if !ok || sf.PkgPath != "" {
+				return nil, fmt.Errorf("unknown field %s", f)
+			}
+			path = append(path, sf.Name)
+			t = sf.Type
+		}
+		paths = append(paths, path)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no fields listed")
+	}
+	return paths, nil
+}
+
+// fieldsNeedComments reports whether any of the paths refers to Comments,
+// which are only loaded when needed.
+func fieldsNeedComments(paths [][]string) bool {
+	for _, p := range paths {
+		if p[0] == "Comments" {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldValues returns the text of each value found by following path from v.
+// Following a path through a slice yields a value for each element.
+func fieldValues(v reflect.Value, path []string) []string {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Slice {
+		var out []string
+		for i := 0; i < v.Len(); i++ {
+			out = append(out, fieldValues(v.Index(i), path)...)
+		}
+		return out
+	}
+	if len(path) > 0 {
+		return fieldValues(v.FieldByName(path[0]), path[1:])
+	}
+	switch x := v.Interface().(type) {
+	case time.Time:
+		if x.IsZero() {
+			return []string{""}
+		}
+		if layout := loadConfig().timeLayout; layout != "" {
+			return []string{x.In(timeZone).Format(layout)}
+		}
+		return []string{x.Format(time.RFC3339)}
+	case string:
+		// Keep each record on one line.
+		x = strings.TrimSpace(x)
+		x = strings.Replace(x, "\r\n", "\n", -1)
+		x = strings.Replace(x, "\\", `\\`, -1)
+		x = strings.Replace(x, "\n", `\n`, -1)
+		x = strings.Replace(x, "\t", `\t`, -1)
+		return []string{x}
+	}
+	return []string{fmt.Sprint(v.Interface())}
+}
+
+// showFields prints the fields of each issue selected by the -field flag,
+// one issue per line, with fields separated by tabs.
+// List-valued fields are printed with elements separated by commas.
+func showFields(w io.Writer, project string, issues []*github.Issue, paths [][]string) {
+	for _, issue := range issues {
+		var j *Issue
+		if fieldsNeedComments(paths) {
+			var err error
+			if j, err = toJSONWithComments(project, issue); err != nil {
+				fatal(err)
+			}
+		} else {
+			j = toJSON(project, issue)
+		}
+		var out []string
+		for _, p := range paths {
+			out = append(out, strings.Join(fieldValues(reflect.ValueOf(j), p), ","))
+		}
+		fmt.Fprintf(w, "%s\n", strings.Join(out, "\t"))
+	}
+}
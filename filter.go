@@ -0,0 +1,53 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// filterComment runs the comment-filter command from the configuration,
+// if any, on the text of the comment, returning the text to print in its
+// place. Empty output hides the comment. If the command fails, the
+// failure is logged and the original text is printed instead.
+func filterComment(com *github.IssueComment) string {
+	filter := loadConfig().CommentFilter
+	if filter == "" {
+		return com.GetBody()
+	}
+
+	cmd := userCommand(filter)
+	cmd.Env = append(os.Environ(),
+		"ISSUE_COMMENT_ID="+fmt.Sprint(com.GetID()),
+		"ISSUE_COMMENT_AUTHOR="+getUserLogin(com.User),
+		"ISSUE_COMMENT_URL="+com.GetHTMLURL())
+	cmd.Stdin = strings.NewReader(com.GetBody())
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		log.Printf("comment-filter on comment %d: %v\n%s", com.GetID(), err, stderr.Bytes())
+		return com.GetBody()
+	}
+	return string(out)
+}
+
+// printFilteredText prints the comment's text after the comment-filter,
+// or a note in its place if the filter hid the comment.
+func printFilteredText(w io.Writer, com *github.IssueComment) {
+	text := filterComment(com)
+	if strings.TrimSpace(text) == "" && strings.TrimSpace(com.GetBody()) != "" {
+		fmt.Fprintf(w, "\n\t[comment hidden by comment-filter]\n")
+		return
+	}
+	printText(w, &text, *bothFlag)
+}
@@ -0,0 +1,97 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// pkgPrefixRE matches the "pkg: " prefix of a title like "cmd/go: fix build".
+var pkgPrefixRE = regexp.MustCompile(`^[A-Za-z0-9_./,{} -]+: `)
+
+// fixMessage returns a commit message skeleton for a change fixing
+// issue n: the title, prefixed by "pkg: " if it names no package,
+// and a "Fixes #n." line.
+func fixMessage(n int, title string) string {
+	if !pkgPrefixRE.MatchString(title) {
+		title = "pkg: " + title
+	}
+	return fmt.Sprintf("%s\n\nFixes #%d.\n", title, n)
+}
+
+// fixmsgHookMarker identifies the prepare-commit-msg hooks
+// installed by issue fixmsg -hook, which may be replaced.
+const fixmsgHookMarker = "# Installed by issue fixmsg -hook."
+
+// fixmsgHook is the prepare-commit-msg hook installed by issue fixmsg -hook.
+// When a commit is made without a message on a branch named for an issue,
+// like those created by issue branch, it fills in the message skeleton.
+const fixmsgHook = `#!/bin/sh
+` + fixmsgHookMarker + `
+# On a branch named for an issue, like 1234-fix-parser,
+# start the commit message with issue fixmsg's skeleton.
+case "$2" in
+"") ;;
+*) exit 0 ;; # message given by -m, -F, a template, a merge, and so on
+esac
+n=$(git symbolic-ref --short HEAD 2>/dev/null | sed -n 's/^\([0-9][0-9]*\)\(-.*\)\{0,1\}$/\1/p')
+[ -n "$n" ] || exit 0
+msg=$(issue -p %s fixmsg "$n") || exit 0
+{ echo "$msg"; cat "$1"; } >"$1.issue" && mv "$1.issue" "$1"
+`
+
+// installFixmsgHook installs the prepare-commit-msg hook into the current
+// git repository. It does not replace a different existing hook unless force is set.
+func installFixmsgHook(project string, force bool) error {
+	out, err := exec.Command("git", "rev-parse", "--git-path", "hooks").Output()
+	if err != nil {
+		return fmt.Errorf("finding git hooks directory: %v", err)
+	}
+	file := filepath.Join(strings.TrimSpace(string(out)), "prepare-commit-msg")
+	if old, err := ioutil.ReadFile(file); err == nil && !bytes.Contains(old, []byte(fixmsgHookMarker)) && !force {
+		return fmt.Errorf("%s already exists; use -f to replace it", file)
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(file, []byte(fmt.Sprintf(fixmsgHook, project)), 0777); err != nil {
+		return err
+	}
+	log.Printf("installed %s", file)
+	return nil
+}
+
+func runFixmsg(project string, args []string) {
+	fs := lookupCommand("fixmsg").flags()
+	hook := fs.Bool("hook", false, "install a prepare-commit-msg hook filling in the message on issue branches")
+	force := fs.Bool("f", false, "with -hook, replace an existing prepare-commit-msg hook")
+	parseFlags(fs, args)
+	if *hook {
+		if fs.NArg() != 0 {
+			fs.Usage()
+		}
+		if err := installFixmsgHook(project, *force); err != nil {
+			fatal(err)
+		}
+		return
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+	}
+	n := issueArgs(fs, fs.Args())[0]
+	issue, err := getIssue(project, n)
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Print(fixMessage(n, getString(issue.Title)))
+}
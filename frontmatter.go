@@ -0,0 +1,71 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// issueFile is the front matter of a Markdown file describing a new issue,
+// as read by "issue create -f".
+type issueFile struct {
+	Title     string     `yaml:"title"`
+	Labels    stringList `yaml:"labels"`
+	Assignee  string     `yaml:"assignee"`
+	Assignees stringList `yaml:"assignees"`
+	Milestone string     `yaml:"milestone"`
+}
+
+// A stringList is a list of strings that may be written in YAML
+// either as a sequence or as a single comma-separated string.
+type stringList []string
+
+func (l *stringList) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		*l = nil
+		for _, f := range strings.Split(n.Value, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				*l = append(*l, f)
+			}
+		}
+		return nil
+	}
+	var list []string
+	if err := n.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// parseIssueFile splits a Markdown file into its YAML front matter,
+// delimited by lines containing just "---", and the remaining body text.
+// A file without front matter is all body.
+func parseIssueFile(data []byte) (*issueFile, string, error) {
+	f := new(issueFile)
+	text := strings.Replace(string(data), "\r\n", "\n", -1)
+	if !strings.HasPrefix(text, "---\n") {
+		return f, text, nil
+	}
+	text = text[len("---\n"):]
+	var front, body string
+	switch i := strings.Index(text, "\n---\n"); {
+	case strings.HasPrefix(text, "---\n"):
+		body = text[len("---\n"):]
+	case i >= 0:
+		front, body = text[:i+1], text[i+len("\n---\n"):]
+	case strings.HasSuffix(text, "\n---"):
+		front = strings.TrimSuffix(text, "---")
+	default:
+		return nil, "", fmt.Errorf("front matter not terminated by ---")
+	}
+	if err := yaml.Unmarshal([]byte(front), f); err != nil {
+		return nil, "", fmt.Errorf("parsing front matter: %v", err)
+	}
+	return f, body, nil
+}
@@ -0,0 +1,453 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"9fans.net/go/plan9"
+	plan9client "9fans.net/go/plan9/client"
+	"github.com/google/go-github/v45/github"
+)
+
+// An fsServer serves GitHub issues as a 9P file tree:
+//
+//	/owner/repo/n/header	the issue's header lines
+//	/owner/repo/n/body	the issue's text
+//	/owner/repo/n/comments	the issue's comments
+//	/owner/repo/n/new	text written here is posted as a comment
+//
+// Directories list only the projects and issues walked to so far,
+// since listing every issue in a large project would take
+// hundreds of API calls.
+type fsServer struct {
+	mu   sync.Mutex
+	seen map[string]map[int]bool // project -> issue numbers walked to
+	user string
+}
+
+// fsFiles lists the files in an issue directory.
+var fsFiles = []string{"header", "body", "comments", "new"}
+
+var fsNameRE = regexp.MustCompile(`\A[A-Za-z0-9_.-]+\z`)
+
+// An fsFid is the state of a 9P fid: a path in the file tree
+// and, once opened, the contents being read or written.
+type fsFid struct {
+	path []string
+	open bool
+	data []byte       // contents of an open file
+	ents [][]byte     // entries of an open directory
+	buf  bytes.Buffer // text written to new
+}
+
+func (f *fsFid) isDir() bool { return len(f.path) < 4 }
+
+// project returns the owner/repo named by the fid's path.
+func (f *fsFid) project() string { return f.path[0] + "/" + f.path[1] }
+
+// number returns the issue number named by the fid's path.
+func (f *fsFid) number() int {
+	n, _ := strconv.Atoi(f.path[2])
+	return n
+}
+
+func runFS(project string, args []string) {
+	fs := lookupCommand("fs").flags()
+	name := fs.String("name", "issue", "post the service as `name` in the name space directory")
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+	}
+
+	ns := plan9client.Namespace()
+	if err := os.MkdirAll(ns, 0700); err != nil {
+		fatal(err)
+	}
+	addr := filepath.Join(ns, *name)
+	os.Remove(addr) // left behind by an earlier server
+	l, err := net.Listen("unix", addr)
+	if err != nil {
+		fatal(err)
+	}
+	user := os.Getenv("USER")
+	if user == "" {
+		user = "none"
+	}
+	s := &fsServer{
+		seen: map[string]map[int]bool{project: {}},
+		user: user,
+	}
+	log.Printf("serving %s; mount with 9pfuse %s /n/issue", addr, addr)
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			fatal(err)
+		}
+		go s.serveConn(c)
+	}
+}
+
+// serveConn serves 9P requests on c, one at a time.
+func (s *fsServer) serveConn(c net.Conn) {
+	defer c.Close()
+	fids := make(map[uint32]*fsFid)
+	msize := uint32(8192 + plan9.IOHDRSIZE)
+	for {
+		tx, err := plan9.ReadFcall(c)
+		if err != nil {
+			return
+		}
+		rx, err := s.handle(fids, &msize, tx)
+		if err != nil {
+			rx = &plan9.Fcall{Type: plan9.Rerror, Ename: err.Error()}
+		}
+		rx.Tag = tx.Tag
+		if err := plan9.WriteFcall(c, rx); err != nil {
+			return
+		}
+	}
+}
+
+var (
+	errNoFid   = errors.New("unknown fid")
+	errNoFile  = errors.New("file does not exist")
+	errPerm    = errors.New("permission denied")
+	errFidOpen = errors.New("fid is open")
+)
+
+func (s *fsServer) handle(fids map[uint32]*fsFid, msize *uint32, tx *plan9.Fcall) (*plan9.Fcall, error) {
+	if tx.Type == plan9.Tversion {
+		for fid := range fids {
+			delete(fids, fid)
+		}
+		if tx.Msize < *msize {
+			*msize = tx.Msize
+		}
+		version := "9P2000"
+		if !strings.HasPrefix(tx.Version, "9P2000") {
+			version = "unknown"
+		}
+		return &plan9.Fcall{Type: plan9.Rversion, Msize: *msize, Version: version}, nil
+	}
+	if tx.Type == plan9.Tauth {
+		return nil, errors.New("authentication not required")
+	}
+	if tx.Type == plan9.Tattach {
+		if fids[tx.Fid] != nil {
+			return nil, errors.New("fid in use")
+		}
+		f := new(fsFid)
+		fids[tx.Fid] = f
+		return &plan9.Fcall{Type: plan9.Rattach, Qid: fsQid(f.path)}, nil
+	}
+	if tx.Type == plan9.Tflush {
+		return &plan9.Fcall{Type: plan9.Rflush}, nil
+	}
+
+	f := fids[tx.Fid]
+	if f == nil {
+		return nil, errNoFid
+	}
+	switch tx.Type {
+	case plan9.Twalk:
+		if f.open {
+			return nil, errFidOpen
+		}
+		path := append([]string(nil), f.path...)
+		var qids []plan9.Qid
+		for i, name := range tx.Wname {
+			next, err := s.walk(path, name)
+			if err != nil {
+				if i == 0 {
+					return nil, err
+				}
+				break
+			}
+			path = next
+			qids = append(qids, fsQid(path))
+		}
+		if len(qids) == len(tx.Wname) {
+			if tx.Newfid != tx.Fid && fids[tx.Newfid] != nil {
+				return nil, errors.New("fid in use")
+			}
+			fids[tx.Newfid] = &fsFid{path: path}
+		}
+		return &plan9.Fcall{Type: plan9.Rwalk, Wqid: qids}, nil
+
+	case plan9.Topen:
+		if f.open {
+			return nil, errFidOpen
+		}
+		if err := s.open(f, tx.Mode&3); err != nil {
+			return nil, err
+		}
+		f.open = true
+		return &plan9.Fcall{Type: plan9.Ropen, Qid: fsQid(f.path), Iounit: *msize - plan9.IOHDRSIZE}, nil
+
+	case plan9.Tread:
+		if !f.open {
+			return nil, errors.New("fid not open")
+		}
+		return &plan9.Fcall{Type: plan9.Rread, Data: f.read(tx.Offset, tx.Count)}, nil
+
+	case plan9.Twrite:
+		if !f.open || f.isDir() || f.path[3] != "new" {
+			return nil, errPerm
+		}
+		f.buf.Write(tx.Data)
+		return &plan9.Fcall{Type: plan9.Rwrite, Count: uint32(len(tx.Data))}, nil
+
+	case plan9.Tclunk, plan9.Tremove:
+		delete(fids, tx.Fid)
+		if f.open && !f.isDir() && f.path[3] == "new" && strings.TrimSpace(f.buf.String()) != "" {
+			if err := commentIssue(f.project(), f.number(), f.buf.String()); err != nil {
+				return nil, err
+			}
+		}
+		if tx.Type == plan9.Tremove {
+			return nil, errPerm
+		}
+		return &plan9.Fcall{Type: plan9.Rclunk}, nil
+
+	case plan9.Tstat:
+		d := s.stat(f.path)
+		stat, err := d.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		return &plan9.Fcall{Type: plan9.Rstat, Stat: stat}, nil
+
+	case plan9.Twstat:
+		// Allow the truncation that accompanies writes to new,
+		// as from a shell's > redirection, but change nothing.
+		if !f.isDir() && f.path[3] == "new" {
+			return &plan9.Fcall{Type: plan9.Rwstat}, nil
+		}
+		return nil, errPerm
+	}
+	return nil, errPerm
+}
+
+// walk returns the result of walking from path to name.
+func (s *fsServer) walk(path []string, name string) ([]string, error) {
+	if name == ".." {
+		if len(path) == 0 {
+			return path, nil
+		}
+		return path[:len(path)-1], nil
+	}
+	next := append(path[:len(path):len(path)], name)
+	switch len(path) {
+	case 0, 1:
+		if !fsNameRE.MatchString(name) {
+			return nil, errNoFile
+		}
+	case 2:
+		n, err := strconv.Atoi(name)
+		if err != nil || n <= 0 || fmt.Sprint(n) != name {
+			return nil, errNoFile
+		}
+		project := path[0] + "/" + path[1]
+		if _, err := bulkReadIssuesCached(project, []int{n}); err != nil {
+			return nil, errNoFile
+		}
+		s.mu.Lock()
+		if s.seen[project] == nil {
+			s.seen[project] = make(map[int]bool)
+		}
+		s.seen[project][n] = true
+		s.mu.Unlock()
+	case 3:
+		for _, file := range fsFiles {
+			if name == file {
+				return next, nil
+			}
+		}
+		return nil, errNoFile
+	default:
+		return nil, errNoFile
+	}
+	return next, nil
+}
+
+// open prepares f for reading or writing, according to mode.
+func (s *fsServer) open(f *fsFid, mode uint8) error {
+	if f.isDir() {
+		if mode != plan9.OREAD {
+			return errPerm
+		}
+		for _, name := range s.list(f.path) {
+			d := s.stat(append(f.path[:len(f.path):len(f.path)], name))
+			ent, err := d.Bytes()
+			if err != nil {
+				return err
+			}
+			f.ents = append(f.ents, ent)
+		}
+		return nil
+	}
+	if f.path[3] == "new" {
+		if mode != plan9.OWRITE {
+			return errPerm
+		}
+		return nil
+	}
+	if mode != plan9.OREAD {
+		return errPerm
+	}
+	project, n := f.project(), f.number()
+	issue, err := getIssue(project, n)
+	if err != nil {
+		return err
+	}
+	updateIssueCache(project, issue)
+	var buf bytes.Buffer
+	switch f.path[3] {
+	case "header":
+		printIssueHeader(&buf, project, issue)
+	case "body":
+		buf.WriteString(getString(issue.Body))
+		if buf.Len() > 0 && !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+			buf.WriteString("\n")
+		}
+	case "comments":
+		for page := 1; ; {
+			list, resp, err := client.Issues.ListComments(context.TODO(), projectOwner(project), projectRepo(project), n, &github.IssueListCommentsOptions{
+				ListOptions: github.ListOptions{
+					Page:    page,
+					PerPage: 100,
+				},
+			})
+			if err != nil {
+				return err
+			}
+			for _, com := range list {
+				printComment(&buf, com)
+			}
+			if resp.NextPage < page {
+				break
+			}
+			page = resp.NextPage
+		}
+	}
+	f.data = buf.Bytes()
+	return nil
+}
+
+// read returns up to count bytes of f's contents at offset.
+// Directory reads return only whole entries.
+func (f *fsFid) read(offset uint64, count uint32) []byte {
+	if !f.isDir() {
+		if offset >= uint64(len(f.data)) {
+			return nil
+		}
+		data := f.data[offset:]
+		if uint64(len(data)) > uint64(count) {
+			data = data[:count]
+		}
+		return data
+	}
+	var data []byte
+	off := uint64(0)
+	for _, ent := range f.ents {
+		if off >= offset {
+			if len(data)+len(ent) > int(count) {
+				break
+			}
+			data = append(data, ent...)
+		}
+		off += uint64(len(ent))
+	}
+	return data
+}
+
+// list returns the names in the directory path.
+func (s *fsServer) list(path []string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	switch len(path) {
+	case 0:
+		for project := range s.seen {
+			names = append(names, projectOwner(project))
+		}
+	case 1:
+		for project := range s.seen {
+			if projectOwner(project) == path[0] {
+				names = append(names, projectRepo(project))
+			}
+		}
+	case 2:
+		var ids []int
+		for n := range s.seen[path[0]+"/"+path[1]] {
+			ids = append(ids, n)
+		}
+		sort.Ints(ids)
+		for _, n := range ids {
+			names = append(names, fmt.Sprint(n))
+		}
+		return names
+	case 3:
+		return fsFiles
+	}
+	sort.Strings(names)
+	// Remove duplicates.
+	out := names[:0]
+	for i, name := range names {
+		if i == 0 || name != names[i-1] {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+func (s *fsServer) stat(path []string) *plan9.Dir {
+	d := &plan9.Dir{
+		Qid:   fsQid(path),
+		Mode:  0444,
+		Mtime: uint32(time.Now().Unix()),
+		Name:  "/",
+		Uid:   s.user,
+		Gid:   s.user,
+		Muid:  s.user,
+	}
+	d.Atime = d.Mtime
+	if len(path) > 0 {
+		d.Name = path[len(path)-1]
+	}
+	switch {
+	case len(path) < 4:
+		d.Mode = plan9.DMDIR | 0555
+	case path[3] == "new":
+		d.Mode = 0222
+	}
+	return d
+}
+
+// fsQid returns the qid for path.
+func fsQid(path []string) plan9.Qid {
+	h := fnv.New64a()
+	h.Write([]byte(strings.Join(path, "/")))
+	q := plan9.Qid{Path: h.Sum64()}
+	if len(path) < 4 {
+		q.Type = plan9.QTDIR
+	}
+	return q
+}
@@ -0,0 +1,128 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// Projects like golang/go review changes in Gerrit, not in pull requests.
+// A bot comments on the issues that a change (CL) mentions, linking to it,
+// so the CLs for an issue are found in its comments.
+
+// A gerritCL is a Gerrit change linked from an issue's comments.
+type gerritCL struct {
+	Number  int
+	Host    string // Gerrit server, like go-review.googlesource.com
+	URL     string
+	Status  string // new, merged, or abandoned; "" if unknown
+	Subject string
+}
+
+// clLinkRE matches links to Gerrit changes: the go.dev/cl and golang.org/cl
+// short links, which redirect to go-review.googlesource.com, and full
+// links to any *-review.googlesource.com server.
+var clLinkRE = regexp.MustCompile(`https?://(?:go\.dev/cl|golang\.org/cl|([a-z0-9-]+-review\.googlesource\.com)/(?:c/[^\s)]+/\+|#/c))/([0-9]+)`)
+
+// findCLs returns the CLs linked from the comments,
+// in the order they were first mentioned.
+func findCLs(comments []*github.IssueComment) []*gerritCL {
+	var list []*gerritCL
+	seen := make(map[string]bool)
+	for _, com := range comments {
+		for _, m := range clLinkRE.FindAllStringSubmatch(com.GetBody(), -1) {
+			host := m[1]
+			if host == "" {
+				host = "go-review.googlesource.com"
+			}
+			n, err := strconv.Atoi(m[2])
+			if err != nil || seen[host+"/"+m[2]] {
+				continue
+			}
+			seen[host+"/"+m[2]] = true
+			list = append(list, &gerritCL{Number: n, Host: host, URL: fmt.Sprintf("https://%s/c/%d", host, n)})
+		}
+	}
+	return list
+}
+
+// gerritClient is the client for Gerrit requests,
+// which are unauthenticated.
+var gerritClient = &http.Client{Timeout: 10 * time.Second}
+
+// loadStatus sets the status and subject of the CL from the Gerrit
+// REST API. Not all servers allow anonymous access, so failures are
+// not fatal: the status is simply left unknown.
+func (cl *gerritCL) loadStatus() error {
+	resp, err := gerritClient.Get(fmt.Sprintf("https://%s/changes/%d", cl.Host, cl.Number))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", cl.URL, resp.Status)
+	}
+	// Gerrit prefixes JSON responses with a line guarding against XSSI.
+	data = bytes.TrimPrefix(data, []byte(")]}'"))
+	var change struct {
+		Status  string
+		Subject string
+	}
+	if err := json.Unmarshal(data, &change); err != nil {
+		return fmt.Errorf("%s: %v", cl.URL, err)
+	}
+	cl.Status = strings.ToLower(change.Status)
+	cl.Subject = change.Subject
+	return nil
+}
+
+// loadCLs returns the CLs linked from the comments,
+// with their status where it can be loaded.
+func loadCLs(comments []*github.IssueComment) []*gerritCL {
+	list := findCLs(comments)
+	done := make(chan bool)
+	for _, cl := range list {
+		go func(cl *gerritCL) {
+			cl.loadStatus()
+			done <- true
+		}(cl)
+	}
+	for range list {
+		<-done
+	}
+	return list
+}
+
+// printCLs prints a CLs: header line listing the CLs
+// and their status, if there are any.
+func printCLs(w io.Writer, cls []*gerritCL) {
+	if len(cls) == 0 {
+		return
+	}
+	var f []string
+	for _, cl := range cls {
+		status := cl.Status
+		if status == "" {
+			status = "unknown"
+		}
+		f = append(f, fmt.Sprintf("%d %s", cl.Number, status))
+	}
+	fmt.Fprintf(w, "CLs: %s\n", strings.Join(f, ", "))
+}
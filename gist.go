@@ -0,0 +1,155 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/google/go-github/v45/github"
+)
+
+const (
+	// maxCommentLen is GitHub's limit on the length of an issue or comment body.
+	maxCommentLen = 65536
+
+	// gistLines is the length of a fenced code block that -gist
+	// considers a long log worth moving out of the comment.
+	gistLines = 20
+)
+
+// gistCache maps uploaded text to its gist URL,
+// so that a bulk edit posting the same comment to many issues
+// uploads each attachment only once.
+var gistCache struct {
+	sync.Mutex
+	m map[string]string
+}
+
+// uploadGist uploads text as a secret gist and returns its URL.
+func uploadGist(desc, name, text string) (string, error) {
+	gistCache.Lock()
+	defer gistCache.Unlock()
+	if url, ok := gistCache.m[text]; ok {
+		return url, nil
+	}
+	public := false
+	g, _, err := client.Gists.Create(context.TODO(), &github.Gist{
+		Description: &desc,
+		Public:      &public,
+		Files: map[github.GistFilename]github.GistFile{
+			github.GistFilename(name): {Content: &text},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("uploading gist: %v", err)
+	}
+	if gistCache.m == nil {
+		gistCache.m = make(map[string]string)
+	}
+	url := getString(g.HTMLURL)
+	gistCache.m[text] = url
+	return url, nil
+}
+
+// gistBody prepares a comment or issue body for posting.
+// If the -gist flag is set, fenced code blocks longer than gistLines lines
+// are uploaded as secret gists and replaced by links.
+// If the body is still longer than GitHub allows, the text past the limit
+// is uploaded as a gist and the body ends with a link to it.
+func gistBody(desc, body string) (string, error) {
+	if *gistFlag {
+		var err error
+		body, err = gistCodeBlocks(desc, body)
+		if err != nil {
+			return "", err
+		}
+	}
+	if len(body) <= maxCommentLen {
+		return body, nil
+	}
+
+	// Split leaving room for the link.
+	const room = 200
+	cut := cutIndex(body, maxCommentLen-room)
+	head, rest := body[:cut], body[cut:]
+	url, err := uploadGist(desc, "comment.txt", strings.TrimLeft(rest, "\n"))
+	if err != nil {
+		return "", err
+	}
+	// Keep an unterminated code block from swallowing the link.
+	return fmt.Sprintf("%s\n\n(Text truncated. The remainder is at %s.)", closeFence(head), url), nil
+}
+
+// cutIndex returns where to cut text to keep at most n bytes:
+// at the last line boundary, or, if there is none,
+// at the last rune boundary.
+func cutIndex(text string, n int) int {
+	if len(text) <= n {
+		return len(text)
+	}
+	if i := strings.LastIndex(text[:n], "\n"); i >= 0 {
+		return i
+	}
+	for n > 0 && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	return n
+}
+
+// closeFence returns text with a fenced code block left open at its end
+// closed by the same kind of fence that opened it.
+func closeFence(text string) string {
+	fence := ""
+	for _, line := range strings.Split(text, "\n") {
+		if _, ok := isFence(line); ok {
+			if fence == "" {
+				fence = strings.TrimSpace(line)[:3]
+			} else {
+				fence = ""
+			}
+		}
+	}
+	if fence != "" {
+		text += "\n" + fence
+	}
+	return text
+}
+
+// gistCodeBlocks replaces long fenced code blocks in body with links to gists.
+func gistCodeBlocks(desc, body string) (string, error) {
+	lines := strings.SplitAfter(body, "\n")
+	var out strings.Builder
+	count := 0
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if _, ok := isFence(line); !ok {
+			out.WriteString(line)
+			continue
+		}
+		end := i + 1
+		for end < len(lines) {
+			if _, ok := isFence(lines[end]); ok {
+				break
+			}
+			end++
+		}
+		if end == len(lines) || end-i-1 <= gistLines {
+			out.WriteString(line)
+			continue
+		}
+		count++
+		url, err := uploadGist(desc, fmt.Sprintf("log%d.txt", count), strings.Join(lines[i+1:end], ""))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&out, "(%d lines at %s)\n", end-i-1, url)
+		i = end
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+var closeFenceTests = []struct {
+	in, out string
+}{
+	{"", ""},
+	{"text", "text"},
+	{"```\ncode\n```", "```\ncode\n```"},
+	{"```go\ncode", "```go\ncode\n```"},
+	{"~~~\ncode", "~~~\ncode\n~~~"},
+	{"  ~~~\ncode", "  ~~~\ncode\n~~~"},
+	{"```\ncode\n```\ntext\n~~~\nmore", "```\ncode\n```\ntext\n~~~\nmore\n~~~"},
+}
+
+func TestCloseFence(t *testing.T) {
+	for _, tt := range closeFenceTests {
+		if out := closeFence(tt.in); out != tt.out {
+			t.Errorf("closeFence(%q) = %q, want %q", tt.in, out, tt.out)
+		}
+	}
+}
+
+var cutIndexTests = []struct {
+	text string
+	n    int
+	cut  int
+}{
+	{"short", 10, 5},
+	{"line one\nline two", 12, 8},
+	{"line one\nline two\nline three", 20, 17},
+	{"abcdef", 3, 3},
+	{"aé", 2, 1}, // é is two bytes
+	{"aé", 3, 3},
+	{"a世界", 3, 1}, // 世 is three bytes
+	{"a世界", 5, 4},
+	{"世界", 2, 0},
+}
+
+func TestCutIndex(t *testing.T) {
+	for _, tt := range cutIndexTests {
+		if cut := cutIndex(tt.text, tt.n); cut != tt.cut {
+			t.Errorf("cutIndex(%q, %d) = %d, want %d", tt.text, tt.n, cut, tt.cut)
+		}
+	}
+}
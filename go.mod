@@ -6,6 +6,7 @@ require (
 	9fans.net/go v0.0.4
 	github.com/google/go-github/v45 v45.1.0
 	golang.org/x/oauth2 v0.0.0-20220524215830-622c5d57e401
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
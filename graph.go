@@ -0,0 +1,232 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// An issueEdge is a reference from one issue to another.
+// Dependency edges point from an issue to the issue it waits on.
+type issueEdge struct {
+	from, to int
+	kind     string // "depends on", or "" for a plain cross-reference
+}
+
+var (
+	// depRE matches dependency phrases followed by a list of issue references.
+	depRE = regexp.MustCompile(`(?i)\b(blocks|blocked by|blocked on|depends on|depends upon|requires)\s+((?:[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+)?#[0-9]+(?:\s*(?:,|and|&)\s*(?:[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+)?#[0-9]+)*)`)
+
+	// refRE matches a single issue reference, either owner/repo#nnnn, #nnnn,
+	// or a github.com issue URL.
+	refRE = regexp.MustCompile(`(?:\b([A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+))?#([0-9]+)\b|https://github\.com/([A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+)/issues/([0-9]+)`)
+)
+
+// issueRefs returns the numbers of the issues in project referred to by text.
+func issueRefs(project, text string) []int {
+	var refs []int
+	for _, m := range refRE.FindAllStringSubmatch(text, -1) {
+		repo, num := m[1], m[2]
+		if m[4] != "" {
+			repo, num = m[3], m[4]
+		}
+		if repo != "" && !strings.EqualFold(repo, project) {
+			continue
+		}
+		if n, err := strconv.Atoi(num); err == nil && n > 0 {
+			refs = append(refs, n)
+		}
+	}
+	return refs
+}
+
+// issueEdges returns the edges described by text written on issue n.
+func issueEdges(project string, n int, text string) []issueEdge {
+	var edges []issueEdge
+	deps := make(map[int]bool)
+	for _, m := range depRE.FindAllStringSubmatch(text, -1) {
+		blocks := strings.EqualFold(m[1], "blocks")
+		for _, ref := range issueRefs(project, m[2]) {
+			deps[ref] = true
+			if blocks {
+				edges = append(edges, issueEdge{ref, n, "depends on"})
+			} else {
+				edges = append(edges, issueEdge{n, ref, "depends on"})
+			}
+		}
+	}
+	for _, ref := range issueRefs(project, text) {
+		if ref != n && !deps[ref] {
+			edges = append(edges, issueEdge{n, ref, ""})
+		}
+	}
+	return edges
+}
+
+// issueGraph is the dependency and reference graph among a set of issues.
+type issueGraph struct {
+	issues map[int]*github.Issue // issues matched by the query
+	nodes  []int                 // all nodes, including referenced issues outside the query
+	edges  []issueEdge
+}
+
+func buildGraph(project string, issues []*github.Issue, comments bool) (*issueGraph, error) {
+	g := &issueGraph{issues: make(map[int]*github.Issue)}
+	for _, issue := range issues {
+		g.issues[getInt(issue.Number)] = issue
+	}
+	seen := make(map[issueEdge]bool)
+	nodes := make(map[int]bool)
+	add := func(edges []issueEdge) {
+		for _, e := range edges {
+			// A plain cross-reference in both directions is one edge.
+			if e.kind == "" && seen[issueEdge{e.to, e.from, ""}] {
+				continue
+			}
+			if !seen[e] {
+				seen[e] = true
+				g.edges = append(g.edges, e)
+				nodes[e.from] = true
+				nodes[e.to] = true
+			}
+		}
+	}
+	for _, issue := range issues {
+		n := getInt(issue.Number)
+		nodes[n] = true
+		add(issueEdges(project, n, getString(issue.Body)))
+		if !comments {
+			continue
+		}
+		for page := 1; ; {
+			list, resp, err := client.Issues.ListComments(context.TODO(), projectOwner(project), projectRepo(project), n, &github.IssueListCommentsOptions{
+				ListOptions: github.ListOptions{
+					Page:    page,
+					PerPage: 100,
+				},
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, com := range list {
+				add(issueEdges(project, n, getString(com.Body)))
+			}
+			if resp.NextPage < page {
+				break
+			}
+			page = resp.NextPage
+		}
+	}
+
+	// A dependency between two issues makes a plain reference redundant.
+	dep := make(map[[2]int]bool)
+	for _, e := range g.edges {
+		if e.kind != "" {
+			dep[[2]int{e.from, e.to}] = true
+			dep[[2]int{e.to, e.from}] = true
+		}
+	}
+	save := g.edges[:0]
+	for _, e := range g.edges {
+		if e.kind != "" || !dep[[2]int{e.from, e.to}] {
+			save = append(save, e)
+		}
+	}
+	g.edges = save
+
+	for n := range nodes {
+		g.nodes = append(g.nodes, n)
+	}
+	sort.Ints(g.nodes)
+	sort.Slice(g.edges, func(i, j int) bool {
+		if g.edges[i].from != g.edges[j].from {
+			return g.edges[i].from < g.edges[j].from
+		}
+		return g.edges[i].to < g.edges[j].to
+	})
+	return g, nil
+}
+
+func (g *issueGraph) label(n int) string {
+	issue := g.issues[n]
+	if issue == nil {
+		return fmt.Sprintf("#%d", n)
+	}
+	return fmt.Sprintf("#%d %s", n, getString(issue.Title))
+}
+
+func (g *issueGraph) writeDot(w io.Writer) {
+	fmt.Fprintf(w, "digraph issues {\n")
+	fmt.Fprintf(w, "\trankdir=LR;\n")
+	fmt.Fprintf(w, "\tnode [shape=box];\n")
+	for _, n := range g.nodes {
+		attr := ""
+		if issue := g.issues[n]; issue == nil {
+			attr = ", style=dashed"
+		} else if getString(issue.State) == "closed" {
+			attr = ", style=filled, fillcolor=lightgrey"
+		}
+		fmt.Fprintf(w, "\t%d [label=%s%s];\n", n, strconv.Quote(g.label(n)), attr)
+	}
+	for _, e := range g.edges {
+		if e.kind == "" {
+			fmt.Fprintf(w, "\t%d -> %d [style=dashed, arrowhead=none];\n", e.from, e.to)
+			continue
+		}
+		fmt.Fprintf(w, "\t%d -> %d [label=%s];\n", e.from, e.to, strconv.Quote(e.kind))
+	}
+	fmt.Fprintf(w, "}\n")
+}
+
+func (g *issueGraph) writeMermaid(w io.Writer) {
+	fmt.Fprintf(w, "graph LR\n")
+	for _, n := range g.nodes {
+		label := strings.Replace(g.label(n), `"`, "#quot;", -1)
+		fmt.Fprintf(w, "\ti%d[\"%s\"]\n", n, label)
+		if issue := g.issues[n]; issue != nil && getString(issue.State) == "closed" {
+			fmt.Fprintf(w, "\tstyle i%d fill:#ddd\n", n)
+		}
+	}
+	for _, e := range g.edges {
+		if e.kind == "" {
+			fmt.Fprintf(w, "\ti%d -.- i%d\n", e.from, e.to)
+			continue
+		}
+		fmt.Fprintf(w, "\ti%d -->|%s| i%d\n", e.from, e.kind, e.to)
+	}
+}
+
+func runGraph(project string, args []string) {
+	c := lookupCommand("graph")
+	fs := c.flags()
+	mermaid := fs.Bool("mermaid", false, "write Mermaid instead of Graphviz DOT")
+	comments := fs.Bool("comments", false, "also scan comments for references")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+	}
+	issues, err := searchIssues(project, strings.Join(fs.Args(), " "))
+	if err != nil {
+		fatal(err)
+	}
+	g, err := buildGraph(project, issues, *comments)
+	if err != nil {
+		fatal(err)
+	}
+	if *mermaid {
+		g.writeMermaid(os.Stdout)
+		return
+	}
+	g.writeDot(os.Stdout)
+}
@@ -0,0 +1,78 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Some API gateways in front of GitHub require extra headers,
+// which the -http-header flag and the http-headers setting add
+// to every GitHub API request.
+
+// A headerList is the value of the repeatable -http-header flag.
+type headerList []string
+
+func headerListFlag(name, usage string) *headerList {
+	l := new(headerList)
+	flag.Var(l, name, usage)
+	return l
+}
+
+func (l *headerList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ", ")
+}
+
+func (l *headerList) Set(s string) error {
+	if _, _, err := parseHeader(s); err != nil {
+		return err
+	}
+	*l = append(*l, s)
+	return nil
+}
+
+// parseHeader parses a header line like "X-Gateway-Key: abc".
+func parseHeader(line string) (key, value string, err error) {
+	key, value, ok := strings.Cut(line, ":")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" || strings.ContainsAny(key, " \t") {
+		return "", "", fmt.Errorf("invalid header %q: want Key: Value", line)
+	}
+	return http.CanonicalHeaderKey(key), strings.TrimSpace(value), nil
+}
+
+// A headerTransport adds headers to GitHub API requests.
+type headerTransport struct {
+	transport http.RoundTripper
+	header    http.Header
+}
+
+// newHeaderTransport returns a transport adding the header lines,
+// already checked by parseHeader, to GitHub API requests made with t.
+// A later line replaces an earlier one with the same key.
+func newHeaderTransport(t http.RoundTripper, lines []string) http.RoundTripper {
+	header := make(http.Header)
+	for _, line := range lines {
+		key, value, _ := parseHeader(line)
+		header.Set(key, value)
+	}
+	return &headerTransport{transport: t, header: header}
+}
+
+func (t *headerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if requestCategory(r) != "" {
+		r = r.Clone(r.Context())
+		for key, values := range t.header {
+			r.Header[key] = values
+		}
+	}
+	return t.transport.RoundTrip(r)
+}
@@ -0,0 +1,172 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// Maintainers can minimize ("hide") comments that are off-topic,
+// outdated, and so on. The REST API does not report which comments
+// are hidden, so that is loaded using GraphQL, as is the edit history.
+
+const minimizedQuery = `
+query($owner: String!, $repo: String!, $number: Int!, $after: String) {
+  repository(owner: $owner, name: $repo) {
+    issueOrPullRequest(number: $number) {
+      ... on Issue {
+        comments(first: 100, after: $after) {
+          nodes { databaseId isMinimized minimizedReason }
+          pageInfo { hasNextPage endCursor }
+        }
+      }
+      ... on PullRequest {
+        comments(first: 100, after: $after) {
+          nodes { databaseId isMinimized minimizedReason }
+          pageInfo { hasNextPage endCursor }
+        }
+      }
+    }
+  }
+}`
+
+// loadMinimized returns the reasons the hidden comments on issue n
+// were hidden, such as "off-topic", by comment ID.
+func loadMinimized(project string, n int) (map[int64]string, error) {
+	hidden := make(map[int64]string)
+	var after *string
+	for {
+		var data struct {
+			Repository struct {
+				IssueOrPullRequest *struct {
+					Comments struct {
+						Nodes []struct {
+							DatabaseID      int64
+							IsMinimized     bool
+							MinimizedReason string
+						}
+						PageInfo struct {
+							HasNextPage bool
+							EndCursor   string
+						}
+					}
+				}
+			}
+		}
+		vars := map[string]interface{}{
+			"owner":  projectOwner(project),
+			"repo":   projectRepo(project),
+			"number": n,
+			"after":  after,
+		}
+		if err := graphQL(minimizedQuery, vars, &data); err != nil {
+			return nil, err
+		}
+		issue := data.Repository.IssueOrPullRequest
+		if issue == nil {
+			return nil, fmt.Errorf("issue %s#%d not found", project, n)
+		}
+		for _, c := range issue.Comments.Nodes {
+			if c.IsMinimized {
+				reason := strings.ToLower(c.MinimizedReason)
+				if reason == "" {
+					reason = "hidden"
+				}
+				hidden[c.DatabaseID] = reason
+			}
+		}
+		if !issue.Comments.PageInfo.HasNextPage {
+			break
+		}
+		after = &issue.Comments.PageInfo.EndCursor
+	}
+	return hidden, nil
+}
+
+// printHiddenComment prints a comment that was hidden for the given
+// reason: collapsed to its header line, unless -show-hidden is set.
+func printHiddenComment(w io.Writer, com *github.IssueComment, reason string) {
+	fmt.Fprintf(w, "\n%s (hidden as %s)\n", commentHeader(com), reason)
+	if *hiddenFlag {
+		printFilteredText(w, com)
+		return
+	}
+	fmt.Fprintf(w, "\n\t[comment hidden; -show-hidden shows it]\n")
+}
+
+// hideReasons are the reasons a comment can be hidden,
+// as accepted by the GitHub minimizeComment mutation.
+var hideReasons = []string{"abuse", "duplicate", "off-topic", "outdated", "resolved", "spam"}
+
+const minimizeMutation = `
+mutation($id: ID!, $reason: ReportedContentClassifiers!) {
+  minimizeComment(input: {subjectId: $id, classifier: $reason}) {
+    minimizedComment { isMinimized }
+  }
+}`
+
+// checkHideReason returns an error if reason is not one of hideReasons.
+func checkHideReason(reason string) error {
+	for _, r := range hideReasons {
+		if r == reason {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown reason %q: want %s", reason, strings.Join(hideReasons, ", "))
+}
+
+// hideComment hides the comment with the given ID for reason,
+// one of hideReasons.
+func hideComment(project string, id int64, reason string) error {
+	if err := checkHideReason(reason); err != nil {
+		return err
+	}
+	com, _, err := client.Issues.GetComment(context.TODO(), projectOwner(project), projectRepo(project), id)
+	if err != nil {
+		return err
+	}
+	vars := map[string]interface{}{
+		"id":     com.GetNodeID(),
+		"reason": strings.ToUpper(strings.Replace(reason, "-", "_", -1)),
+	}
+	var data struct{}
+	return graphQL(minimizeMutation, vars, &data)
+}
+
+func runHide(project string, args []string) {
+	fs := lookupCommand("hide").flags()
+	reason := fs.String("r", "off-topic", "hide the comments for `reason`: "+strings.Join(hideReasons, ", "))
+	parseFlags(fs, args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+	}
+	if err := checkHideReason(*reason); err != nil {
+		usageErrorf("%v", err)
+	}
+	var ids []int64
+	for _, arg := range fs.Args() {
+		id, ok := parseCommentID(arg)
+		if !ok {
+			usageErrorf("invalid comment ID %q", arg)
+		}
+		ids = append(ids, id)
+	}
+	failed := false
+	for _, id := range ids {
+		if err := hideComment(project, id, *reason); err != nil {
+			log.Printf("comment %d: %v", id, err)
+			failed = true
+		}
+	}
+	if failed {
+		exit(exitError)
+	}
+}
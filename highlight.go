@@ -0,0 +1,217 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// A codeLang describes the syntax of a language for highlighting.
+type codeLang struct {
+	comment  string          // line comment prefix
+	block    bool            // has /* */ comments
+	keywords map[string]bool // reserved words
+}
+
+func newCodeLang(comment string, block bool, keywords string) *codeLang {
+	l := &codeLang{comment: comment, block: block, keywords: make(map[string]bool)}
+	for _, k := range strings.Fields(keywords) {
+		l.keywords[k] = true
+	}
+	return l
+}
+
+var (
+	langGo     = newCodeLang("//", true, "break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var nil true false")
+	langC      = newCodeLang("//", true, "auto break case char const continue default do double else enum extern float for goto if int long register return short signed sizeof static struct switch typedef union unsigned void volatile while class namespace template public private protected virtual new delete this NULL nullptr true false")
+	langJS     = newCodeLang("//", true, "async await break case catch class const continue default delete do else export extends finally for function if import in instanceof let new of return switch this throw try typeof var void while yield null undefined true false interface type")
+	langRust   = newCodeLang("//", true, "as break const continue crate else enum extern fn for if impl in let loop match mod move mut pub ref return self Self static struct super trait type unsafe use where while true false")
+	langJava   = newCodeLang("//", true, "abstract boolean break byte case catch char class const continue default do double else enum extends final finally float for if implements import instanceof int interface long new package private protected public return short static super switch this throw throws try void while null true false")
+	langPython = newCodeLang("#", false, "and as assert async await break class continue def del elif else except finally for from global if import in is lambda nonlocal not or pass raise return try while with yield None True False")
+	langShell  = newCodeLang("#", false, "if then else elif fi for while until do done case esac function in return export local")
+)
+
+// codeLangs maps the info strings of fenced code blocks to languages.
+var codeLangs = map[string]*codeLang{
+	"go":         langGo,
+	"golang":     langGo,
+	"c":          langC,
+	"cc":         langC,
+	"cpp":        langC,
+	"c++":        langC,
+	"h":          langC,
+	"js":         langJS,
+	"javascript": langJS,
+	"ts":         langJS,
+	"typescript": langJS,
+	"rust":       langRust,
+	"rs":         langRust,
+	"java":       langJava,
+	"kotlin":     langJava,
+	"python":     langPython,
+	"py":         langPython,
+	"sh":         langShell,
+	"bash":       langShell,
+	"shell":      langShell,
+	"console":    langShell,
+	"zsh":        langShell,
+}
+
+// isFence reports whether line opens or closes a fenced code block,
+// returning the block's info string, such as the language name.
+func isFence(line string) (info string, ok bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "```") && !strings.HasPrefix(line, "~~~") {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimLeft(line, line[:1])), true
+}
+
+// traceStartRE matches the first line of a Go panic or goroutine dump.
+var traceStartRE = regexp.MustCompile(`^(panic: |fatal error: |goroutine [0-9]+ \[)`)
+
+// traceFuncRE matches the function call lines of a Go stack trace.
+var traceFuncRE = regexp.MustCompile(`^created by |^[A-Za-z0-9_./*()\[\]{},-]+\(.*\)$`)
+
+// isTraceLine reports whether line belongs to a Go stack trace:
+// a goroutine header, a function call, or its file and line.
+func isTraceLine(line string) bool {
+	return traceStartRE.MatchString(line) || traceFuncRE.MatchString(line) ||
+		strings.HasPrefix(line, "\t") && strings.Contains(line, ".go:")
+}
+
+// A highlighter colors the lines of a code block or stack trace.
+type highlighter struct {
+	kind    string    // "diff", "trace", "code", or "" for no highlighting
+	lang    *codeLang // language, for kind "code"
+	comment bool      // inside a /* */ comment
+}
+
+// newHighlighter returns a highlighter for a fenced code block
+// with the given info string and lines. If the info string does not
+// name a known language, the lines are checked for a diff or stack trace.
+func newHighlighter(info string, lines []string) *highlighter {
+	if f := strings.Fields(info); len(f) > 0 {
+		info = strings.ToLower(f[0])
+	}
+	switch info {
+	case "diff", "patch":
+		return &highlighter{kind: "diff"}
+	}
+	if l := codeLangs[info]; l != nil {
+		return &highlighter{kind: "code", lang: l}
+	}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if _, ok := isFence(line); ok {
+			break
+		}
+		switch {
+		case strings.HasPrefix(line, "diff --git "), strings.HasPrefix(line, "@@ "):
+			return &highlighter{kind: "diff"}
+		case traceStartRE.MatchString(line):
+			return &highlighter{kind: "trace"}
+		}
+	}
+	return &highlighter{}
+}
+
+// line returns the colored form of a single line.
+// Lines already containing escape sequences, such as hyperlinks,
+// are left alone, so as not to break them.
+func (h *highlighter) line(s string) string {
+	if strings.Contains(s, "\x1b") {
+		return s
+	}
+	switch h.kind {
+	case "diff":
+		switch {
+		case strings.HasPrefix(s, "+++"), strings.HasPrefix(s, "---"), strings.HasPrefix(s, "diff "), strings.HasPrefix(s, "index "):
+			return colorize(sgrBold, s)
+		case strings.HasPrefix(s, "@@"):
+			return colorize(sgrCyan, s)
+		case strings.HasPrefix(s, "+"):
+			return colorize(sgrGreen, s)
+		case strings.HasPrefix(s, "-"):
+			return colorize(sgrRed, s)
+		}
+	case "trace":
+		switch {
+		case strings.HasPrefix(s, "panic: "), strings.HasPrefix(s, "fatal error: "):
+			return colorize(sgrBold+sgrRed, s)
+		case strings.HasPrefix(s, "goroutine "):
+			return colorize(sgrBold, s)
+		case strings.HasPrefix(s, "\t"):
+			return colorize(sgrDim, s)
+		case s != "":
+			return colorize(sgrCyan, s)
+		}
+	case "code":
+		return h.code(s)
+	}
+	return s
+}
+
+// code colors the comments, strings, and keywords in a line of code.
+func (h *highlighter) code(s string) string {
+	var out strings.Builder
+	for len(s) > 0 {
+		if h.comment {
+			i := strings.Index(s, "*/")
+			if i < 0 {
+				out.WriteString(colorize(sgrDim, s))
+				break
+			}
+			out.WriteString(colorize(sgrDim, s[:i+2]))
+			s = s[i+2:]
+			h.comment = false
+			continue
+		}
+		switch c := s[0]; {
+		case strings.HasPrefix(s, h.lang.comment):
+			out.WriteString(colorize(sgrDim, s))
+			return out.String()
+		case h.lang.block && strings.HasPrefix(s, "/*"):
+			h.comment = true
+			out.WriteString(colorize(sgrDim, "/*"))
+			s = s[2:]
+		case c == '"' || c == '\'' || c == '`':
+			i := 1
+			for i < len(s) && s[i] != c {
+				if s[i] == '\\' && c != '`' {
+					i++
+				}
+				i++
+			}
+			if i < len(s) {
+				i++
+			} else {
+				i = len(s)
+			}
+			out.WriteString(colorize(sgrGreen, s[:i]))
+			s = s[i:]
+		case isIdentByte(c):
+			i := 1
+			for i < len(s) && isIdentByte(s[i]) {
+				i++
+			}
+			if h.lang.keywords[s[:i]] {
+				out.WriteString(colorize(sgrMagenta, s[:i]))
+			} else {
+				out.WriteString(s[:i])
+			}
+			s = s[i:]
+		default:
+			out.WriteByte(c)
+			s = s[1:]
+		}
+	}
+	return out.String()
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c >= 0x80
+}
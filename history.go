@@ -0,0 +1,276 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The REST API does not report the revisions of issue bodies and
+// comments, so the edit history is loaded using GraphQL.
+
+const historyQuery = `
+fragment edits on Comment {
+  author { login }
+  createdAt
+  userContentEdits(first: 100) {
+    nodes { editedAt editor { login } diff }
+  }
+}
+query($owner: String!, $repo: String!, $number: Int!, $after: String) {
+  repository(owner: $owner, name: $repo) {
+    issueOrPullRequest(number: $number) {
+      ... on Issue {
+        ...edits
+        comments(first: 100, after: $after) {
+          nodes { ...edits }
+          pageInfo { hasNextPage endCursor }
+        }
+      }
+      ... on PullRequest {
+        ...edits
+        comments(first: 100, after: $after) {
+          nodes { ...edits }
+          pageInfo { hasNextPage endCursor }
+        }
+      }
+    }
+  }
+}`
+
+// A gqlEdited is the GraphQL form of an issue or comment
+// and its revisions.
+type gqlEdited struct {
+	Author           struct{ Login string }
+	CreatedAt        time.Time
+	UserContentEdits struct {
+		Nodes []struct {
+			EditedAt time.Time
+			Editor   struct{ Login string }
+			Diff     *string // full text of the revision; nil if deleted
+		}
+	}
+}
+
+// A revision is one version of the text of an issue or comment.
+type revision struct {
+	Editor string
+	Time   time.Time
+	Text   string
+}
+
+// An editHistory is the edit history of an issue body or a comment.
+type editHistory struct {
+	Author    string
+	Created   time.Time
+	Revisions []revision // oldest first, starting with the original text
+}
+
+func (g *gqlEdited) history() *editHistory {
+	h := &editHistory{Author: g.Author.Login, Created: g.CreatedAt}
+	for _, e := range g.UserContentEdits.Nodes {
+		if e.Diff == nil {
+			continue
+		}
+		h.Revisions = append(h.Revisions, revision{e.Editor.Login, e.EditedAt, *e.Diff})
+	}
+	sort.SliceStable(h.Revisions, func(i, j int) bool {
+		return h.Revisions[i].Time.Before(h.Revisions[j].Time)
+	})
+	return h
+}
+
+// loadHistory returns the edit histories of the body and the edited
+// comments of issue n. The histories of unedited text are omitted.
+func loadHistory(project string, n int) (body *editHistory, comments []*editHistory, err error) {
+	var after *string
+	for {
+		type gqlIssue struct {
+			gqlEdited
+			Comments struct {
+				Nodes    []gqlEdited
+				PageInfo struct {
+					HasNextPage bool
+					EndCursor   string
+				}
+			}
+		}
+		var data struct {
+			Repository struct {
+				IssueOrPullRequest *gqlIssue
+			}
+		}
+		vars := map[string]interface{}{
+			"owner":  projectOwner(project),
+			"repo":   projectRepo(project),
+			"number": n,
+			"after":  after,
+		}
+		if err := graphQL(historyQuery, vars, &data); err != nil {
+			return nil, nil, err
+		}
+		issue := data.Repository.IssueOrPullRequest
+		if issue == nil {
+			return nil, nil, fmt.Errorf("issue %s#%d not found", project, n)
+		}
+		if body == nil {
+			body = issue.history()
+		}
+		for i := range issue.Comments.Nodes {
+			if h := issue.Comments.Nodes[i].history(); len(h.Revisions) > 1 {
+				comments = append(comments, h)
+			}
+		}
+		if !issue.Comments.PageInfo.HasNextPage {
+			break
+		}
+		after = &issue.Comments.PageInfo.EndCursor
+	}
+	if len(body.Revisions) < 2 {
+		body = nil
+	}
+	return body, comments, nil
+}
+
+// showHistory prints the edit history of issue n and its comments,
+// as a diff between each revision and the one before it.
+func showHistory(w io.Writer, project string, n int) error {
+	body, comments, err := loadHistory(project, n)
+	if err != nil {
+		return err
+	}
+	if body == nil && len(comments) == 0 {
+		fmt.Fprintf(w, "Issue %s#%d and its comments have not been edited.\n", project, n)
+		return nil
+	}
+	if body != nil {
+		printHistory(w, fmt.Sprintf("Reported by %s (%s)", body.Author, formatTime(body.Created)), body)
+	}
+	for _, h := range comments {
+		printHistory(w, fmt.Sprintf("Comment by %s (%s)", h.Author, formatTime(h.Created)), h)
+	}
+	return nil
+}
+
+func printHistory(w io.Writer, title string, h *editHistory) {
+	edits := len(h.Revisions) - 1
+	fmt.Fprintf(w, "%s, edited %d time%s\n", colorize(sgrBold+sgrCyan, title), edits, suffix(edits))
+	hl := &highlighter{kind: "diff"}
+	for i, r := range h.Revisions {
+		if i == 0 {
+			fmt.Fprintf(w, "\nOriginal text (%s)\n\n", formatTime(r.Time))
+			if text := strings.TrimSpace(r.Text); text != "" {
+				fmt.Fprintf(w, "\t%s\n", strings.Replace(text, "\n", "\n\t", -1))
+			}
+			continue
+		}
+		fmt.Fprintf(w, "\nEdited by %s (%s)\n\n", r.Editor, formatTime(r.Time))
+		for _, line := range lineDiff(h.Revisions[i-1].Text, r.Text) {
+			fmt.Fprintf(w, "\t%s\n", hl.line(line))
+		}
+	}
+	fmt.Fprintf(w, "\n")
+}
+
+// lineDiff returns a unified diff of the lines of old and new,
+// with two lines of context around each change.
+func lineDiff(old, new string) []string {
+	const context = 2
+	x := strings.Split(strings.Replace(strings.TrimSpace(old), "\r\n", "\n", -1), "\n")
+	y := strings.Split(strings.Replace(strings.TrimSpace(new), "\r\n", "\n", -1), "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:].
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	// Walk the table to list the lines, each prefixed by ' ', '-', or '+'.
+	type op struct {
+		line string
+		i, j int // line numbers in x and y before this line
+	}
+	var ops []op
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			ops = append(ops, op{" " + x[i], i, j})
+			i++
+			j++
+		case j == len(y) || i < len(x) && lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, op{"-" + x[i], i, j})
+			i++
+		default:
+			ops = append(ops, op{"+" + y[j], i, j})
+			j++
+		}
+	}
+
+	// Group the changes into hunks with context.
+	var out []string
+	for k := 0; k < len(ops); {
+		if ops[k].line[0] == ' ' {
+			k++
+			continue
+		}
+		start := k - context
+		if start < 0 {
+			start = 0
+		}
+		end := k
+		for end < len(ops) {
+			if ops[end].line[0] != ' ' {
+				end++
+				continue
+			}
+			// Extend past a run of unchanged lines only if
+			// another change follows within twice the context.
+			run := end
+			for run < len(ops) && ops[run].line[0] == ' ' {
+				run++
+			}
+			if run < len(ops) && run-end <= 2*context {
+				end = run
+				continue
+			}
+			end += context
+			if end > len(ops) {
+				end = len(ops)
+			}
+			break
+		}
+		var nx, ny int
+		for _, o := range ops[start:end] {
+			if o.line[0] != '+' {
+				nx++
+			}
+			if o.line[0] != '-' {
+				ny++
+			}
+		}
+		out = append(out, fmt.Sprintf("@@ -%d,%d +%d,%d @@", ops[start].i+1, nx, ops[start].j+1, ny))
+		for _, o := range ops[start:end] {
+			out = append(out, o.line)
+		}
+		k = end
+	}
+	return out
+}
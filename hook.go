@@ -0,0 +1,135 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// hookNames are the hooks that can be set in the configuration.
+// A pre-put hook runs before any command changes an issue and can veto
+// the change by failing; the post-put and post-create hooks run after
+// an issue is changed or created, and their failures are only logged.
+var hookNames = []string{"pre-put", "post-put", "post-create"}
+
+// A hookChange is the JSON that a hook reads on standard input,
+// describing the change about to be made or just made.
+// Fields not being changed are omitted.
+type hookChange struct {
+	Hook            string
+	Project         string
+	Number          int
+	URL             string    `json:",omitempty"`
+	State           string    `json:",omitempty"` // state before the change, if known, or of the new issue
+	Title           *string   `json:",omitempty"`
+	NewState        *string   `json:",omitempty"`
+	Assignee        *string   `json:",omitempty"`
+	Assignees       *[]string `json:",omitempty"` // complete new list
+	AddAssignees    []string  `json:",omitempty"`
+	RemoveAssignees []string  `json:",omitempty"`
+	Labels          *[]string `json:",omitempty"` // complete new list
+	AddLabels       []string  `json:",omitempty"`
+	RemoveLabels    []string  `json:",omitempty"`
+	Milestone       *string   `json:",omitempty"`
+	Type            *string   `json:",omitempty"`
+	Comment         string    `json:",omitempty"`
+	Changes         []string  `json:",omitempty"` // post-put: the changes made
+}
+
+// userCommand returns the command to run the configured command line s,
+// using the shell if s has arguments or other shell syntax,
+// as runEditor does.
+func userCommand(s string) *exec.Cmd {
+	if strings.ContainsAny(s, "|&;<>()$`\\\"' \t\n*?[#~=%") {
+		return exec.Command("sh", "-c", s)
+	}
+	return exec.Command(s)
+}
+
+// runHook runs the named hook, if configured, with the change as its input.
+// Its output is included in the error if it fails.
+func runHook(name string, change *hookChange) error {
+	hook := loadConfig().Hooks[name]
+	if hook == "" {
+		return nil
+	}
+	change.Hook = name
+	data, err := json.MarshalIndent(change, "", "\t")
+	if err != nil {
+		return err
+	}
+	cmd := userCommand(hook)
+	cmd.Env = append(os.Environ(),
+		"ISSUE_HOOK="+name,
+		"ISSUE_PROJECT="+change.Project,
+		fmt.Sprintf("ISSUE_NUMBER=%d", change.Number))
+	cmd.Stdin = bytes.NewReader(append(data, '\n'))
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%s hook: %v: %s", name, err, msg)
+		}
+		return fmt.Errorf("%s hook: %v", name, err)
+	}
+	return nil
+}
+
+// putIssue makes a change to issue n described by change, running the
+// hooks around it: the pre-put hook, which can veto the change, and
+// then, if apply makes any of the changes, the post-put hook with the
+// list apply returns of the changes made.
+func putIssue(project string, n int, change *hookChange, apply func() ([]string, error)) error {
+	change.Project = project
+	change.Number = n
+	change.URL = issueURL(project, n)
+	if err := runHook("pre-put", change); err != nil {
+		return err
+	}
+	did, err := apply()
+	if len(did) > 0 {
+		change.Changes = did
+		runPostHook("post-put", change)
+	}
+	return err
+}
+
+// commentIssue posts a comment on issue n, running the hooks.
+func commentIssue(project string, n int, text string) error {
+	return putIssue(project, n, &hookChange{Comment: text}, func() ([]string, error) {
+		if err := postComment(project, n, text); err != nil {
+			return nil, err
+		}
+		return []string{"saved comment"}, nil
+	})
+}
+
+// closeWithComment posts a comment on issue n and closes it
+// as not planned, running the hooks.
+func closeWithComment(project string, n int, text string) error {
+	closed := "closed"
+	return putIssue(project, n, &hookChange{NewState: &closed, Comment: text}, func() ([]string, error) {
+		if err := postComment(project, n, text); err != nil {
+			return nil, err
+		}
+		if err := closeIssue(project, n, "not_planned"); err != nil {
+			return []string{"saved comment"}, err
+		}
+		return []string{"saved comment", "closed"}, nil
+	})
+}
+
+// runPostHook runs the named hook, logging any failure:
+// the change has already been made.
+func runPostHook(name string, change *hookChange) {
+	if err := runHook(name, change); err != nil {
+		log.Print(err)
+	}
+}
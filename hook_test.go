@@ -0,0 +1,99 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var putHookTests = []struct {
+	name   string
+	op     []string
+	prePut string // pre-put hook command
+	err    string // error wanted, if any
+	log    []string
+	change map[string]interface{} // fields of the post-put input, if it runs
+}{
+	{
+		name:   "close vetoed",
+		op:     []string{"close", "7"},
+		prePut: "echo closing requires a comment; exit 1",
+		err:    "pre-put hook: exit status 1: closing requires a comment",
+	},
+	{
+		name:   "close",
+		op:     []string{"close", "7"},
+		prePut: "true",
+		log:    []string{`PATCH /repos/golang/go/issues/7 {"state":"closed"}`},
+		change: map[string]interface{}{"Hook": "post-put", "Number": 7.0, "NewState": "closed", "Changes": []interface{}{"closed"}},
+	},
+	{
+		name:   "label vetoed",
+		op:     []string{"label", "7", "+bug"},
+		prePut: `grep -q '"AddLabels"' && exit 1 || true`,
+		err:    "pre-put hook: exit status 1",
+	},
+	{
+		name:   "label",
+		op:     []string{"label", "7", "-bug"},
+		prePut: `grep -q '"AddLabels"' && exit 1 || true`,
+		log:    []string{"DELETE /repos/golang/go/issues/7/labels/bug"},
+		change: map[string]interface{}{"Hook": "post-put", "Number": 7.0, "RemoveLabels": []interface{}{"bug"}, "Changes": []interface{}{"removed label bug"}},
+	},
+	{
+		name:   "comment vetoed",
+		op:     []string{"comment", "7", "+1"},
+		prePut: `grep -q '"Comment": "+1"' && exit 1 || true`,
+		err:    "pre-put hook: exit status 1",
+	},
+}
+
+func TestPutHooks(t *testing.T) {
+	for _, tt := range putHookTests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "post-put.json")
+			writeConfig(t, "hooks:\n  pre-put: "+quoteYAML(tt.prePut)+"\n  post-put: "+quoteYAML("cat >"+out)+"\n")
+			log := fakeAPI(t, http.StatusNoContent)
+			_, err := runBatchOp("golang/go", tt.op)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("runBatchOp: %v, want %q", err, tt.err)
+				}
+			} else if err != nil {
+				t.Fatalf("runBatchOp: %v", err)
+			}
+			if !reflect.DeepEqual(*log, tt.log) {
+				t.Errorf("requests:\n\t%s\nwant:\n\t%s", strings.Join(*log, "\n\t"), strings.Join(tt.log, "\n\t"))
+			}
+			data, err := ioutil.ReadFile(out)
+			if tt.change == nil {
+				if err == nil {
+					t.Errorf("post-put ran after a vetoed change:\n%s", data)
+				}
+				return
+			}
+			var change map[string]interface{}
+			if err := json.Unmarshal(data, &change); err != nil {
+				t.Fatalf("post-put input: %v\n%s", err, data)
+			}
+			for k, v := range tt.change {
+				if !reflect.DeepEqual(change[k], v) {
+					t.Errorf("post-put %s = %v, want %v", k, change[k], v)
+				}
+			}
+		})
+	}
+}
+
+func quoteYAML(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
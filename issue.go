@@ -6,6 +6,9 @@
 Issue is a client for reading and updating issues in a GitHub project issue tracker.
 
 	usage: issue [-a] [-e] [-p owner/repo] <query>
+	       issue [-p owner/repo] <command> [args]
+	       issue [-p owner/repo] -serve addr
+	       issue [-p owner/repo] -tui [query]
 
 Issue runs the query against the given project's issue tracker and
 prints a table of matching issues, sorted by issue summary.
@@ -32,6 +35,494 @@ The token only needs the 'repo' scope checkbox, and optionally 'private_repo'
 if you want to work with issue trackers for private repositories.
 It does not need any other permissions.
 The -token flag specifies an alternate file from which to read the token.
+If there is no token file, issue uses the password for machine
+api.github.com in $HOME/.netrc (or the file named by $NETRC), as in
+"machine api.github.com login rsc password ghp_xxx". An entry with
+the password x-oauth-basic gives the token as its login instead.
+Like the token file, the .netrc file must not be readable by others.
+
+Fine-grained personal access tokens and GitHub App tokens expire.
+If the configuration file (see Configuration below) sets token-command,
+a command that prints a new token, such as "gh auth token", issue runs
+it when GitHub rejects the token as expired, and then retries the
+request. Alternatively, github-app gives the id, installation id, and
+private key-file of a GitHub App, and issue mints installation tokens
+for it, before each expires. With either, the token file is optional:
+without one, issue gets its first token the same way.
+
+To use GitHub Enterprise Server, set the -api flag, or the api setting
+in the configuration file, to the server's URL, as in
+"-api https://github.example.com". For such a host, issue adds the
+/api/v3/ path of the REST API, and uses /api/graphql for GraphQL.
+Uploads go to /api/uploads/ on the same host, or to the host given by
+the -upload-api flag or upload-api setting. The .netrc entry is then
+looked up by the server's host name, and printed issue URLs use it too.
+Setting only -upload-api keeps api.github.com for the API.
+
+For API gateways that require extra headers, the -http-header flag,
+which can be repeated, adds a header line like "X-Gateway-Key: abc"
+to every GitHub API request, as do the lines listed in the http-headers
+setting. A flag's header replaces a setting's header with the same key.
+
+When the API host is not directly reachable, the -transport flag, or
+the transport setting, says how to connect to it: "unix:path" connects
+to a Unix domain socket, and anything else is a command whose standard
+input and output are the connection, like an ssh ProxyCommand, with %h
+and %p replaced by the host and port: "-transport 'ssh bastion -W %h:%p'".
+TLS still runs over the connection, end to end. Only connections to the
+API and upload hosts use the transport.
+
+The -record flag writes every HTTP request issue makes, and the response
+to it, to a cassette file, one JSON object per line, with the token and
+any other GitHub tokens replaced by REDACTED. The -replay flag answers
+requests from such a file instead of the network, without needing a
+token, so that a recorded session, such as an acme workflow, can be run
+again offline for a demo or a test. Each recorded response is used
+once, for a request with the same method, URL, and body, although
+the since and before parameters and the dates in search queries,
+which depend on the time of the run, may differ. A request with no
+recorded response fails.
+
+Commands
+
+If the first argument is the name of one of the following commands,
+issue runs that command instead of a query. Each command accepts its own
+flags after the command name; the global flags, such as -p, come before
+it, although a command's own flags may follow its arguments, as in
+"issue comment 1234 -m text". If the arguments are not valid for the
+command, as in "issue comment", issue treats the whole command line as a
+query instead. To search for words that begin with a command name,
+precede them with "--", as in "issue -- todo". The first group of
+commands is a scriptable form of the query-driven interface described
+above:
+
+	issue show [-json] [-raw|-both] <n>...
+	issue list [-json] [query]
+	issue create [-f file] [-template name [-e]] [-title title] [-body text|-]
+		[-labels l1,l2] [-assignee login] [-milestone name]
+	issue comment <n> [-m text | text]
+	issue close [-m comment] <n>...
+	issue edit <n>|new|<query>
+
+The show command prints the full history of each numbered issue, as
+"issue <n>" does. With -both, as with the global -both flag, it prints
+the raw markdown of the body and each comment in a column beside the
+wrapped text, to help when fixing someone else's formatting. The list
+command prints the issues matching the query, or all open issues if
+there is no query. The create command creates an issue without an
+editor, printing the new issue's URL; a body of "-" is read from
+standard input. The -f flag reads the issue from a Markdown file (or
+standard input, if the name is "-"), which may begin with YAML front
+matter giving the title and other metadata:
+
+	---
+	title: "net/http: Server leaks goroutines on shutdown"
+	labels: [NeedsInvestigation, help wanted]
+	assignees: [rsc]
+	milestone: Go1.25
+	---
+	<describe issue here>
+
+Labels and assignees may also be written as comma-separated strings.
+Flags given on the command line override the front matter.
+
+The -template flag creates the issue from one of the repository's issue
+templates in .github/ISSUE_TEMPLATE, named by its name or file name.
+For an issue form, create asks for each field in turn on the terminal,
+ending a multi-line answer with a line containing only a period, and asks
+again for a required field left empty. With -e, or for a Markdown template,
+create instead opens the template in the system editor, with <...> markers
+where the answers go, and reopens it until the required fields are filled in.
+
+The comment command posts a comment: the -m text, or the remaining
+arguments, or if neither is given, the text read from standard input, as
+in "cat reply.md | issue comment 1234". The close command closes the
+numbered issues, first posting the -m text as a comment on each. The
+edit command edits issues in the system editor, as the -e flag does.
+
+The remaining commands provide other views and operations:
+
+	issue assign <n> @me|@org/team|<login>...
+	issue attachments [-o dir] <n>
+	issue branch [-link [-remote name]] [-base ref] [-name name] <n>
+	issue check-refs [-since date] [revision-range]
+	issue clone <n> -to owner/repo
+	issue completion bash|zsh|fish
+	issue dup <n> <m>
+	issue epic <milestone>
+	issue feed [-o file] <query>
+	issue fixmsg <n> | -hook [-f]
+	issue fs [-name name]
+	issue graph [-mermaid] [-comments] <query>
+	issue hide [-r reason] <comment-id>...
+	issue label <n> +<add> -<remove>...
+	issue merge <src> <dst>
+	issue milestone <n> <milestone-name>|none
+	issue milestones [-state open|closed|all] [-sort due|title] [-due-before date] [-due-after date] [-title regexp] [-ics|-mermaid]
+	issue pick [-o] [-refresh] [pattern...]
+	issue plumbing
+	issue policy [-n] list|run [name...]
+	issue ratelimit
+	issue resolved-by <n>
+	issue resume [journal]
+	issue retitle <n> <title>
+	issue schedule [-once]
+	issue sendmail [sendmail-args...]
+	issue split [-n] <n>
+	issue suggest-owner [-apply [-y]] <n>
+	issue task <n> [check|uncheck|toggle <i>]
+	issue todo [-o file] [query]
+	issue trace [-a] [-ref ref] <n>
+	issue triage -apply-rules [-n] <query>
+	issue tw-sync [-close] [-n] [query]
+	issue unassign <n> [@me|<login>...]
+	issue watch [-once] [-metrics addr]
+
+The assign command adds the listed users to the assignees of issue n.
+The unassign command removes them, or removes all assignees if none
+are listed. In both, "@me" stands for the authenticated user,
+so "issue assign 1234 @me" takes an issue during triage.
+
+A team, written "@org/team" as in "issue assign 1234 @golang/release",
+stands for one of its members: the one with the fewest open issues
+assigned in the project, taking turns among members with equally few.
+When running in a terminal, the command lists the members with their
+open issue counts and asks which to assign, defaulting to that choice.
+A team may also be written in an Assignee header line, where it is
+replaced the same way when the issue is saved. Listing team members
+requires the token to have the 'read:org' scope. A login in an Assignee
+line is checked against the users who can be assigned issues in the
+project before the issue is saved, and an unknown one is reported with
+the closest matches, as in "Unknown assignee: bradfits (did you mean
+bradfitz?)". Labels added in a Labels line are checked the same way.
+When editing a single issue in a terminal, issue instead offers to create
+a missing label, asking for its color, if the token can push to the
+repository. A milestone, in a Milestone line or the -milestone flag,
+may be given by any unambiguous prefix of its title, ignoring case,
+and a missing one may be created the same way, with an optional due date.
+Issue asks these questions only once every header line checks out,
+so a mistake on another line changes nothing.
+
+The attachments command downloads the images and files uploaded into
+the body and comments of issue n, authenticating with the GitHub token
+so that attachments in private repositories can be read. It writes them
+to the current directory, or to dir if the -o flag is given, and prints
+the name, size, and original URL of each file saved.
+
+The branch command creates a git branch for working on issue n, named
+from the issue number and title, as in "8786-duration-format", or by -name,
+and checks it out in the current git repository. The branch starts at
+the current commit, or at the -base ref. With -link, the branch is instead
+created on GitHub, starting at the head of the repository's default
+branch or the -base branch, and linked to the issue in the Development
+section of its page, where the issue's Branch header lines show it;
+issue then fetches it from the -remote, origin by default, and checks
+out a local branch tracking it.
+
+The check-refs command scans the messages of the commits in the current
+git repository, as listed by git log with the given revision range (by
+default, the history of the current branch), for references to issues
+like "Fixes #1234" and "Updates golang/go#1234". It reports each issue
+that such a commit says it fixes but that is still open, and each
+reference to another repository, to a pull request, or to an issue that
+does not exist. The -since flag limits the scan to recent commits.
+Check-refs exits with status 3 if it reports any problems.
+
+The clone command copies issue n into the repository given by -to,
+for reports filed in the wrong repository that GitHub cannot transfer,
+such as across organizations. The copy has the same title and the same
+text, preceded by a line linking to the original and to its author's profile,
+without mentioning the author, along with those of the original's labels
+that exist in the target repository; the others are listed.
+The command prints the new issue's URL.
+
+The completion command prints a completion script for the named shell.
+To enable completion, add to the shell's startup file:
+
+	source <(issue completion bash)   # bash
+	source <(issue completion zsh)    # zsh
+	issue completion fish | source    # fish
+
+Besides flags and command names, the scripts complete search qualifiers
+in queries, including label names after "label:", milestone titles after
+"milestone:", and assignable user logins after "assignee:", "author:",
+and "mentions:". The values are kept in the user's cache directory
+and refreshed from GitHub once a day.
+
+The dup command closes issue n as a duplicate of issue m: it comments
+"Duplicate of #m" on n, which GitHub shows as marking n a duplicate,
+closes n as not planned, and comments on m to link back to n.
+
+The epic command prints the open and closed issues in a milestone as a tree.
+Umbrella issues, those with a task list in their body, are listed first
+with their completion percentage. Beneath each umbrella are its tasks;
+a task that refers to another issue in the milestone ("- [ ] #nnnn")
+makes that issue a child, nested with its own tasks beneath it.
+A task referring to a closed issue counts as complete.
+Issues that are neither umbrellas nor children are listed last.
+
+The feed command writes an Atom feed of the issues matching the query,
+most recently updated first, for feed readers and chat integrations.
+The feed lists at most 50 issues. It is written to standard output,
+or to the file named by the -o flag.
+
+The fixmsg command prints a commit message skeleton for a change fixing
+issue n: the issue title, with a "pkg: " placeholder if it does not
+already begin with a package name, and a "Fixes #n." line.
+With -hook, fixmsg instead installs a git prepare-commit-msg hook in the
+current repository that starts the message with that skeleton when
+committing without a message on a branch named for an issue, such as one
+created by issue branch. The hook runs issue, which must be in $PATH.
+An existing hook is replaced only with -f.
+
+The fs command serves issues as a 9P file system, posted as "issue"
+(or the -name name) in the plan9port name space directory, for use
+by 9p(1), shell scripts, and acme after mounting it with 9pfuse:
+
+	/n/issue/golang/go/1234/header		the header lines
+	/n/issue/golang/go/1234/body		the issue text
+	/n/issue/golang/go/1234/comments	the comments
+	/n/issue/golang/go/1234/new		text written here is posted as a comment
+
+Directories list only the projects and issues opened so far.
+
+The graph command prints a Graphviz DOT graph of the issues matching
+the query. Phrases like "blocked by #nnnn", "depends on #nnnn", and
+"blocks #nnnn" in issue bodies become labeled dependency edges,
+pointing from each issue to the issues it waits on. Other references
+to issues in the project become dashed, unlabeled edges.
+Referenced issues outside the query results are drawn dashed, and
+closed issues are shaded. The -mermaid flag writes a Mermaid flowchart
+instead, and the -comments flag also scans each issue's comments.
+
+The hide command hides the comments with the given IDs, as shown in
+comment header lines, for the -r reason: abuse, duplicate, off-topic
+(the default), outdated, resolved, or spam. Hiding comments requires
+the token to have triage or write access to the repository.
+Issue collapses hidden comments to their header lines, marked with the
+reason, unless the -show-hidden flag is given. It similarly collapses
+the comments by the authors listed in the collapse-authors setting,
+such as bots, to their header lines and the first line of their text,
+unless the -expand flag is given.
+
+The label command adds the labels prefixed with + to issue n
+and removes those prefixed with -. The milestone command moves
+issue n to the named milestone, or removes it from its milestone
+if the name is "none". Both make the change directly, without an editor.
+
+The merge command consolidates issue src into issue dst. It copies the
+text and comments of src into dst as quoted comments, each attributed to
+its author, packing them into as few comments as GitHub's length limit
+allows. It then adds to dst the labels of src that dst lacks, moves dst
+into src's milestone if dst has none, and closes src as a duplicate
+of dst, as the dup command does.
+
+The milestones command lists the project's open milestones in order
+by due date, with their counts of open and closed issues and the
+percentage of the issues closed. The -state flag lists closed or all
+milestones instead, marking the closed ones, and -sort title sorts
+them by title. The -due-before and -due-after flags list only the
+milestones due before or on and after a date, and -title only those
+whose titles match a regular expression. With the -ics flag,
+it instead prints an iCalendar feed with an all-day event on each
+milestone's due date, so that release deadlines can be shown in calendars.
+With the -mermaid flag, it prints a Mermaid Gantt chart, for embedding
+in documents, with a bar for each milestone with a due date, running from
+the milestone's creation to its due date and labeled with the share of
+its issues closed. Milestones with no open issues are shown as done,
+and overdue ones with open issues as critical.
+
+The pick command finds an issue known locally by fuzzy matching: the
+project's open issues, listed from GitHub at most once a day (or again
+with -refresh), and any other issues in the -serve mirror. On a terminal,
+it shows the issues matching the pattern typed so far, each by number,
+title, and labels, and prints the number of the one chosen with enter,
+or with -o opens it in the web browser. Each word of the pattern must
+match, its letters appearing in order, as in "issue pick gorleak".
+Without a terminal, pick prints all the issues matching the pattern.
+
+The plumbing command prints plumbing rules for plan9port's plumber,
+described in the next section.
+
+The policy command runs the triage policies in the configuration
+file (see Configuration below). "issue policy list" lists the policies
+and their actions; "issue policy run" runs the named policies, or all
+of them. A policy considers the open issues matching its query, or all
+open issues, and acts on those meeting all of its conditions: having
+all of the listed labels and none of the excluded ones, being in the
+given milestone (or "none"), being assigned or not, having been created
+at least the given age ago, and having been inactive (not updated) for
+at least the given time. Durations are written like "36h", "30d",
+or "2w". The actions are posting a comment, pinging users by mentioning
+them in the comment, adding (+name) and removing (-name) labels, and
+closing the issue. Since acting on an issue updates it, a policy with an
+inactive condition does not act on the same issue again until it has been
+inactive again for that long. The -n flag prints the issues that would
+be acted on without taking any actions.
+
+The ratelimit command prints, for the core, search, and GraphQL APIs,
+the remaining requests in the current rate limit window, when the window
+resets, and how many of the window's requests issue itself has made,
+counting all its invocations, so that you can tell whether a large bulk
+edit will fit in what remains. The rate limits belong to the token and
+are shared with any other programs using it.
+
+To keep a single run from spending a shared token's budget, the
+-max-requests=n flag stops issue before it makes more than n GitHub
+API requests, failing the operation that would exceed the budget.
+When standard input and standard error are a terminal, issue instead
+asks whether to allow another n requests. The changes to one issue
+are checked against the budget together, before the first is made,
+so a bulk edit stops between issues, never partway through one, and
+can be finished later with issue resume. With -max-requests set,
+issue prints a summary of the requests it made, by category (read,
+write, search, and graphql), on standard error when it exits.
+
+The resolved-by command reports how closed issue n was fixed: the
+commit that closed it, with the pull request that merged the commit,
+if any, and the first release tag containing it; the commits
+cherry-picked from that commit onto other branches, as recorded by
+"git cherry-pick -x"; and backport issues referring to n, like those
+golang/go opens for release branches, with the commits that fixed them.
+Finding the release tags is fast when run in a clone of the repository
+that has the commits, and otherwise compares the commits with the
+project's GitHub releases, which repositories like golang/go lack.
+
+The resume command finishes a bulk edit that was interrupted, such as
+by a crash or by typing ^C. Before changing any issues, a bulk edit
+writes a journal of the edit to the cache directory and then records
+each step of each issue's update as it is done: the metadata change,
+the labels, and the comment, which is posted last. The journal is
+removed once every issue has been updated; if any remain, resume
+applies the edit to them, as though the edit had not stopped. For an
+issue whose update failed partway, resume does only the steps not yet
+done, and does not skip the issue for having changed since the edit
+began (see ``Bulk Edit Window'' below), since the edit itself changed
+it. With no argument, resume lists the journals of unfinished bulk
+edits.
+
+The retitle command changes the title of issue n, without an editor,
+for quick edits during triage such as adding a package prefix:
+"issue retitle 1234 net/http: Server hangs on close". The words of the
+title may be given as separate arguments or quoted as one.
+
+The schedule command runs the reports in the configuration file
+(see Configuration below) on their schedules until interrupted,
+so that, for example, a nightly triage digest needs no cron job.
+Each report lists the open issues matching its query and is written
+to standard output, or instead to its file (replacing it) or to the
+chat webhooks, or both. A report runs every given interval, like "12h"
+or "1d", or, if it gives a time of day, at that time every day or every
+given number of days. The times of the last runs are kept in the cache
+directory, so that restarting the command neither repeats nor skips
+reports. The -once flag runs every report once immediately and exits.
+
+The sendmail command reads a mail message from standard input and
+posts its text as a comment on the issue that the message replies to,
+as identified by its In-Reply-To or References header. It accepts and
+ignores any arguments, so that it can be configured as a mail client's
+sendmail program for replies to the messages written by -mbox (see
+Mbox Output below) or to GitHub's notification mail. Quoted text at the
+end of the message, and the line introducing it, are removed.
+
+The split command turns an umbrella issue into child issues: each
+unchecked task list item in the body of issue n that does not already
+refer to an issue becomes a new issue titled with the item's text,
+with the same labels and milestone as n. The items are then replaced
+by references to the new issues, which GitHub displays with their
+titles and states, and the new issue numbers are printed. If n is
+edited while the issues are being created, its body is left alone.
+The -n flag prints the items that would be split out.
+
+The suggest-owner command looks for the repository's files mentioned
+in the body and comments of issue n, such as in stack traces or links,
+and matches them against the repository's CODEOWNERS file to suggest
+assignees, most frequent first. If the configuration file (see
+Configuration below) has path-labels rules, which use the CODEOWNERS
+syntax with labels in place of owners, it suggests labels too.
+The -apply flag adds the suggested assignees and labels after asking
+for confirmation, or without asking if -y is also given. Teams are
+replaced by one of their members, as in the assign command.
+
+The task command lists the task list items in the body of issue n,
+numbered from 1. Given an operation and a task number, it updates
+that item's checkbox by editing the issue body. If the body is edited
+by someone else between reading and writing it, the command fails
+rather than overwrite their changes.
+
+The todo command prints the open issues matching the query, by default
+those assigned to the authenticated user, as todo.txt lines, in order
+by issue number. Each line gives the issue's creation date and title,
+the project as a +owner/repo tag, its labels as @context tags (with spaces
+replaced by underscores), and a key like issue:golang/go#1234.
+With the -o flag, the command instead updates the named todo.txt file,
+replacing the lines with keys for the project's issues and keeping
+all other lines, so that the file can be regenerated at any time.
+
+The trace command finds the stack frames, like "/usr/local/go/src/net/http/server.go:1850",
+in the text of issue n and prints, for each frame in the project's
+repository, a link to that line on GitHub. A file in the module cache
+is shown at its module version, and, for golang/go, a file in GOROOT
+is shown at the Go version from the "go version" output in the issue.
+Other files are shown on the default branch, or all files at the -ref
+git ref. With -a, trace instead opens each file in an acme window
+with the frame's line selected.
+
+The triage command, with the -apply-rules flag, applies the labeling
+rules in the configuration file (see Configuration below) to the issues
+matching the query, printing each changed issue and its changes.
+A rule matches an issue when its regular expression matches the issue's
+title or body, or only the one named by "in". Every matching rule adds
+its labels; the first matching rule with a milestone or assignee sets it,
+but only on issues that have none. An assignee may be a team, as in the
+assign command. The -n flag prints the changes without making them.
+
+The tw-sync command exports the open issues matching the query
+into Taskwarrior, creating or updating one task per issue.
+Each task's UUID is derived from the issue URL, so running the command
+again updates the same tasks. Labels become tags, the milestone becomes
+the project, and the issue URL is added as an annotation. A task completed
+in Taskwarrior while its issue is still open is left alone, unless the
+-close flag is given, in which case the issue is closed on GitHub.
+The -n flag prints the tasks that would be imported, in Taskwarrior's
+JSON format, instead of running "task import".
+
+The watch command checks the queries and issues listed in the
+configuration file (see Configuration below) every few minutes and
+posts a message to the configured chat webhooks for each issue that
+is opened, closed, reopened, or given a new label. The first check
+only records the state of the issues. The -once flag checks once and
+exits, for running from cron. The -metrics flag serves the same
+Prometheus metrics as -serve at /metrics on the given address.
+
+Like git, issue also runs external commands: if the first argument is
+a lower-case name like "stale" that is not one of the commands above,
+and an executable named issue-stale is found in $PATH, issue runs it
+with the remaining arguments, exiting with its exit status. The command
+finds the project in $ISSUE_PROJECT, the GitHub token in $ISSUE_TOKEN,
+the API endpoint in $ISSUE_API_URL, and the issue program itself in
+$ISSUE, for running issue commands in turn. External commands take
+precedence over queries of the same name; the usage message lists them.
+
+Batch Mode
+
+The -batch flag makes issue read operations from standard input,
+one per line, and apply them in order. The operations are:
+
+	comment <n> <text>
+	label <n> +<add> -<remove> ...
+	milestone <n> <milestone-name>|none
+	retitle <n> <title>
+	close <n>
+	reopen <n>
+
+Words are separated by spaces; a word beginning with a double quote is
+a Go-syntax quoted string, so comment text containing newlines can be
+written as "first line\nsecond line". Blank lines and lines beginning
+with # are ignored. If GitHub's rate limit is exhausted, issue pauses
+until it resets. After the last operation, issue prints any failures,
+each with its input line, and a count of successes and failures,
+exiting with status 1 if any operation failed.
 
 Acme Editor Integration
 
@@ -47,6 +538,8 @@ and open a window (or navigate to an existing one).
 
 	nnnn			issue #nnnn
 	#nnnn			issue #nnnn
+	owner/repo#nnnn		issue #nnnn in another project
+	owner/repo#all		the issue list of another project
 	all			the issue list
 	milestone(s)		the milestone list
 	<milestone-name>	the named milestone (e.g., Go1.5)
@@ -56,6 +549,13 @@ Executing "New" opens an issue creation window.
 Executing "Search <query>" opens a new window showing the
 results of that search.
 
+While running, issue -a also listens on the plumber's githubissue port,
+so that plumbing a reference like golang/go#1234 or a GitHub issue URL
+from any window opens that issue. The rules printed by "issue plumbing"
+send such references to that port, starting issue -a if it is not
+already running; to use them, add them to $HOME/lib/plumbing, before
+any rules that might match the same text.
+
 Issue Window
 
 An issue window, opened by loading an issue number,
@@ -69,23 +569,114 @@ For example:
 	Labels: release-none repo-main size-m
 	Milestone:
 	URL: https://github.com/golang/go/issues/8786
+	Participants: rsc (1), dsymonds (reporter)
 
-	Reported by dsymonds (2014-09-21 23:02:50)
+	Reported by dsymonds, member (2014-09-21 23:02:50)
 
 		It'd be nice if http://play.golang.org/p/KCnUQOPyol
 		printed "[+3us]", which would require time.Duration
 		implementing fmt.Formatter to get the '+' flag.
 
-	Comment by rsc (2015-01-08 05:17:06)
+	Comment by rsc, member (2015-01-08 05:17:06) [70279049] https://github.com/golang/go/issues/8786#issuecomment-70279049
 
 		time must not depend on fmt.
 
+The "Reported by" and "Comment by" lines give each author's association
+with the repository: owner, member, collaborator, contributor,
+first-time contributor, first-time GitHub user, or none.
+Each comment's header line also gives its ID in brackets and its permalink,
+so that the comment can be referred to precisely, in replies or elsewhere.
+
+When the issue or a comment has reactions, a "Reactions:" line after
+its header line tallies them, as in "Reactions: +1 12, heart 3".
+Comments are printed in time order, interleaved with the issue's
+events, such as "* rsc added to milestone Go1.5" or
+"* gopherbot requested review from rsc". With -sort-comments reactions, the comments come first instead,
+those with the most +1 reactions first, so that the most popular
+answers and workarounds in a long thread are easy to find.
+The -comments flag omits the events, printing only the comments.
+The -header flag prints only the header, without the text, comments,
+or events (or the "CLs" and "Participants" lines, which are derived
+from the comments), for quick lookups from scripts: "issue -header 8786".
+With -json, -header omits the comments, events, and pull requests,
+and -comments omits the events.
+
+The -since flag prints only the comments and events after a given time,
+a date like "2024-01-01" or an age like "7d", replacing the issue's text
+and older comments with a note saying how many were left out. Each time
+issue prints an issue to be read, on the command line or in an acme or
+sam window, but not in the tui's preview or for -stdio-server, it records
+the time in the cache directory as the issue's read mark, and -since last
+prints only what is new since the read mark, or the whole issue if it has
+never been printed:
+"issue -since last 8786". Similarly, like tail, -last n prints only the
+final n comments and events, replacing the text and earlier ones with a
+note: "issue -last 5 8786".
+
+In repositories whose organization has enabled issue types,
+the header includes a "Type:" line for an issue with a type, such as
+"Type: Bug". Adding or changing the line and executing Put sets the
+type, and removing its value clears it. Queries can select issues by
+type with the type: qualifier, as in "type:Bug".
+
+For open issues, the header also lists any pull requests that declare
+they fix the issue ("Fixes #nnnn"), with their review state and combined
+check status:
+
+	PR: #9012 open, approved, checks success
+
+It also lists the branches linked to the issue in the Development section
+of its GitHub page, which often means that someone has started on a fix,
+naming the branch's repository when it is not the project's:
+
+	Branch: 8786-duration-format in dsymonds/go
+
+For projects that review changes in Gerrit, as golang/go does, a "CLs"
+header lists the changes (CLs) linked from the issue's comments, such as
+those a bot posts when a CL mentions the issue, with each CL's status
+(new, merged, or abandoned) as reported by the Gerrit server, or unknown
+if the server cannot be asked:
+
+	CLs: 412345 merged, 413456 abandoned
+
+If the issue body contains a task list ("- [ ] item" or "- [x] item"),
+the header shows how many of the tasks are complete, as in "Tasks: 3/7",
+and issue lists show the same count after the title.
+
+The "Participants" header lists the issue's reporter, assignees, and
+commenters, each with the number of comments they have posted,
+those with the most comments first: a guide to whom to ask or mention.
+
 Executing "Get" reloads the issue data.
 
+Executing "Task" toggles the checkbox of the task list item on the selected
+line (or the line containing the cursor), as the task command does.
+
+Executing "History" opens a window showing the edit history of the issue
+body and comments, as printed by the -history flag (see Edit History below).
+
+Executing "Both" opens a window showing the raw markdown of the issue body
+and each comment beside its wrapped form, as printed by the -both flag.
+
+Executing "Reply" with the cursor on a comment's header line, or with a
+comment ID or permalink selected, starts a reply to that comment: it adds
+a link to the comment and a quotation of its text where a new comment
+is written. Writing the reply below the quotation and executing Put
+posts it. A quotation left without any reply is not posted.
+
+Executing "Hide" with the cursor on a comment's header line hides the
+comment as off-topic, as the hide command does; "Hide outdated" and
+the like give another reason. Hidden comments are collapsed to their
+header lines, marked with the reason they were hidden.
+
+Executing "Expand" shows the comments collapsed because of the
+collapse-authors setting in full, and executing it again collapses them.
+
 Executing "Put" updates an issue. It saves any changes to the issue header
 and, if any text has been entered between the header and the "Reported by" line,
 posts that text as a new comment. If both succeed, Put then reloads the issue data.
-The "Closed" and "URL" headers cannot be changed.
+The "Closed", "Tasks", "URL", "PR", "Branch", "CLs", and "Participants"
+headers cannot be changed.
 
 Issue Creation Window
 
@@ -166,6 +757,17 @@ the metadata header and, if any text has been entered between the header
 and the first issue line, posts that text as a comment. If all operations succeed,
 Put then refreshes the window as Get does.
 
+After updating the issues, Put prints a table of the results, one issue
+per line, listing the changes made and the outcome: ok, skipped, or the
+error, so that the failed issues can be retried on their own. Bulk edits
+made with issue -e and issue resume print the same table on standard
+output (see ``JSON Input'' below for the -json form).
+
+Put does not overwrite changes made by others in the meantime: it skips
+and reports any issue updated since the window last loaded it, which
+Get then shows as it now is. The same holds for bulk edits made with
+issue -e and for a journaled edit finished by issue resume.
+
 Milestone List Window
 
 The milestone list window, opened by loading any of the names
@@ -180,6 +782,120 @@ For example:
 Loading one of the listed milestone names opens a search for issues
 in that milestone.
 
+Terminal Output
+
+When standard output is a terminal, issue renders issue numbers,
+issue references, and URLs as OSC 8 hyperlinks to the corresponding
+GitHub pages, so that they can be clicked in terminals that support them.
+
+Terminal output is also colored: issue states, header names, comment
+headers, and labels, which are drawn in their colors from the repository.
+Fenced code blocks are highlighted according to their language
+(Go, C, JavaScript, Rust, Java, Python, or shell), as are diffs and
+Go panics and goroutine stack traces, fenced or not. Code blocks,
+stack traces, and indented lines are never re-wrapped.
+
+Issue wraps the text of issues and comments to fit the terminal,
+or at 70 columns when not writing to a terminal (100 in acme).
+The -wrap flag sets the width instead, and -nowrap turns wrapping off.
+Markdown tables, like code blocks, are never wrapped.
+
+Times are printed in UTC. The -tz flag selects another time zone:
+Local for the system's, or a name like America/New_York. The -relative
+flag instead prints times relative to now, like "3 days ago". Acme
+windows show both, as in "2025-06-02 14:03:11, 3 days ago".
+The time-format setting in the configuration file (see Configuration
+below) changes the format of printed times, including those in -field
+output, which are otherwise in RFC 3339 format. It may be a Go time
+layout, like "Jan 2, 2006 3:04PM", or a strftime format, like
+"%d/%m/%Y %H:%M", using %Y, %y, %m, %d, %e, %H, %I, %M, %S, %p, %b,
+%B, %a, %A, %Z, %z, %F, %T, %R, and %D.
+The -color flag controls coloring: "auto", the default, colors output
+only when writing to a terminal and the NO_COLOR environment variable
+is unset; "always" and "never" override the detection.
+
+When printing an issue or query results to a terminal, issue pipes
+the output through $PAGER, or less if $PAGER is unset, or, if less
+is not installed, prints it directly.
+Unless $LESS is already set, issue sets it to "FRX", so that less
+exits immediately when the text fits on one screen and passes colors
+and hyperlinks through. The -no-pager flag disables the pager.
+
+When standard error is a terminal and a search, issue list, or comment
+thread takes more than a couple of pages to fetch, issue shows a progress
+line there, with the pages and items fetched so far and the API requests
+remaining, erasing it once the fetch is done.
+
+Terminal Browsing
+
+For those who do not run acme, the -tui flag browses the issues matching
+the query, or all open issues, in a full-screen terminal interface.
+The issues are listed on the left, and the selected issue's thread is
+shown on the right. The keys are:
+
+	j, k, down, up    select the next or previous issue
+	space, b          scroll the thread down or up a page
+	o                 open the selected issue in the web browser
+	c                 comment on the issue, written in the system editor
+	l                 change labels, typed as +name to add or -name to remove
+	m                 set the milestone, or none to remove it
+	u                 undo the latest triage key action
+	r                 reload the list
+	q                 quit
+
+The tui-keys setting in the configuration file (see Configuration below)
+adds triage keys, each applying a batch operation other than retitle
+(see Batch Mode below) to the selected issue immediately, as in:
+
+	tui-keys:
+	  b: label +bug
+	  "5": milestone Go1.25
+	  x: close
+
+A triage key overrides the built-in key of the same name, except q and u.
+Before each action, issue saves the issue's state, so that u can undo
+the actions in reverse order, restoring the state, labels, and milestone
+and deleting any comment posted.
+
+Editor Plugins
+
+The -stdio-server flag makes issue serve requests from an editor plugin,
+such as for Vim or Emacs, so that the plugin can reuse issue's parsing,
+caching, and authentication. Requests and responses are JSON-RPC 2.0
+messages, one per line, on standard input and output. The methods are:
+
+	open     {"number": n}               the text of issue n, as {"text": ...}
+	search   {"query": q}                the issues matching q, as with -json
+	put      {"number": n, "text": t}    apply edited text t of opened issue n
+	comment  {"number": n, "text": t}    post t as a comment on issue n
+
+The text of an issue is as in an acme issue window (see above), and put
+applies the edits as Put does, relative to the issue as last opened,
+returning the updated text. Each method also accepts a "project" parameter,
+which defaults to the -p flag.
+
+Sam Editor Integration
+
+If the -sam flag is specified, issue works with a running sam instead,
+writing the text of each window it would open in acme to a file in a
+temporary directory and asking the plumber to open that file, as B does.
+As in acme mode, the query is optional and defaults to "all"; its
+words form a single query, as on the command line. Issue keeps running, watching for sam to write the files:
+
+Writing an issue file (for example, golang/go/1234) applies the changes,
+as Put does in an acme issue window.
+
+Writing a new issue file, opened by the query "new", creates the issue.
+
+A search file (golang/go/all or golang/go/search) lists one issue per
+line as golang/go#nnnn followed by its title; plumbing the reference
+opens that issue. Writing the search file, perhaps after deleting some
+lines, opens a bulk edit file for the issues still listed, and writing
+the bulk edit file applies its changes to them.
+
+After applying changes, issue rewrites the file with the issues' new state,
+which can be loaded into sam with the e command.
+
 Alternate Editor Integration
 
 The -e flag enables basic editing of issues with editors other than acme.
@@ -188,14 +904,79 @@ Issue prepares a textual representation of issue data in a temporary file,
 opens that file in the editor, waits for the editor to exit, and then applies any
 changes from the file to the actual issues.
 
+Before changing anything, issue checks the header lines, in the editor
+and in acme's Put alike: an unknown header, a State other than open or
+closed, an empty Title, or a garbled Closed time is reported with its
+line number and the values expected, and no part of the edit is applied.
+
 When <query> is a single number, issue -e edits a single issue.
 See the ``Issue Window'' section above.
+With -reply-to <comment-id>, as in "issue -e -reply-to 70279049 8786",
+the new comment area starts with a reply to the comment with that ID,
+as the acme Reply command adds.
 
 If the <query> is the text "new", issue -e creates a new issue.
 See the ``Issue Creation Window'' section above.
 
 Otherwise, for general queries, issue -e edits multiple issues in bulk.
-See the ``Bulk Edit Window'' section above.
+See the ``Bulk Edit Window'' section above. A bulk edit updates a few
+issues at a time, showing its progress in a bar when standard error is
+a terminal, and reports any errors at the end, in the order of the list.
+
+With -atomic, a bulk edit, whether run by issue -e or by Put in acme,
+applies to all the issues or to none: issue saves the state of each
+issue before updating it, and if any update fails, it stops, deletes
+the comments it posted, and restores the state, assignees, labels,
+milestone, and type of the issues it changed.
+
+Long Comments
+
+GitHub limits issue and comment bodies to 65536 characters.
+When a new issue or comment is longer than that, issue uploads the text
+past the limit as a secret gist and ends the posted text with a link to it.
+If the -gist flag is given, issue also moves every fenced code block longer
+than 20 lines, such as a long log or crash dump, into its own secret gist,
+leaving a link in its place. Creating gists requires the token to have
+the 'gist' scope.
+
+Exit Status
+
+Issue exits with status 0 on success, 1 if a query matched no issues,
+2 for invalid flags or arguments, 3 if a GitHub API request or other
+operation failed, and 4 if credentials are missing or were rejected.
+For a query, status 1 is reported even with -json, which still prints
+an empty list, so that scripts can test whether anything matched.
+
+Serve Mode
+
+The -serve flag makes issue run an HTTP server on the given address,
+answering read-only requests about the project's issues, so that editor
+plugins and dashboards can query the tracker without each holding a
+GitHub token. For example, after
+
+	issue -serve localhost:8123
+
+the server answers these requests:
+
+	/issue/N     the issue numbered N, with comments
+	/search?q=Q  the issues matching the query Q
+	/feed?q=Q    an Atom feed of the issues matching Q, as from "issue feed"
+
+The other responses use the same JSON structures as the -json flag
+(see the next section); adding json=2 to the request selects version 2.
+For browsing, the server also renders simple read-only web pages:
+/ lists the issues matching the query in its q parameter, and /N shows
+the issue numbered N and its comments.
+
+The server keeps a mirror of its responses in the user's cache directory.
+A response less than a minute old is served from the mirror without
+asking GitHub; an older one is served, with a Warning header, only when
+GitHub cannot be reached.
+
+For monitoring, /metrics reports in the Prometheus text format the
+number of GitHub API requests and failures, the remaining API rate limit,
+the time since the mirror was last refreshed from GitHub, and the number
+of requests served.
 
 JSON Output
 
@@ -215,9 +996,15 @@ using these data structures:
 		Reporter  string
 		Created   time.Time
 		Text      string
+		Tasks     []*Task
 		Comments  []*Comment
 	}
 
+	type Task struct {
+		Done bool
+		Text string
+	}
+
 	type Comment struct {
 		Author string
 		Time   time.Time
@@ -226,6 +1013,290 @@ using these data structures:
 
 If asked for a specific issue, the output is an Issue with Comments.
 Otherwise, the result is an array of Issues without Comments.
+
+The -json=2 flag selects version 2 of the output, which adds more
+information and will grow over time, while the version 1 structures
+above remain unchanged. The version must be joined to the flag by =,
+because a plain -json selects version 1: "issue -json 2" prints issue 2
+as version 1 JSON.
+
+	type Issue2 struct {
+		Number        int
+		ID            int64
+		NodeID        string
+		Ref           string
+		Title         string
+		State         string
+		StateReason   string
+		Assignee      string
+		Assignees     []string
+		Closed        time.Time
+		ClosedBy      string
+		Labels        []string
+		Milestone     string
+		URL           string
+		Reporter      string
+		Created       time.Time
+		Updated       time.Time
+		Locked        bool
+		IsPullRequest bool
+		Merged        bool
+		MergeCommit   string
+		Reactions     map[string]int
+		Text          string
+		Tasks         []*Task
+		PullRequests  []*PullRequest
+		CLs           []*CL
+		Comments      []*Comment2
+		Events        []*Event
+	}
+
+	type Comment2 struct {
+		ID        int64
+		NodeID    string
+		Author    string
+		Time      time.Time
+		Updated   time.Time
+		Reactions map[string]int
+		Text      string
+	}
+
+	type PullRequest struct {
+		Number int
+		Title  string
+		State  string
+		Review string
+		Checks string
+	}
+
+	type CL struct {
+		Number  int
+		URL     string
+		Status  string
+		Subject string
+	}
+
+	type Event struct {
+		Actor     string
+		Event     string
+		Time      time.Time
+		Assignee  string `json:",omitempty"`
+		Label     string `json:",omitempty"`
+		Milestone string `json:",omitempty"`
+		CommitID  string `json:",omitempty"`
+		From      string `json:",omitempty"`
+		To        string `json:",omitempty"`
+	}
+
+ID and NodeID are GitHub's numeric REST and GraphQL node identifiers,
+for tools that edit, delete, or react to a specific issue or comment.
+IsPullRequest distinguishes pull requests, which GitHub also reports
+as issues, from plain issues; for a merged pull request, Merged is true
+and MergeCommit is the SHA of the merge commit.
+The state reasons and merge status of the closed issues in a list are
+loaded together, in one GraphQL request for every 100 issues.
+Reactions maps reaction names such as "+1" and "heart" to their counts.
+PullRequests lists the pull requests that declare they fix the issue,
+as in the PR header lines, and CLs lists the Gerrit changes linked from
+the comments, as in the CLs header line, with an empty Status if it is
+unknown. As in version 1, Comments, Events, PullRequests, and CLs are
+filled in only for a specific issue.
+
+Field Output
+
+The -field flag prints selected fields of the JSON data structures
+as plain text, one issue per line with fields separated by tabs,
+so that common scripted uses do not require a JSON processor.
+The flag's value is a comma-separated list of field names, which are
+case-insensitive; a dotted path selects a field within a list of
+structures, as in Comments.Author. List values are printed with elements
+separated by commas, times in RFC 3339 format, and newlines and tabs
+in text as \n and \t. For example:
+
+	$ issue -field number,title,labels label:Documentation
+	8231	doc: clarify io.Reader semantics	Documentation,NeedsFix
+
+Comments are only loaded when a listed field refers to them.
+The -json flag's output is unaffected.
+
+Org Output
+
+The -org flag prints the results as an Org mode document for planning
+in Emacs, with one heading per issue: a TODO item for an open issue
+and a DONE item for a closed one, tagged with the issue's labels and,
+when its milestone has a due date, scheduled on that date. Each heading
+has properties giving the issue reference, URL, assignee, milestone,
+reporter, and creation time. For a specific issue, the issue text
+follows the properties.
+
+Edit History
+
+The -history flag, as in "issue -history 1234", prints the revisions
+of the numbered issue's body and of each of its edited comments.
+The original text is printed in full, followed by a unified diff for
+each later edit, with the editor's name and the time of the edit.
+GitHub only makes the edit history available through its GraphQL API,
+which requires a token even for public repositories.
+
+Mbox Output
+
+The -mbox flag writes the issues matching the query, or the single
+numbered issue, as mail messages in mboxrd format, for reading and
+searching in a mail client:
+
+	issue -mbox label:NeedsFix >needsfix.mbox
+
+Each issue is a message from its reporter with subject
+"[owner/repo] Title (#N)" and the issue body as its text, followed by
+one reply per comment. The Message-ID, In-Reply-To, and References
+headers match those of GitHub's notification mail, so the messages
+thread together with it. The issue's state, labels, milestone, and URL
+are recorded in the X-Issue-State, Keywords, X-Issue-Milestone, and
+X-Issue-URL headers.
+
+JSON Input
+
+The -json and -e flags together, as in "issue -json -e 1234", edit
+the numbered issue by applying an IssuePatch read as JSON from standard
+input, so that other programs can make changes without generating
+the textual edit format:
+
+	type IssuePatch struct {
+		Title     *string
+		State     *string
+		Assignee  *string
+		Labels    *[]string
+		Milestone *string
+		Text      *string
+		Comment   string
+	}
+
+Omitted or null fields are left unchanged. An empty Milestone removes
+the issue from its milestone, Text replaces the issue body, and a
+non-empty Comment is posted as a new comment. For convenience,
+the read-only fields of Issue (Number, Ref, Closed, URL, Reporter,
+Created, Tasks, and Comments) are accepted and ignored, so the output
+of -json can be edited and sent back; any other field is an error.
+After applying the patch, issue prints the updated Issue, without Comments.
+
+With a query instead of an issue number, the -json and -e flags edit the
+matching issues in bulk in the system editor, as -e alone does, and then
+print the results of the edit as a JSON array of BulkResult:
+
+	type BulkResult struct {
+		Number     int
+		Changes    []string // changes made successfully
+		Error      string   // omitted if none
+		Skipped    bool     // changed since loaded, so not updated
+		NotTried   bool     // not updated after an -atomic edit failed
+		RolledBack bool     // changes undone after an -atomic edit failed
+	}
+
+The issue resume command also prints its results this way with -json.
+
+Configuration
+
+Issue reads optional settings from issue/config.yaml in the user's
+configuration directory ($XDG_CONFIG_HOME or ~/.config on Unix systems).
+For example:
+
+	queries:
+	  mine: assignee:rsc label:NeedsFix
+	watch:
+	  interval: 10m
+	  queries: [mine, "label:release-blocker"]
+	  issues: [1234]
+	webhooks:
+	  - url: https://hooks.slack.com/services/...
+	  - url: https://matrix.example.com/_matrix/client/r0/rooms/...
+	    kind: matrix
+	path-labels:
+	  - "/src/net/http/ NeedsInvestigation"
+	rules:
+	  - match: "^cmd/go:"
+	    in: title
+	    labels: [GoCommand]
+	    assignee: "@golang/tools-team"
+	  - match: "(?i)data race"
+	    labels: [RaceDetector]
+	    milestone: Backlog
+	policies:
+	  - name: no-response
+	    query: label:WaitingForInfo
+	    if:
+	      inactive: 30d
+	    then:
+	      comment: Closing for lack of response. Please comment if this is still a problem.
+	      label: [-WaitingForInfo]
+	      close: true
+	  - name: stale-assigned
+	    if:
+	      assigned: true
+	      no-labels: [Pinned]
+	      inactive: 8w
+	    then:
+	      ping: [assignees]
+	      comment: Are you still working on this?
+	reports:
+	  - name: nightly
+	    query: label:release-blocker
+	    at: "09:00"
+	    webhook: true
+	  - name: mine
+	    query: mine
+	    every: 4h
+	    file: /home/rsc/mine.txt
+	tui-keys:
+	  b: label +bug
+	  x: close
+	time-format: "%d/%m/%Y %H:%M"
+	collapse-authors: [gopherbot]
+	comment-filter: strip-boilerplate
+	hooks:
+	  pre-put: require-close-comment
+	  post-put: logger -t issue
+	token-command: gh auth token
+	api: https://github.example.com
+	http-headers:
+	  - "X-Gateway-Key: abc"
+
+The queries are saved queries, by name. The watch section lists the
+saved query names or queries and the issue numbers that the watch
+command checks, and how often (default 5m). The webhooks are posted to
+by the watch command and by reports with webhook set; kind is slack
+(the default), mattermost, or matrix. A Slack or Mattermost webhook
+is sent {"text": message}; a Matrix one is sent an m.text message event, so its URL must include the access token.
+The path-labels rules are used by the suggest-owner command,
+the rules by the triage command, the policies by the policy command,
+the reports by the schedule command, and the tui-keys by -tui.
+The time-format applies to all printed times (see Terminal Output above).
+The comments by the collapse-authors are printed collapsed to one-line
+summaries (see the hide command above).
+The comment-filter is a command, run by the shell if it has arguments,
+that each comment's text is piped through before it is printed, for
+site-specific policies like stripping boilerplate or translating.
+The environment variables $ISSUE_COMMENT_ID, $ISSUE_COMMENT_AUTHOR, and
+$ISSUE_COMMENT_URL identify the comment. The command's output replaces
+the text, and empty output hides the comment, as a spam classifier might.
+If the command fails, the original text is printed.
+The hooks are commands run when issues change: pre-put before issue
+changes an issue, post-put after, and post-create after an issue is
+created by Put or the create command. The put hooks run for every
+change of an issue's title, state, assignees, labels, milestone, or
+type and every comment posted: by Put (in an editor, acme, or a bulk
+edit), by -json -e, by -batch and the tui keys, and by commands such
+as close, comment, label, assign, dup, merge, triage, and policy.
+Only undoing changes, by an -atomic bulk edit's rollback or the tui's
+u key, and the task command, which changes just the body, run no hooks.
+Each hook reads a JSON description of the change on standard input,
+with the issue's Project, Number, URL, and State (if known), the Hook
+name, and the Title, NewState, Assignee or Assignees (or AddAssignees
+and RemoveAssignees), Labels (or AddLabels and RemoveLabels),
+Milestone, Type, and Comment being set, if any; post-put adds the list
+of Changes made. A pre-put hook that exits with
+a non-zero status vetoes the change, and its output is reported as the
+error, so a hook can enforce policies like "closing requires a comment".
+Failures of the post hooks are only logged.
 */
 package main // import "rsc.io/github/issue"
 
@@ -239,6 +1310,7 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -249,27 +1321,77 @@ import (
 	"time"
 
 	"github.com/google/go-github/v45/github"
-	"golang.org/x/oauth2"
 )
 
 var (
-	acmeFlag  = flag.Bool("a", false, "open in new acme window")
-	editFlag  = flag.Bool("e", false, "edit in system editor")
-	jsonFlag  = flag.Bool("json", false, "write JSON output")
-	project   = flag.String("p", "golang/go", "GitHub owner/repo name")
-	rawFlag   = flag.Bool("raw", false, "do no processing of markdown")
-	tokenFile = flag.String("token", "", "read GitHub token personal access token from `file` (default $HOME/.github-issue-token)")
-	logHTTP   = flag.Bool("loghttp", false, "log http requests")
+	acmeFlag    = flag.Bool("a", false, "open in new acme window")
+	apiFlag     = flag.String("api", "", "use the GitHub API at `url`, such as https://github.example.com for GitHub Enterprise Server")
+	atomicFlag  = flag.Bool("atomic", false, "undo a bulk edit's changes if any issue fails to update")
+	batchFlag   = flag.Bool("batch", false, "run batch operations read from standard input")
+	bothFlag    = flag.Bool("both", false, "show the raw markdown of the body and comments beside the wrapped text")
+	colorFlag   = flag.String("color", "auto", "color terminal output: `when` is auto, always, or never")
+	commentFlag = flag.Bool("comments", false, "print an issue's comments but not its events")
+	editFlag    = flag.Bool("e", false, "edit in system editor")
+	expandFlag  = flag.Bool("expand", false, "show the full text of comments by the collapse-authors from the configuration")
+	fieldFlag   = flag.String("field", "", "print only the comma-separated `list` of JSON fields, tab-separated")
+	gistFlag    = flag.Bool("gist", false, "upload long code blocks in new comments as secret gists")
+	headerFlag  = flag.Bool("header", false, "print only an issue's header, without its text, comments, or events")
+	hiddenFlag  = flag.Bool("show-hidden", false, "show the text of comments that maintainers have hidden")
+	historyFlag = flag.Bool("history", false, "print the edit history of the issue and its comments")
+	httpHeaders = headerListFlag("http-header", "add the header `line`, like \"X-Key: value\", to GitHub API requests (repeatable)")
+	jsonFlag    = jsonVersionFlag("json", "write JSON output; -json=2 selects the extended schema")
+	lastFlag    = flag.Int("last", 0, "print only the last `n` comments and events of an issue")
+	maxRequests = flag.Int("max-requests", 0, "stop after `n` GitHub API requests, or ask to continue in a terminal, and print a summary")
+	mboxFlag    = flag.Bool("mbox", false, "write issues and comments as mail messages in mbox format")
+	orgFlag     = flag.Bool("org", false, "write Org mode output")
+	project     = flag.String("p", "golang/go", "GitHub owner/repo name")
+	rawFlag     = flag.Bool("raw", false, "do no processing of markdown")
+	recordFlag  = flag.String("record", "", "record the HTTP requests and responses, without credentials, in the cassette `file`")
+	relFlag     = flag.Bool("relative", false, "print times relative to now, like \"3 days ago\"")
+	replayFlag  = flag.String("replay", "", "answer HTTP requests from the cassette `file` written by -record, without using the network")
+	replyFlag   = flag.Int64("reply-to", 0, "with -e and an issue number, start a new comment replying to the comment with this `id`")
+	samFlag     = flag.Bool("sam", false, "open in sam, through the plumber")
+	serveFlag   = flag.String("serve", "", "serve a read-only HTTP API for the project on `addr`")
+	sinceFlag   = flag.String("since", "", "print only the activity on an issue since `time`: a date, an age like 7d, or last, for since it was last printed")
+	sortFlag    = flag.String("sort-comments", "time", "print comments in `order`: time, or reactions to put the most +1'd first")
+	stdioFlag   = flag.Bool("stdio-server", false, "serve JSON-RPC requests from editor plugins on standard input and output")
+	tokenFile   = flag.String("token", "", "read GitHub token personal access token from `file` (default $HOME/.github-issue-token)")
+	tuiFlag     = flag.Bool("tui", false, "browse the issues matching the query in a full-screen terminal interface")
+	tunnelFlag  = flag.String("transport", "", "connect to the API host through `spec`: unix:path for a Unix socket, or a tunnel command like \"ssh bastion -W %h:%p\"")
+	tzFlag      = flag.String("tz", "UTC", "print times in time `zone`: UTC, Local, or a name like America/New_York")
+	uploadFlag  = flag.String("upload-api", "", "upload files to the GitHub API at `url` (default the -api host)")
+	wrapFlag    = flag.Int("wrap", 0, "wrap text at `n` columns (default 70, or the terminal width)")
+	logHTTP     = flag.Bool("loghttp", false, "log http requests")
+	noPager     = flag.Bool("no-pager", false, "do not pipe terminal output through $PAGER")
+	noWrap      = flag.Bool("nowrap", false, "do not wrap text")
 )
 
+// fieldPaths is the parsed form of the -field flag.
+var fieldPaths [][]string
+
 func usage() {
 	fmt.Fprintf(os.Stderr, `usage: issue [-a] [-e] [-p owner/repo] <query>
+       issue [-p owner/repo] <command> [args]
+       issue [-p owner/repo] -serve addr
+       issue [-p owner/repo] -tui [query]
 
 If query is a single number, prints the full history for the issue.
 Otherwise, prints a table of matching results.
+
+Commands:
 `)
+	for _, c := range commands {
+		if strings.HasPrefix(c.name, "__") {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "\t%s %s\n\t\t%s\n", c.name, c.args, c.short)
+	}
+	if names := listPlugins(); len(names) > 0 {
+		fmt.Fprintf(os.Stderr, "\nExternal commands:\n\t%s\n", strings.Join(names, " "))
+	}
+	fmt.Fprintf(os.Stderr, "\nFlags:\n")
 	flag.PrintDefaults()
-	os.Exit(2)
+	exit(exitUsage)
 }
 
 func main() {
@@ -278,115 +1400,566 @@ func main() {
 	log.SetFlags(0)
 	log.SetPrefix("issue: ")
 
-	if flag.NArg() == 0 && !*acmeFlag {
+	if flag.NArg() == 0 && !*acmeFlag && !*samFlag && !*batchFlag && *serveFlag == "" && !*stdioFlag && !*tuiFlag {
 		usage()
 	}
+	if *serveFlag != "" && (flag.NArg() > 0 || *acmeFlag || *editFlag || *batchFlag) {
+		usageErrorf("cannot use -serve with a query, -a, -e, or -batch")
+	}
+	if *stdioFlag && (flag.NArg() > 0 || *acmeFlag || *samFlag || *editFlag || *batchFlag || *serveFlag != "" || *jsonFlag != 0 || *fieldFlag != "") {
+		usageErrorf("-stdio-server cannot be used with a query or other modes")
+	}
+	if *batchFlag && (flag.NArg() > 0 || *acmeFlag || *editFlag) {
+		usageErrorf("cannot use -batch with a query, -a, or -e")
+	}
 
-	if *jsonFlag && *acmeFlag {
-		log.Fatal("cannot use -a with -json")
+	if *tuiFlag && (*acmeFlag || *samFlag || *editFlag || *batchFlag || *serveFlag != "" || *stdioFlag || *jsonFlag != 0 || *fieldFlag != "" || *orgFlag || *mboxFlag || *historyFlag) {
+		usageErrorf("cannot use -tui with other modes or output formats")
+	}
+	if *wrapFlag < 0 || *wrapFlag > 0 && *noWrap {
+		usageErrorf("-wrap must be positive and cannot be used with -nowrap")
+	}
+	if loc, err := time.LoadLocation(*tzFlag); err != nil {
+		usageErrorf("invalid -tz: %v", err)
+	} else {
+		timeZone = loc
+	}
+	if *bothFlag && *rawFlag {
+		usageErrorf("cannot use -both with -raw")
+	}
+	if *sortFlag != "time" && *sortFlag != "reactions" {
+		usageErrorf("invalid -sort-comments %q: want time or reactions", *sortFlag)
+	}
+	if *jsonFlag != 0 && *acmeFlag {
+		usageErrorf("cannot use -a with -json")
+	}
+	if *orgFlag && (*jsonFlag != 0 || *fieldFlag != "" || *acmeFlag || *samFlag || *editFlag) {
+		usageErrorf("cannot use -org with -json, -field, -a, -sam, or -e")
+	}
+	if *mboxFlag && (*jsonFlag != 0 || *fieldFlag != "" || *orgFlag || *acmeFlag || *samFlag || *editFlag) {
+		usageErrorf("cannot use -mbox with -json, -field, -org, -a, -sam, or -e")
+	}
+	if *samFlag && (*acmeFlag || *editFlag || *jsonFlag != 0 || *batchFlag || *serveFlag != "") {
+		usageErrorf("cannot use -sam with -a, -e, -json, -batch, or -serve")
+	}
+	if *fieldFlag != "" {
+		if *jsonFlag != 0 || *acmeFlag || *editFlag {
+			usageErrorf("cannot use -field with -json, -a, or -e")
+		}
+		var err error
+		if fieldPaths, err = parseFieldList(*fieldFlag); err != nil {
+			usageErrorf("-field: %v", err)
+		}
+	}
+	if *historyFlag {
+		if *jsonFlag != 0 || *fieldFlag != "" || *orgFlag || *mboxFlag || *acmeFlag || *samFlag || *editFlag {
+			usageErrorf("cannot use -history with -json, -field, -org, -mbox, -a, -sam, or -e")
+		}
+		if n, _ := strconv.Atoi(flag.Arg(0)); n <= 0 || flag.NArg() != 1 {
+			usageErrorf("-history requires a single issue number")
+		}
+	}
+	if *commentFlag && *headerFlag {
+		usageErrorf("cannot use -comments with -header")
+	}
+	if (*commentFlag || *headerFlag) && (*acmeFlag || *samFlag || *editFlag || *fieldFlag != "" || *orgFlag || *mboxFlag || *historyFlag || *tuiFlag) {
+		usageErrorf("cannot use -comments or -header with -a, -sam, -e, -field, -org, -mbox, -history, or -tui")
+	}
+	if *sinceFlag != "" {
+		if *jsonFlag != 0 || *fieldFlag != "" || *orgFlag || *mboxFlag || *acmeFlag || *samFlag || *editFlag || *historyFlag || *headerFlag || *tuiFlag {
+			usageErrorf("cannot use -since with -json, -field, -org, -mbox, -a, -sam, -e, -history, -header, or -tui")
+		}
+		if *sinceFlag != "last" {
+			if _, err := parseSince(*sinceFlag, time.Now()); err != nil {
+				usageErrorf("-since: %v", err)
+			}
+		}
+	}
+	if *lastFlag != 0 {
+		if *lastFlag < 0 || *sinceFlag != "" || *sortFlag != "time" {
+			usageErrorf("-last must be positive and cannot be used with -since or -sort-comments reactions")
+		}
+		if *jsonFlag != 0 || *fieldFlag != "" || *orgFlag || *mboxFlag || *acmeFlag || *samFlag || *editFlag || *historyFlag || *headerFlag || *tuiFlag {
+			usageErrorf("cannot use -last with -json, -field, -org, -mbox, -a, -sam, -e, -history, -header, or -tui")
+		}
+	}
+	if *jsonFlag != 0 && *editFlag && strings.Join(flag.Args(), " ") == "new" {
+		usageErrorf("cannot use -json with -e new")
 	}
-	if *jsonFlag && *editFlag {
-		log.Fatal("cannot use -e with -acme")
+	if *recordFlag != "" && *replayFlag != "" {
+		usageErrorf("cannot use -record with -replay")
+	}
+	if *replyFlag != 0 {
+		if n, _ := strconv.Atoi(flag.Arg(0)); !*editFlag || *jsonFlag != 0 || n <= 0 || flag.NArg() != 1 {
+			usageErrorf("-reply-to requires -e and a single issue number")
+		}
 	}
 
+	tunnel := *tunnelFlag
+	if tunnel == "" {
+		tunnel = loadConfig().Transport
+	}
+	if t, ok := http.DefaultTransport.(*http.Transport); ok && tunnel != "" {
+		http.DefaultTransport = newTunnelTransport(t, tunnel)
+	}
+	if *recordFlag != "" {
+		t, err := newRecordTransport(http.DefaultTransport, *recordFlag)
+		if err != nil {
+			fatalf("-record: %v", err)
+		}
+		http.DefaultTransport = t
+	}
+	if *replayFlag != "" {
+		t, err := newReplayTransport(*replayFlag)
+		if err != nil {
+			fatalf("-replay: %v", err)
+		}
+		http.DefaultTransport = t
+	}
+	if headers := append(loadConfig().HTTPHeaders, *httpHeaders...); len(headers) > 0 {
+		http.DefaultTransport = newHeaderTransport(http.DefaultTransport, headers)
+	}
+	http.DefaultTransport = newUsageTransport(http.DefaultTransport)
+	defer printRequestSummary()
+	defer saveUsage()
 	if *logHTTP {
 		http.DefaultTransport = newLogger(http.DefaultTransport)
 	}
+	if *serveFlag != "" {
+		http.DefaultTransport = newMetricsTransport(http.DefaultTransport)
+	}
+
+	switch *colorFlag {
+	case "auto", "always", "never":
+	default:
+		usageErrorf("invalid -color setting: must be auto, always, or never")
+	}
+
+	if *tunnelFlag != "" {
+		if err := checkTransport(*tunnelFlag); err != nil {
+			usageErrorf("-transport: %v", err)
+		}
+	}
+	for _, u := range []string{*apiFlag, *uploadFlag} {
+		if u == "" {
+			continue
+		}
+		if pu, err := url.Parse(u); err != nil || pu.Host == "" || pu.Scheme != "https" && pu.Scheme != "http" {
+			usageErrorf("invalid API URL %q: want a URL like https://github.example.com", u)
+		}
+	}
 
 	f := strings.Split(*project, "/")
 	if len(f) != 2 {
-		log.Fatal("invalid form for -p argument: must be owner/repo, like golang/go")
+		usageErrorf("invalid form for -p argument: must be owner/repo, like golang/go")
+	}
+
+	if c := lookupCommand(flag.Arg(0)); c != nil && c.noAuth && !queryForced() {
+		if runCommand(c, *project, flag.Args()[1:]) {
+			return
+		}
 	}
 
 	loadAuth()
 
+	plain := *acmeFlag || *samFlag || *stdioFlag || *editFlag || *jsonFlag != 0 || fieldPaths != nil || *orgFlag || *mboxFlag || *tuiFlag
+	termLinks = !plain && isTerminal(os.Stdout) && supportsHyperlinks()
+	termColor = !plain && useColor(*colorFlag)
+
 	if *acmeFlag {
 		acmeMode()
 	}
+	if *samFlag {
+		samMode()
+	}
 
-	q := strings.Join(flag.Args(), " ")
+	if *batchFlag {
+		runBatch(*project, os.Stdin)
+		return
+	}
 
-	if *editFlag && q == "new" {
-		editIssue(*project, []byte(createTemplate), new(github.Issue))
+	if *serveFlag != "" {
+		serveMode(*project, *serveFlag)
 		return
 	}
 
-	n, _ := strconv.Atoi(q)
-	if n != 0 {
-		if *editFlag {
-			var buf bytes.Buffer
-			issue, err := showIssue(&buf, *project, n)
-			if err != nil {
-				log.Fatal(err)
-			}
-			editIssue(*project, buf.Bytes(), issue)
+	if *stdioFlag {
+		stdioServer(os.Stdin, os.Stdout)
+		return
+	}
+
+	if *tuiFlag {
+		tuiMode(*project, strings.Join(flag.Args(), " "))
+		return
+	}
+
+	if c := lookupCommand(flag.Arg(0)); c != nil && !c.noAuth && !queryForced() {
+		if runCommand(c, *project, flag.Args()[1:]) {
 			return
 		}
-		if _, err := showIssue(os.Stdout, *project, n); err != nil {
-			log.Fatal(err)
-		}
-		return
+	}
+	if path := lookupPlugin(flag.Arg(0)); path != "" && !queryForced() {
+		runPlugin(path, *project, flag.Args()[1:])
 	}
 
+	q := strings.Join(flag.Args(), " ")
+	if n, _ := strconv.Atoi(q); *editFlag && *jsonFlag != 0 && n > 0 {
+		editJSON(*project, n)
+		return
+	}
 	if *editFlag {
-		all, err := searchIssues(*project, q)
+		editQuery(*project, q)
+		return
+	}
+	if n, _ := strconv.Atoi(q); n != 0 {
+		printIssues(*project, []int{n})
+		return
+	}
+	printQuery(*project, q)
+}
+
+// editQuery edits issues in the system editor:
+// a new issue if q is "new", the single issue numbered q,
+// or else all the issues matching the query q, in bulk.
+func editQuery(project, q string) {
+	if q == "new" {
+		editIssue(project, []byte(createTemplate), new(github.Issue))
+		return
+	}
+	if n, _ := strconv.Atoi(q); n != 0 {
+		var buf bytes.Buffer
+		issue, err := showIssue(&buf, project, n, true)
 		if err != nil {
-			log.Fatal(err)
+			fatal(err)
 		}
-		if len(all) == 0 {
-			log.Fatal("no issues matched search")
+		text := buf.Bytes()
+		if *replyFlag != 0 {
+			draft, err := replyDraft(project, n, *replyFlag)
+			if err != nil {
+				fatal(err)
+			}
+			text = insertReply(text, draft)
 		}
-		sort.Sort(issuesByTitle(all))
-		bulkEditIssues(*project, all)
+		editIssue(project, text, issue)
 		return
 	}
+	all, err := searchIssues(project, q)
+	if err != nil {
+		fatal(err)
+	}
+	if len(all) == 0 {
+		log.Print("no issues matched search")
+		exit(exitNoMatch)
+	}
+	sort.Sort(issuesByTitle(all))
+	bulkEditIssues(project, all)
+}
+
+// printIssues prints the full history of each of the numbered issues.
+func printIssues(project string, ids []int) {
+	out, stop := stdout()
+	for i, n := range ids {
+		if i > 0 && *jsonFlag == 0 {
+			fmt.Fprintf(out, "\n")
+		}
+		var err error
+		if *historyFlag {
+			err = showHistory(out, project, n)
+		} else {
+			_, err = showIssue(out, project, n, true)
+		}
+		if err != nil {
+			stop()
+			fatal(err)
+		}
+	}
+	stop()
+}
+
+// printQuery prints the issues matching the query q.
+// If there are none, it exits with status exitNoMatch.
+func printQuery(project, q string) {
+	out, stop := stdout()
+	n, err := showQuery(out, project, q)
+	stop()
+	if err != nil {
+		fatal(err)
+	}
+	if n == 0 {
+		exit(exitNoMatch)
+	}
+}
 
-	if err := showQuery(os.Stdout, *project, q); err != nil {
-		log.Fatal(err)
+// stdout returns the writer for printing issues and query results,
+// which is a pager when standard output is a terminal,
+// along with a function to call when the output is complete.
+func stdout() (io.Writer, func()) {
+	if *noPager || !isTerminal(os.Stdout) {
+		return os.Stdout, func() {}
 	}
+	return startPager()
 }
 
-func showIssue(w io.Writer, project string, n int) (*github.Issue, error) {
-	issue, _, err := client.Issues.Get(context.TODO(), projectOwner(project), projectRepo(project), n)
+// showIssue fetches issue n and prints it to w.
+// If read is set, as it is in the views of a single issue
+// that a user reads but not in previews or for other programs,
+// printing the issue's text records its read mark, for -since last.
+func showIssue(w io.Writer, project string, n int, read bool) (*github.Issue, error) {
+	issue, err := getIssue(project, n)
 	if err != nil {
 		return nil, err
 	}
 	updateIssueCache(project, issue)
-	return issue, printIssue(w, project, issue)
+	return issue, printIssue(w, project, issue, read)
 }
 
 const timeFormat = "2006-01-02 15:04:05"
 
-func printIssue(w io.Writer, project string, issue *github.Issue) error {
-	if *jsonFlag {
-		showJSONIssue(w, project, issue)
-		return nil
+// timeZone is the time zone for printed times, set from the -tz flag.
+var timeZone = time.UTC
+
+// timeLayout returns the layout for printed times:
+// the configured time-format, or else timeFormat.
+func timeLayout() string {
+	if layout := loadConfig().timeLayout; layout != "" {
+		return layout
+	}
+	return timeFormat
+}
+
+// formatTime returns t formatted for printing: in the -tz time zone,
+// or with -relative as the time since now, like "3 days ago".
+// In acme, it gives both, as in "2006-01-02 15:04:05, 3 days ago".
+func formatTime(t time.Time) string {
+	abs := t.In(timeZone).Format(timeLayout())
+	switch {
+	case *acmeFlag:
+		return abs + ", " + relativeTime(t, time.Now())
+	case *relFlag:
+		return relativeTime(t, time.Now())
+	}
+	return abs
+}
+
+// relativeTime returns the time from now to t, like "3 days ago"
+// or "in 2 hours", rounded down to the largest whole unit.
+func relativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	form := "%d %s%s ago"
+	if d < 0 {
+		d = -d
+		form = "in %d %s%s"
+	}
+	if d < time.Minute {
+		return "just now"
 	}
+	day := 24 * time.Hour
+	for _, u := range []struct {
+		name string
+		size time.Duration
+	}{
+		{"year", 365 * day},
+		{"month", 30 * day},
+		{"week", 7 * day},
+		{"day", day},
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+	} {
+		if n := int(d / u.size); n > 0 {
+			return fmt.Sprintf(form, n, u.name, suffix(n))
+		}
+	}
+	return "just now"
+}
 
+// printComment prints a comment as part of an issue's history.
+func printComment(w io.Writer, com *github.IssueComment) {
+	fmt.Fprintf(w, "\n%s\n", commentHeader(com))
+	printReactions(w, com.Reactions)
+	printFilteredText(w, com)
+}
+
+// reactionOrder is the order in which reactions are listed.
+var reactionOrder = []string{"+1", "-1", "laugh", "hooray", "confused", "heart", "rocket", "eyes"}
+
+// printReactions prints the tallies of the reactions
+// to an issue or comment, if there are any.
+func printReactions(w io.Writer, r *github.Reactions) {
+	counts := reactionCounts(r)
+	var f []string
+	for _, name := range reactionOrder {
+		if n := counts[name]; n > 0 {
+			f = append(f, fmt.Sprintf("%s %d", name, n))
+		}
+	}
+	if len(f) > 0 {
+		fmt.Fprintf(w, "Reactions: %s\n", strings.Join(f, ", "))
+	}
+}
+
+// authorName returns the login of the author of an issue or comment,
+// followed by the author's association with the repository,
+// as in "rsc, member" or "gopher, first-time contributor".
+func authorName(user *github.User, assoc *string) string {
+	a := strings.ToLower(getString(assoc))
+	switch a {
+	case "":
+		return getUserLogin(user)
+	case "first_time_contributor":
+		a = "first-time contributor"
+	case "first_timer":
+		a = "first-time GitHub user"
+	}
+	return getUserLogin(user) + ", " + a
+}
+
+// commentHeader returns the line introducing a comment, giving its
+// author, time, ID, and permalink, as in
+// "Comment by rsc (2015-01-08 05:17:06) [70279049] https://...#issuecomment-70279049".
+func commentHeader(com *github.IssueComment) string {
+	s := fmt.Sprintf("Comment by %s (%s) [%d]", authorName(com.User, com.AuthorAssociation), formatTime(getTime(com.CreatedAt)), com.GetID())
+	if url := com.GetHTMLURL(); url != "" {
+		s += " " + url
+	}
+	return s
+}
+
+// printText prints the text of an issue body or comment: as is with -raw,
+// beside its raw markdown if both is set, and otherwise wrapped and indented.
+func printText(w io.Writer, body *string, both bool) {
+	if body == nil {
+		return
+	}
+	switch text := strings.TrimSpace(*body); {
+	case both:
+		if text != "" {
+			fmt.Fprintf(w, "\n%s", sideBySide(text))
+		}
+	case *rawFlag:
+		fmt.Fprintf(w, "\n%s\n\n", *body)
+	case text != "":
+		fmt.Fprintf(w, "\n\t%s\n", wrap(text, "\t"))
+	}
+}
+
+// participants returns the list of the people taking part in an issue:
+// its reporter, assignees, and commenters, with their comment counts,
+// as in "rsc (5), bcmills (2, assignee), dsymonds (reporter)".
+// Those with the most comments are listed first.
+func participants(issue *github.Issue, comments []*github.IssueComment) string {
+	type person struct {
+		login string
+		count int
+		roles []string
+	}
+	var list []*person
+	byLogin := make(map[string]*person)
+	add := func(login string) *person {
+		p := byLogin[login]
+		if p == nil {
+			p = &person{login: login}
+			byLogin[login] = p
+			list = append(list, p)
+		}
+		return p
+	}
+	if login := getUserLogin(issue.User); login != "" {
+		p := add(login)
+		p.roles = append(p.roles, "reporter")
+	}
+	for _, u := range issue.Assignees {
+		p := add(getUserLogin(u))
+		p.roles = append(p.roles, "assignee")
+	}
+	for _, com := range comments {
+		add(getUserLogin(com.User)).count++
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].count > list[j].count })
+
+	var f []string
+	for _, p := range list {
+		var notes []string
+		if p.count > 0 {
+			notes = append(notes, fmt.Sprint(p.count))
+		}
+		notes = append(notes, p.roles...)
+		f = append(f, fmt.Sprintf("%s (%s)", p.login, strings.Join(notes, ", ")))
+	}
+	return strings.Join(f, ", ")
+}
+
+// printIssueHeader prints the header lines of an issue:
+// its title, state, and other metadata.
+func printIssueHeader(w io.Writer, project string, issue *github.Issue) {
 	fmt.Fprintf(w, "Title: %s\n", getString(issue.Title))
 	fmt.Fprintf(w, "State: %s\n", getString(issue.State))
 	fmt.Fprintf(w, "Assignee: %s\n", getUserLogin(issue.Assignee))
 	if issue.ClosedAt != nil {
-		fmt.Fprintf(w, "Closed: %s\n", getTime(issue.ClosedAt).Format(timeFormat))
+		fmt.Fprintf(w, "Closed: %s\n", formatTime(getTime(issue.ClosedAt)))
 	}
 	fmt.Fprintf(w, "Labels: %s\n", strings.Join(getLabelNames(issue.Labels), " "))
+	if typ := getIssueType(project, issue); typ != "" {
+		fmt.Fprintf(w, "Type: %s\n", typ)
+	}
 	fmt.Fprintf(w, "Milestone: %s\n", getMilestoneTitle(issue.Milestone))
-	fmt.Fprintf(w, "URL: https://github.com/%s/%s/issues/%d\n", projectOwner(project), projectRepo(project), getInt(issue.Number))
+	if tasks := taskSummary(getString(issue.Body)); tasks != "" {
+		fmt.Fprintf(w, "Tasks: %s\n", tasks)
+	}
+	fmt.Fprintf(w, "URL: %s\n", issueURL(project, getInt(issue.Number)))
+	if getString(issue.State) == "open" {
+		printLinkedPulls(w, project, issue)
+		if !issue.IsPullRequest() {
+			printLinkedBranches(w, project, issue)
+		}
+	}
+}
 
-	fmt.Fprintf(w, "\nReported by %s (%s)\n", getUserLogin(issue.User), getTime(issue.CreatedAt).Format(timeFormat))
-	if issue.Body != nil {
-		if *rawFlag {
-			fmt.Fprintf(w, "\n%s\n\n", *issue.Body)
-		} else {
-			text := strings.TrimSpace(*issue.Body)
-			if text != "" {
-				fmt.Fprintf(w, "\n\t%s\n", wrap(text, "\t"))
-			}
+func printIssue(w io.Writer, project string, issue *github.Issue, read bool) error {
+	if *jsonFlag == 2 {
+		showJSON2Issue(w, project, issue)
+		return nil
+	}
+	if *jsonFlag != 0 {
+		if *headerFlag {
+			writeJSON(w, toJSON(project, issue))
+			return nil
 		}
+		showJSONIssue(w, project, issue)
+		return nil
+	}
+	if fieldPaths != nil {
+		showFields(w, project, []*github.Issue{issue}, fieldPaths)
+		return nil
+	}
+	if *orgFlag {
+		showOrg(w, project, []*github.Issue{issue}, true)
+		return nil
+	}
+	if *mboxFlag {
+		return showMbox(w, project, []*github.Issue{issue})
 	}
 
-	var output []string
+	if termLinks || termColor {
+		out := w
+		var buf bytes.Buffer
+		w = &buf
+		defer func() {
+			io.WriteString(out, colorIssue(linkify(project, buf.String()), issue))
+		}()
+	}
+
+	if *headerFlag {
+		printIssueHeader(w, project, issue)
+		return nil
+	}
+
+	// With -since, the activity before that time is left out.
+	since := issueSince(project, getInt(issue.Number))
+	readAt := time.Now()
 
+	var comments []*github.IssueComment
+	progress := newPageProgress(fmt.Sprintf("comments on #%d", getInt(issue.Number)))
+	defer progress.done()
 	for page := 1; ; {
 		list, resp, err := client.Issues.ListComments(context.TODO(), projectOwner(project), projectRepo(project), getInt(issue.Number), &github.IssueListCommentsOptions{
 			ListOptions: github.ListOptions{
@@ -394,23 +1967,8 @@ func printIssue(w io.Writer, project string, issue *github.Issue) error {
 				PerPage: 100,
 			},
 		})
-		for _, com := range list {
-			var buf bytes.Buffer
-			w := &buf
-			fmt.Fprintf(w, "%s\n", getTime(com.CreatedAt).Format(time.RFC3339))
-			fmt.Fprintf(w, "\nComment by %s (%s)\n", getUserLogin(com.User), getTime(com.CreatedAt).Format(timeFormat))
-			if com.Body != nil {
-				if *rawFlag {
-					fmt.Fprintf(w, "\n%s\n\n", *com.Body)
-				} else {
-					text := strings.TrimSpace(*com.Body)
-					if text != "" {
-						fmt.Fprintf(w, "\n\t%s\n", wrap(text, "\t"))
-					}
-				}
-			}
-			output = append(output, buf.String())
-		}
+		comments = append(comments, list...)
+		progress.add(len(list), resp)
 		if err != nil {
 			return err
 		}
@@ -420,86 +1978,162 @@ func printIssue(w io.Writer, project string, issue *github.Issue) error {
 		page = resp.NextPage
 	}
 
-	for page := 1; ; {
-		list, resp, err := client.Issues.ListIssueEvents(context.TODO(), projectOwner(project), projectRepo(project), getInt(issue.Number), &github.ListOptions{
-			Page:    page,
-			PerPage: 100,
-		})
-		for _, ev := range list {
-			var buf bytes.Buffer
-			w := &buf
-			fmt.Fprintf(w, "%s\n", getTime(ev.CreatedAt).Format(time.RFC3339))
-			switch event := getString(ev.Event); event {
-			case "mentioned", "subscribed", "unsubscribed":
-				// ignore
-			default:
-				fmt.Fprintf(w, "\n* %s %s (%s)\n", getUserLogin(ev.Actor), event, getTime(ev.CreatedAt).Format(timeFormat))
-			case "closed", "referenced", "merged":
-				id := getString(ev.CommitID)
-				if id != "" {
-					if len(id) > 7 {
-						id = id[:7]
-					}
-					id = " in commit " + id
-				}
-				fmt.Fprintf(w, "\n* %s %s%s (%s)\n", getUserLogin(ev.Actor), event, id, getTime(ev.CreatedAt).Format(timeFormat))
-				if id != "" {
-					commit, _, err := client.Git.GetCommit(context.TODO(), projectOwner(project), projectRepo(project), *ev.CommitID)
-					if err == nil {
-						fmt.Fprintf(w, "\n\tAuthor: %s <%s> %s\n\tCommitter: %s <%s> %s\n\n\t%s\n",
-							getString(commit.Author.Name), getString(commit.Author.Email), getTime(commit.Author.Date).Format(timeFormat),
-							getString(commit.Committer.Name), getString(commit.Committer.Email), getTime(commit.Committer.Date).Format(timeFormat),
-							wrap(getString(commit.Message), "\t"))
-					}
-				}
-			case "assigned", "unassigned":
-				fmt.Fprintf(w, "\n* %s %s %s (%s)\n", getUserLogin(ev.Actor), event, getUserLogin(ev.Assignee), getTime(ev.CreatedAt).Format(timeFormat))
-			case "labeled", "unlabeled":
-				fmt.Fprintf(w, "\n* %s %s %s (%s)\n", getUserLogin(ev.Actor), event, getString(ev.Label.Name), getTime(ev.CreatedAt).Format(timeFormat))
-			case "milestoned", "demilestoned":
-				if event == "milestoned" {
-					event = "added to milestone"
-				} else {
-					event = "removed from milestone"
-				}
-				fmt.Fprintf(w, "\n* %s %s %s (%s)\n", getUserLogin(ev.Actor), event, getString(ev.Milestone.Title), getTime(ev.CreatedAt).Format(timeFormat))
-			case "renamed":
-				fmt.Fprintf(w, "\n* %s changed title (%s)\n  - %s\n  + %s\n", getUserLogin(ev.Actor), getTime(ev.CreatedAt).Format(timeFormat), getString(ev.Rename.From), getString(ev.Rename.To))
-			}
-			output = append(output, buf.String())
+	printIssueHeader(w, project, issue)
+	printCLs(w, loadCLs(comments))
+	fmt.Fprintf(w, "Participants: %s\n", participants(issue, comments))
+
+	// Each entry in output begins with a line used as its sort key,
+	// which is the time of the comment or event, except that with
+	// -sort-comments reactions the comments sort first,
+	// by their +1 reactions and then all their reactions.
+	var output []string
+
+	// Comments hidden by maintainers are collapsed.
+	// If they cannot be identified, all comments are shown.
+	var hidden map[int64]string
+	if len(comments) > 0 {
+		hidden, _ = loadMinimized(project, getInt(issue.Number))
+	}
+
+	for _, com := range comments {
+		if getTime(com.CreatedAt).Before(since) {
+			continue
 		}
-		if err != nil {
-			return err
+		var buf bytes.Buffer
+		w := &buf
+		if *sortFlag == "reactions" {
+			fmt.Fprintf(w, "0 %08d %08d ", 1e7-com.GetReactions().GetPlusOne(), 1e7-com.GetReactions().GetTotalCount())
 		}
-		if resp.NextPage < page {
-			break
+		fmt.Fprintf(w, "%s\n", getTime(com.CreatedAt).Format(time.RFC3339))
+		if reason, ok := hidden[com.GetID()]; ok {
+			printHiddenComment(w, com, reason)
+		} else if collapseComment(project, getInt(issue.Number), com) {
+			printCollapsedComment(w, com)
+		} else {
+			printComment(w, com)
+		}
+		output = append(output, buf.String())
+	}
+
+	if !*commentFlag {
+		progress = newPageProgress(fmt.Sprintf("events on #%d", getInt(issue.Number)))
+		defer progress.done()
+		for page := 1; ; {
+			list, resp, err := client.Issues.ListIssueEvents(context.TODO(), projectOwner(project), projectRepo(project), getInt(issue.Number), &github.ListOptions{
+				Page:    page,
+				PerPage: 100,
+			})
+			for _, ev := range list {
+				if getTime(ev.CreatedAt).Before(since) {
+					continue
+				}
+				var buf bytes.Buffer
+				w := &buf
+				fmt.Fprintf(w, "%s\n", getTime(ev.CreatedAt).Format(time.RFC3339))
+				printEvent(w, project, ev)
+				output = append(output, buf.String())
+			}
+			progress.add(len(list), resp)
+			if err != nil {
+				return err
+			}
+			if resp.NextPage < page {
+				break
+			}
+			page = resp.NextPage
 		}
-		page = resp.NextPage
 	}
 
 	sort.Strings(output)
+
+	// With -last, only the final comments and events are printed.
+	omitted := 0
+	if *lastFlag > 0 {
+		output, omitted = lastEntries(output, *lastFlag)
+	}
+
+	fmt.Fprintf(w, "\nReported by %s (%s)\n", authorName(issue.User, issue.AuthorAssociation), formatTime(getTime(issue.CreatedAt)))
+	printReactions(w, issue.Reactions)
+	switch {
+	case getTime(issue.CreatedAt).Before(since):
+		earlier := 0
+		for _, com := range comments {
+			if getTime(com.CreatedAt).Before(since) {
+				earlier++
+			}
+		}
+		fmt.Fprintf(w, "\n\t[text and %d earlier comment%s omitted; showing activity since %s]\n", earlier, suffix(earlier), formatTime(since))
+	case omitted == 1:
+		fmt.Fprintf(w, "\n\t[text and 1 earlier comment or event omitted]\n")
+	case omitted > 1:
+		fmt.Fprintf(w, "\n\t[text and %d earlier comments and events omitted]\n", omitted)
+	default:
+		printText(w, issue.Body, *bothFlag)
+	}
+
 	for _, s := range output {
 		i := strings.Index(s, "\n")
 		fmt.Fprintf(w, "%s", s[i+1:])
 	}
 
+	if read {
+		markRead(project, getInt(issue.Number), readAt)
+	}
 	return nil
 }
 
-func showQuery(w io.Writer, project, q string) error {
+// lastEntries returns the last n of the sorted comments and events in output,
+// along with the number omitted before them.
+// Ignored events have nothing after their sort key lines and do not count.
+func lastEntries(output []string, n int) (shown []string, omitted int) {
+	for _, s := range output {
+		if strings.Count(s, "\n") > 1 {
+			shown = append(shown, s)
+		}
+	}
+	if len(shown) > n {
+		omitted = len(shown) - n
+		shown = shown[omitted:]
+	}
+	return shown, omitted
+}
+
+// showQuery prints the issues matching the query q,
+// returning the number of issues printed.
+func showQuery(w io.Writer, project, q string) (int, error) {
 	all, err := searchIssues(project, q)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	sort.Sort(issuesByTitle(all))
-	if *jsonFlag {
-		showJSONList(project, all)
-		return nil
+	if *jsonFlag == 2 {
+		showJSON2List(w, project, all)
+		return len(all), nil
+	}
+	if *jsonFlag != 0 {
+		showJSONList(w, project, all)
+		return len(all), nil
+	}
+	if fieldPaths != nil {
+		showFields(w, project, all, fieldPaths)
+		return len(all), nil
+	}
+	if *orgFlag {
+		showOrg(w, project, all, false)
+		return len(all), nil
+	}
+	if *mboxFlag {
+		return len(all), showMbox(w, project, all)
 	}
 	for _, issue := range all {
-		fmt.Fprintf(w, "%v\t%v\n", getInt(issue.Number), getString(issue.Title))
+		n := getInt(issue.Number)
+		title := getString(issue.Title)
+		if tasks := taskSummary(getString(issue.Body)); tasks != "" {
+			title += " [" + tasks + "]"
+		}
+		fmt.Fprintf(w, "%v\t%v\n", hyperlink(issueURL(project, n), colorize(stateColor(getString(issue.State)), fmt.Sprint(n))), title)
 	}
-	return nil
+	return len(all), nil
 }
 
 type issuesByTitle []*github.Issue
@@ -519,9 +2153,11 @@ func searchIssues(project, q string) ([]*github.Issue, error) {
 	}
 
 	var all []*github.Issue
+	progress := newPageProgress("search results")
+	defer progress.done()
 	for page := 1; ; {
 		// TODO(rsc): Rethink excluding pull requests.
-		x, resp, err := client.Search.Issues(context.TODO(), "type:issue state:open repo:"+project+" "+q, &github.SearchOptions{
+		x, resp, err := client.Search.Issues(context.TODO(), "is:issue state:open repo:"+project+" "+q, &github.SearchOptions{
 			ListOptions: github.ListOptions{
 				Page:    page,
 				PerPage: 100,
@@ -531,6 +2167,7 @@ func searchIssues(project, q string) ([]*github.Issue, error) {
 			updateIssueCache(project, x.Issues[i])
 			all = append(all, x.Issues[i])
 		}
+		progress.add(len(x.Issues), resp)
 		if err != nil {
 			return all, err
 		}
@@ -617,6 +2254,8 @@ func queryToListOptions(project, q string) (opt github.IssueListByRepoOptions, o
 
 func listRepoIssues(project string, opt github.IssueListByRepoOptions) ([]*github.Issue, error) {
 	var all []*github.Issue
+	progress := newPageProgress("issues")
+	defer progress.done()
 	for page := 1; ; {
 		xopt := opt
 		xopt.ListOptions = github.ListOptions{
@@ -628,6 +2267,7 @@ func listRepoIssues(project string, opt github.IssueListByRepoOptions) ([]*githu
 			updateIssueCache(project, issues[i])
 			all = append(all, issues[i])
 		}
+		progress.add(len(issues), resp)
 		if err != nil {
 			return all, err
 		}
@@ -637,7 +2277,7 @@ func listRepoIssues(project string, opt github.IssueListByRepoOptions) ([]*githu
 		page = resp.NextPage
 	}
 
-	// Filter out pull requests, since we cannot say type:issue like in searchIssues.
+	// Filter out pull requests, since we cannot say is:issue like in searchIssues.
 	// TODO(rsc): Rethink excluding pull requests.
 	save := all[:0]
 	for _, issue := range all {
@@ -649,12 +2289,32 @@ func listRepoIssues(project string, opt github.IssueListByRepoOptions) ([]*githu
 }
 
 func loadMilestones(project string) ([]*github.Milestone, error) {
-	// NOTE(rsc): There appears to be no paging possible.
-	all, _, err := client.Issues.ListMilestones(context.TODO(), projectOwner(project), projectRepo(project), &github.MilestoneListOptions{
-		State: "open",
-	})
-	if err != nil {
-		return nil, err
+	return listMilestones(project, "open")
+}
+
+// listMilestones returns the project's milestones in the given state:
+// open, closed, or all.
+func listMilestones(project, state string) ([]*github.Milestone, error) {
+	var all []*github.Milestone
+	progress := newPageProgress("milestones")
+	defer progress.done()
+	for page := 1; ; {
+		list, resp, err := client.Issues.ListMilestones(context.TODO(), projectOwner(project), projectRepo(project), &github.MilestoneListOptions{
+			State: state,
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: 100,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, list...)
+		progress.add(len(list), resp)
+		if resp.NextPage < page {
+			break
+		}
+		page = resp.NextPage
 	}
 	if all == nil {
 		all = []*github.Milestone{}
@@ -665,22 +2325,27 @@ func loadMilestones(project string) ([]*github.Milestone, error) {
 func wrap(t string, prefix string) string {
 	var out strings.Builder
 	t = strings.Replace(t, "\r\n", "\n", -1)
-	max := 70
-	if *acmeFlag {
-		max = 100
-	}
-	doWrap := true
+	max := wrapWidth()
+	doWrap := max > 0
+	trace := false
 	lines := strings.Split(t, "\n")
 	for i, line := range lines {
 		if i > 0 {
 			out.WriteByte('\n')
 			out.WriteString(prefix)
 		}
-		if line == "```" {
+		if _, ok := isFence(line); ok && max > 0 {
 			doWrap = !doWrap
 		}
+		// Leave stack traces and indented code alone.
+		if traceStartRE.MatchString(line) {
+			trace = true
+		} else if trace && line != "" && !isTraceLine(line) {
+			trace = false
+		}
 		s := line
-		if doWrap {
+		// Leave table rows alone too.
+		if doWrap && !trace && !strings.HasPrefix(line, "\t") && !strings.HasPrefix(line, "    ") && !strings.HasPrefix(strings.TrimSpace(line), "|") {
 			for len(s) > max {
 				i := strings.LastIndex(s[:max], " ")
 				if i < 0 {
@@ -703,12 +2368,20 @@ func wrap(t string, prefix string) string {
 
 var client *github.Client
 
-// GitHub personal access token, from https://github.com/settings/applications.
-var authToken string
+// auth holds the GitHub personal access token,
+// from https://github.com/settings/applications.
+var auth = new(tokenSource)
 
 func loadAuth() {
 	var data []byte
 	var err error
+	if *replayFlag != "" {
+		// Replayed responses need no token.
+		if client, err = newClient(nil); err != nil {
+			fatal(err)
+		}
+		return
+	}
 	switch {
 	case lookExec("secret-tool") == nil:
 		// TODO(hank) This host argument should be parameterized.
@@ -727,30 +2400,55 @@ func loadAuth() {
 		if *tokenFile != "" {
 			filename = *tokenFile
 			shortFilename = *tokenFile
+		} else if _, err := os.Stat(filename); os.IsNotExist(err) {
+			// Without a token file, use the token
+			// for the API host in .netrc, if any.
+			token, err := netrcToken(apiHost())
+			if err != nil {
+				log.Printf("reading token: %v", err)
+				exit(exitAuth)
+			}
+			if token != "" {
+				data = []byte(token)
+				break
+			}
 		}
 		data, err = ioutil.ReadFile(filename)
+		if os.IsNotExist(err) && *tokenFile == "" && canRefreshToken() {
+			// Get the first token as though refreshing it.
+			break
+		}
 		if err != nil {
-			log.Fatal("reading token: ", err, "\n\n"+
-				"Please create a personal access token at https://github.com/settings/tokens/new\n"+
-				"and write it to ", shortFilename, " to use this program.\n"+
+			log.Print("reading token: ", err, "\n\n"+
+				"Please create a personal access token at "+webURL()+"settings/tokens/new\n"+
+				"and write it to ", shortFilename, " to use this program,\n"+
+				"or add it to $HOME/.netrc as the password for machine "+apiHost()+".\n"+
 				"The token only needs the repo scope, or private_repo if you want to\n"+
 				"view or edit issues for private repositories.\n"+
 				"The benefit of using a personal access token over using your GitHub\n"+
 				"password directly is that you can limit its use and revoke it at any time.\n\n")
+			exit(exitAuth)
 		}
 		fi, err := os.Stat(filename)
 		if err != nil {
-			log.Fatal(err)
+			fatal(err)
 		}
 		if fi.Mode()&0077 != 0 {
-			log.Fatalf("reading token: %s mode is %#o, want %#o", shortFilename, fi.Mode()&0777, fi.Mode()&0700)
+			log.Printf("reading token: %s mode is %#o, want %#o", shortFilename, fi.Mode()&0777, fi.Mode()&0700)
+			exit(exitAuth)
 		}
 	}
-	authToken = strings.TrimSpace(string(data))
-	t := &oauth2.Transport{
-		Source: &tokenSource{AccessToken: authToken},
+	auth.set(strings.TrimSpace(string(data)))
+	client, err = newClient(&http.Client{Transport: newRefreshTransport(auth)})
+	if err != nil {
+		fatal(err)
+	}
+	if auth.current() == "" {
+		if err := auth.refresh(""); err != nil {
+			log.Printf("reading token: %v", err)
+			exit(exitAuth)
+		}
 	}
-	client = github.NewClient(&http.Client{Transport: t})
 }
 
 func lookExec(n string) (err error) {
@@ -758,12 +2456,6 @@ func lookExec(n string) (err error) {
 	return err
 }
 
-type tokenSource oauth2.Token
-
-func (t *tokenSource) Token() (*oauth2.Token, error) {
-	return (*oauth2.Token)(t), nil
-}
-
 func getInt(x *int) int {
 	if x == nil {
 		return 0
@@ -874,35 +2566,117 @@ type Issue struct {
 	Reporter  string
 	Created   time.Time
 	Text      string
+	Tasks     []*Task
 	Comments  []*Comment
 }
 
+type Task struct {
+	Done bool
+	Text string
+
+	line int // line number in the issue body
+}
+
 type Comment struct {
 	Author string
 	Time   time.Time
 	Text   string
 }
 
+// showJSONIssue writes the JSON for issue and its comments.
+// Issues can have thousands of comments, so rather than build the
+// whole Issue in memory, it writes the header fields and then each page
+// of comments as it arrives. The output is the same as json.MarshalIndent
+// would produce for toJSONWithComments(project, issue).
+// Nothing is written unless the first page of comments can be read,
+// and a later error still ends the output with valid JSON.
 func showJSONIssue(w io.Writer, project string, issue *github.Issue) {
-	data, err := json.MarshalIndent(toJSONWithComments(project, issue), "", "\t")
-	if err != nil {
-		log.Fatal(err)
+	j := toJSON(project, issue)
+	header := []struct {
+		name  string
+		value interface{}
+	}{
+		{"Number", j.Number},
+		{"Ref", j.Ref},
+		{"Title", j.Title},
+		{"State", j.State},
+		{"Assignee", j.Assignee},
+		{"Closed", j.Closed},
+		{"Labels", j.Labels},
+		{"Milestone", j.Milestone},
+		{"URL", j.URL},
+		{"Reporter", j.Reporter},
+		{"Created", j.Created},
+		{"Text", j.Text},
+		{"Tasks", j.Tasks},
 	}
-	data = append(data, '\n')
-	w.Write(data)
+	n := 0
+	end := func() {
+		if n > 0 {
+			w.Write([]byte("\n\t"))
+		}
+		w.Write([]byte("]\n}\n"))
+	}
+	for page := 1; ; {
+		list, resp, err := client.Issues.ListComments(context.TODO(), projectOwner(project), projectRepo(project), getInt(issue.Number), &github.IssueListCommentsOptions{
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: 100,
+			},
+		})
+		if err != nil {
+			if page > 1 {
+				end()
+			}
+			fatal(err)
+		}
+		if page == 1 {
+			w.Write([]byte("{\n"))
+			for _, f := range header {
+				data, err := json.MarshalIndent(f.value, "\t", "\t")
+				if err != nil {
+					fatal(err)
+				}
+				fmt.Fprintf(w, "\t%q: %s,\n", f.name, data)
+			}
+			w.Write([]byte("\t\"Comments\": ["))
+		}
+		for _, com := range list {
+			data, err := json.MarshalIndent(&Comment{
+				Author: getUserLogin(com.User),
+				Time:   getTime(com.CreatedAt),
+				Text:   getString(com.Body),
+			}, "\t\t", "\t")
+			if err != nil {
+				end()
+				fatal(err)
+			}
+			if n > 0 {
+				w.Write([]byte(","))
+			}
+			w.Write([]byte("\n\t\t"))
+			w.Write(data)
+			n++
+		}
+		if resp.NextPage < page {
+			break
+		}
+		page = resp.NextPage
+	}
+	end()
 }
 
-func showJSONList(project string, all []*github.Issue) {
+func showJSONList(w io.Writer, project string, all []*github.Issue) {
 	j := []*Issue{} // non-nil for json
 	for _, issue := range all {
 		j = append(j, toJSON(project, issue))
 	}
 	data, err := json.MarshalIndent(j, "", "\t")
 	if err != nil {
-		log.Fatal(err)
+		fatal(err)
 	}
 	data = append(data, '\n')
-	os.Stdout.Write(data)
+	w.Write(data)
 }
 
 func toJSON(project string, issue *github.Issue) *Issue {
@@ -915,19 +2689,23 @@ func toJSON(project string, issue *github.Issue) *Issue {
 		Closed:    getTime(issue.ClosedAt),
 		Labels:    getLabelNames(issue.Labels),
 		Milestone: getMilestoneTitle(issue.Milestone),
-		URL:       fmt.Sprintf("https://github.com/%s/%s/issues/%d\n", projectOwner(project), projectRepo(project), getInt(issue.Number)),
+		URL:       issueURL(project, getInt(issue.Number)) + "\n",
 		Reporter:  getUserLogin(issue.User),
 		Created:   getTime(issue.CreatedAt),
 		Text:      getString(issue.Body),
+		Tasks:     parseTasks(getString(issue.Body)),
 		Comments:  []*Comment{},
 	}
 	if j.Labels == nil {
 		j.Labels = []string{}
 	}
+	if j.Tasks == nil {
+		j.Tasks = []*Task{}
+	}
 	return j
 }
 
-func toJSONWithComments(project string, issue *github.Issue) *Issue {
+func toJSONWithComments(project string, issue *github.Issue) (*Issue, error) {
 	j := toJSON(project, issue)
 	for page := 1; ; {
 		list, resp, err := client.Issues.ListComments(context.TODO(), projectOwner(project), projectRepo(project), getInt(issue.Number), &github.IssueListCommentsOptions{
@@ -937,7 +2715,7 @@ func toJSONWithComments(project string, issue *github.Issue) *Issue {
 			},
 		})
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		for _, com := range list {
 			j.Comments = append(j.Comments, &Comment{
@@ -951,7 +2729,7 @@ func toJSONWithComments(project string, issue *github.Issue) *Issue {
 		}
 		page = resp.NextPage
 	}
-	return j
+	return j, nil
 }
 
 func newLogger(t http.RoundTripper) http.RoundTripper {
@@ -0,0 +1,150 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// Sorted comments and events as printIssue collects them:
+// a sort key line, then the printed text, if any.
+var (
+	comment1 = "2022-01-01T00:00:00Z\n\nComment 1\n"
+	ignored  = "2022-01-02T00:00:00Z\n"
+	event1   = "2022-01-03T00:00:00Z\n* rsc closed\n"
+	comment2 = "2022-01-04T00:00:00Z\n\nComment 2\n"
+)
+
+var lastEntriesTests = []struct {
+	output  []string
+	n       int
+	shown   []string
+	omitted int
+}{
+	{nil, 1, nil, 0},
+	{[]string{comment1}, 1, []string{comment1}, 0},
+	{[]string{comment1}, 5, []string{comment1}, 0},
+	{[]string{comment1, event1, comment2}, 2, []string{event1, comment2}, 1},
+	{[]string{comment1, event1, comment2}, 1, []string{comment2}, 2},
+	{[]string{comment1, event1, comment2}, 3, []string{comment1, event1, comment2}, 0},
+	{[]string{comment1, ignored, event1, comment2}, 2, []string{event1, comment2}, 1},
+	{[]string{comment1, event1, ignored}, 1, []string{event1}, 1},
+	{[]string{ignored, ignored}, 1, nil, 0},
+}
+
+func TestLastEntries(t *testing.T) {
+	for _, tt := range lastEntriesTests {
+		shown, omitted := lastEntries(tt.output, tt.n)
+		if !reflect.DeepEqual(shown, tt.shown) || omitted != tt.omitted {
+			t.Errorf("lastEntries(%q, %d) = %q, %d, want %q, %d", tt.output, tt.n, shown, omitted, tt.shown, tt.omitted)
+		}
+	}
+}
+
+// commentsAPI starts a server standing in for the GitHub API, which lists
+// the comments of an issue in pages, one per element of pages, and fails
+// the request for page fail, if it is set.
+// It points client at the server until the test ends.
+func commentsAPI(t *testing.T, pages []int, fail int) string {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+		if page == fail || page > len(pages) {
+			http.Error(w, "server error", http.StatusInternalServerError)
+			return
+		}
+		if page < len(pages) {
+			w.Header().Set("Link", fmt.Sprintf(`<%s%s?page=%d>; rel="next"`, "http://"+r.Host, r.URL.Path, page+1))
+		}
+		var list []*github.IssueComment
+		for i := 0; i < pages[page-1]; i++ {
+			list = append(list, &github.IssueComment{
+				User: &github.User{Login: github.String("gopher")},
+				Body: github.String(fmt.Sprintf("comment %d.%d <&>", page, i)),
+			})
+		}
+		json.NewEncoder(w).Encode(list)
+	}))
+	useServer(t, srv)
+	return srv.URL
+}
+
+var jsonIssue = &github.Issue{
+	Number: github.Int(7),
+	Title:  github.String("x: something is broken"),
+	State:  github.String("open"),
+	User:   &github.User{Login: github.String("rsc")},
+	Labels: []*github.Label{{Name: github.String("NeedsFix")}},
+	Body:   github.String("- [ ] fix it\n- [x] find it\n"),
+}
+
+var showJSONIssueTests = []struct {
+	name  string
+	pages []int // comments on each page
+}{
+	{"no comments", []int{0}},
+	{"one page", []int{2}},
+	{"two pages", []int{100, 3}},
+}
+
+func TestShowJSONIssue(t *testing.T) {
+	writeConfig(t, "-")
+	for _, tt := range showJSONIssueTests {
+		t.Run(tt.name, func(t *testing.T) {
+			commentsAPI(t, tt.pages, 0)
+			var buf bytes.Buffer
+			showJSONIssue(&buf, "golang/go", jsonIssue)
+			j, err := toJSONWithComments("golang/go", jsonIssue)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, _ := json.MarshalIndent(j, "", "\t")
+			if got := buf.String(); got != string(want)+"\n" {
+				t.Errorf("showJSONIssue:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+// TestShowJSONIssueError checks that an error listing the comments
+// leaves either no output or valid JSON, in a child process.
+func TestShowJSONIssueError(t *testing.T) {
+	if u := os.Getenv("ISSUE_TEST_JSONISSUE"); u != "" {
+		client = github.NewClient(nil)
+		client.BaseURL, _ = url.Parse(u + "/")
+		showJSONIssue(os.Stdout, "golang/go", jsonIssue)
+		return
+	}
+	writeConfig(t, "-")
+	for _, fail := range []int{1, 2, 3} {
+		u := commentsAPI(t, []int{100, 100, 100}, fail)
+		cmd := exec.Command(os.Args[0], "-test.run=^TestShowJSONIssueError$")
+		cmd.Env = append(os.Environ(), "ISSUE_TEST_JSONISSUE="+u)
+		out, err := cmd.Output()
+		if e, ok := err.(*exec.ExitError); !ok || e.ExitCode() != exitError {
+			t.Fatalf("failing page %d: %v, want exit status %d", fail, err, exitError)
+		}
+		if fail == 1 && len(out) != 0 {
+			t.Errorf("failing page 1: wrote %q, want nothing", out)
+		}
+		if fail > 1 && !json.Valid(out) {
+			t.Errorf("failing page %d: wrote invalid JSON:\n%s", fail, out)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// A bulk edit writes a journal before changing any issues, so that
+// if it is interrupted, "issue resume" can finish the job.
+// The journal is a file of JSON lines: the first holds the edit,
+// and each following line records an issue updated successfully
+// or the steps of an issue's update done so far, so that resuming
+// neither repeats them nor mistakes them for changes made by others.
+// The journal is removed once every issue has been updated.
+
+// A bulkJournal is the journal of a bulk edit.
+type bulkJournal struct {
+	Project string
+	Base    *github.Issue     // metadata header the edit was made from
+	Text    []byte            // bulk edit text
+	IDs     []int             // issues to update
+	Shown   map[int]time.Time // update times of issues when the edit began
+
+	file  string
+	mu    sync.Mutex
+	f     *os.File
+	done  map[int]bool
+	steps map[int]*issueSteps
+}
+
+// A journalEntry is a line in a journal after the first.
+type journalEntry struct {
+	Done  int         `json:",omitempty"` // issue updated
+	Issue int         `json:",omitempty"` // issue whose update has begun
+	Steps *issueSteps `json:",omitempty"` // steps of the update of Issue done
+}
+
+// journalDir returns the directory holding bulk edit journals.
+func journalDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "issue", "journal"), nil
+}
+
+// createJournal creates a new journal for a bulk edit.
+func createJournal(project string, base *github.Issue, text []byte, ids []int, shown map[int]time.Time) (*bulkJournal, error) {
+	dir, err := journalDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	f, err := ioutil.TempFile(dir, "bulk-*.journal")
+	if err != nil {
+		return nil, err
+	}
+	j := &bulkJournal{Project: project, Base: base, Text: text, IDs: ids, Shown: shown, file: f.Name(), f: f, done: make(map[int]bool), steps: make(map[int]*issueSteps)}
+	data, err := json.Marshal(j)
+	if err == nil {
+		_, err = f.Write(append(data, '\n'))
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(j.file)
+		return nil, err
+	}
+	return j, nil
+}
+
+// openJournal opens an existing journal to continue its bulk edit.
+func openJournal(file string) (*bulkJournal, error) {
+	f, err := os.OpenFile(file, os.O_RDWR|os.O_APPEND, 0)
+	if err != nil {
+		return nil, err
+	}
+	j := &bulkJournal{file: file, f: f, done: make(map[int]bool), steps: make(map[int]*issueSteps)}
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1<<24)
+	for line := 1; s.Scan(); line++ {
+		if line == 1 {
+			err = json.Unmarshal(s.Bytes(), j)
+		} else {
+			var e journalEntry
+			// A crash can leave the last line incomplete;
+			// the issue it was recording is simply updated again.
+			if json.Unmarshal(s.Bytes(), &e) == nil {
+				if e.Done != 0 {
+					j.done[e.Done] = true
+				}
+				if e.Issue != 0 && e.Steps != nil {
+					j.steps[e.Issue] = e.Steps
+				}
+			}
+		}
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("%s:%d: %v", file, line, err)
+		}
+	}
+	if err := s.Err(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if j.Project == "" || len(j.IDs) == 0 {
+		f.Close()
+		return nil, fmt.Errorf("%s: not a bulk edit journal", file)
+	}
+	return j, nil
+}
+
+// remaining returns the issues not yet updated.
+func (j *bulkJournal) remaining() []int {
+	var ids []int
+	for _, id := range j.IDs {
+		if !j.done[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// record notes in the journal that issue n has been updated.
+// A nil journal records nothing.
+func (j *bulkJournal) record(n int) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.done[n] = true
+	j.write(journalEntry{Done: n})
+}
+
+// progress returns the steps of the update of issue n done by an
+// earlier run of the edit, or nil if the update was never begun.
+func (j *bulkJournal) progress(n int) *issueSteps {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if s := j.steps[n]; s != nil {
+		c := *s
+		return &c
+	}
+	return nil
+}
+
+// step notes in the journal the steps of the update of issue n
+// done so far, which may be none, when the update begins.
+// A nil journal records nothing.
+func (j *bulkJournal) step(n int, s *issueSteps) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	c := *s
+	j.steps[n] = &c
+	j.write(journalEntry{Issue: n, Steps: &c})
+}
+
+// write appends the entry e to the journal.
+func (j *bulkJournal) write(e journalEntry) {
+	data, _ := json.Marshal(e)
+	j.f.Write(append(data, '\n'))
+}
+
+// finish closes the journal, removing it if the edit is complete.
+// It reports whether the journal was removed.
+func (j *bulkJournal) finish() bool {
+	if j == nil {
+		return true
+	}
+	j.f.Close()
+	if len(j.remaining()) > 0 {
+		return false
+	}
+	os.Remove(j.file)
+	return true
+}
+
+// discard closes and removes the journal,
+// as when an atomic edit has been rolled back.
+func (j *bulkJournal) discard() {
+	if j == nil {
+		return
+	}
+	j.f.Close()
+	os.Remove(j.file)
+}
+
+func runResume(project string, args []string) {
+	fs := lookupCommand("resume").flags()
+	parseFlags(fs, args)
+	if fs.NArg() > 1 {
+		fs.Usage()
+	}
+
+	if fs.NArg() == 0 {
+		// List the journals of interrupted bulk edits.
+		dir, err := journalDir()
+		if err != nil {
+			fatal(err)
+		}
+		files, _ := filepath.Glob(filepath.Join(dir, "bulk-*.journal"))
+		if len(files) == 0 {
+			fmt.Printf("no interrupted bulk edits\n")
+			exit(exitNoMatch)
+		}
+		for _, file := range files {
+			j, err := openJournal(file)
+			if err != nil {
+				log.Print(err)
+				continue
+			}
+			j.f.Close()
+			left := len(j.remaining())
+			fmt.Printf("%s\t%s\t%d of %d issue%s left\n", file, j.Project, left, len(j.IDs), suffix(len(j.IDs)))
+		}
+		return
+	}
+
+	j, err := openJournal(fs.Arg(0))
+	if err != nil {
+		fatal(err)
+	}
+	ids := j.remaining()
+	if len(ids) == 0 {
+		j.finish()
+		log.Printf("bulk edit already complete")
+		return
+	}
+	log.Printf("resuming bulk edit of %s: %d of %d issue%s left", j.Project, len(ids), len(j.IDs), suffix(len(j.IDs)))
+	status, progress, finish := bulkProgress()
+	results, err := applyBulkEdit(j.Project, j.Base, j.Text, ids, j.Shown, j, status, progress)
+	finish()
+	printBulkResults(os.Stdout, results)
+	if err != nil {
+		fatal(err)
+	}
+	log.Printf("updated %d issue%s", len(ids), suffix(len(ids)))
+}
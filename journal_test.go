@@ -0,0 +1,176 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+)
+
+var journalTests = []struct {
+	name      string
+	record    []int               // issues recorded as updated
+	steps     map[int]*issueSteps // steps recorded, in issue order
+	partial   string              // incomplete last line, as after a crash
+	remaining []int
+	progress  map[int]*issueSteps // steps expected after reopening
+	finished  bool                // whether finish removes the journal
+}{
+	{
+		name:      "new",
+		remaining: []int{1, 2, 3},
+	},
+	{
+		name:      "some done",
+		record:    []int{1, 3},
+		remaining: []int{2},
+	},
+	{
+		name:     "all done",
+		record:   []int{3, 2, 1},
+		finished: true,
+	},
+	{
+		name:      "steps",
+		record:    []int{1},
+		steps:     map[int]*issueSteps{2: {Edited: true}, 3: {}},
+		remaining: []int{2, 3},
+		progress:  map[int]*issueSteps{2: {Edited: true}, 3: {}},
+	},
+	{
+		name:      "all steps",
+		steps:     map[int]*issueSteps{2: {Edited: true, Labels: true, Comment: 42}},
+		remaining: []int{1, 2, 3},
+		progress:  map[int]*issueSteps{2: {Edited: true, Labels: true, Comment: 42}},
+	},
+	{
+		name:      "crash",
+		record:    []int{2},
+		partial:   `{"Done":`,
+		remaining: []int{1, 3},
+	},
+	{
+		name:      "crash during steps",
+		steps:     map[int]*issueSteps{1: {Edited: true}},
+		partial:   `{"Issue":1,"Steps":{"Edited":true,"Lab`,
+		remaining: []int{1, 2, 3},
+		progress:  map[int]*issueSteps{1: {Edited: true}},
+	},
+}
+
+func TestJournal(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	base := &github.Issue{Milestone: &github.Milestone{Title: github.String("Go1.20")}}
+	shown := map[int]time.Time{1: time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)}
+	for _, tt := range journalTests {
+		t.Run(tt.name, func(t *testing.T) {
+			j, err := createJournal("golang/go", base, []byte("Milestone: Go1.20\n"), []int{1, 2, 3}, shown)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, n := range tt.record {
+				j.record(n)
+			}
+			for _, n := range []int{1, 2, 3} {
+				if s := tt.steps[n]; s != nil {
+					j.step(n, s)
+				}
+			}
+			if tt.partial != "" {
+				j.f.Write([]byte(tt.partial))
+			}
+			j.f.Close()
+
+			j, err = openJournal(j.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if j.Project != "golang/go" || string(j.Text) != "Milestone: Go1.20\n" || getMilestoneTitle(j.Base.Milestone) != "Go1.20" || !j.Shown[1].Equal(shown[1]) {
+				t.Errorf("reopened journal = %+v", j)
+			}
+			if got := j.remaining(); !reflect.DeepEqual(got, tt.remaining) {
+				t.Errorf("remaining() = %v, want %v", got, tt.remaining)
+			}
+			for _, n := range []int{1, 2, 3} {
+				got, want := j.progress(n), tt.progress[n]
+				if (got == nil) != (want == nil) || got != nil && (got.Edited != want.Edited || got.Labels != want.Labels || got.Comment != want.Comment) {
+					t.Errorf("progress(%d) = %+v, want %+v", n, got, want)
+				}
+			}
+			if removed := j.finish(); removed != tt.finished {
+				t.Errorf("finish() = %v, want %v", removed, tt.finished)
+			}
+			if _, err := os.Stat(j.file); os.IsNotExist(err) != tt.finished {
+				t.Errorf("after finish, Stat = %v, want removed %v", err, tt.finished)
+			}
+			if !tt.finished {
+				os.Remove(j.file)
+			}
+		})
+	}
+}
+
+func TestJournalProgressCopy(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	j, err := createJournal("golang/go", &github.Issue{}, nil, []int{1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.discard()
+	s := &issueSteps{Edited: true}
+	j.step(1, s)
+	s.Labels = true
+	p := j.progress(1)
+	if p.Labels {
+		t.Errorf("step kept a reference to the caller's steps")
+	}
+	p.Comment = 1
+	if j.progress(1).Comment != 0 {
+		t.Errorf("progress returned the journal's own steps")
+	}
+	var nilJournal *bulkJournal
+	nilJournal.record(1)
+	nilJournal.step(1, s)
+	if nilJournal.progress(1) != nil || !nilJournal.finish() {
+		t.Errorf("nil journal recorded progress")
+	}
+}
+
+var openJournalErrorTests = []struct {
+	name string
+	text string
+	err  string
+}{
+	{"empty", "", "not a bulk edit journal"},
+	{"no issues", `{"Project":"golang/go"}` + "\n", "not a bulk edit journal"},
+	{"no project", `{"IDs":[1]}` + "\n", "not a bulk edit journal"},
+	{"bad header", "{\n", ":1: "},
+}
+
+func TestOpenJournalErrors(t *testing.T) {
+	dir := t.TempDir()
+	for _, tt := range openJournalErrorTests {
+		file := filepath.Join(dir, "bulk-test.journal")
+		if err := ioutil.WriteFile(file, []byte(tt.text), 0600); err != nil {
+			t.Fatal(err)
+		}
+		j, err := openJournal(file)
+		if err == nil {
+			j.f.Close()
+			t.Errorf("%s: openJournal succeeded", tt.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: openJournal: %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
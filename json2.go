@@ -0,0 +1,391 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// A jsonVersion is the value of the -json flag:
+// 0 for text output, or the version of the JSON schema to write.
+// A plain -json selects version 1.
+type jsonVersion int
+
+func jsonVersionFlag(name, usage string) *jsonVersion {
+	v := new(jsonVersion)
+	flag.Var(v, name, usage)
+	return v
+}
+
+func (v *jsonVersion) String() string {
+	if v == nil || *v == 0 {
+		return "false"
+	}
+	return strconv.Itoa(int(*v))
+}
+
+func (v *jsonVersion) IsBoolFlag() bool { return true }
+
+func (v *jsonVersion) Set(s string) error {
+	switch s {
+	case "false", "0":
+		*v = 0
+	case "true", "1":
+		*v = 1
+	case "2":
+		*v = 2
+	default:
+		return fmt.Errorf("unknown JSON version %q: want 1 or 2", s)
+	}
+	return nil
+}
+
+// JSON output, version 2.
+// Version 1 (Issue and Comment) must stay unchanged for existing consumers;
+// new fields go here.
+// If you make changes to the structs, copy them back into the doc comment.
+
+type Issue2 struct {
+	Number        int
+	ID            int64
+	NodeID        string
+	Ref           string
+	Title         string
+	State         string
+	StateReason   string
+	Assignee      string
+	Assignees     []string
+	Closed        time.Time
+	ClosedBy      string
+	Labels        []string
+	Milestone     string
+	URL           string
+	Reporter      string
+	Created       time.Time
+	Updated       time.Time
+	Locked        bool
+	IsPullRequest bool
+	Merged        bool
+	MergeCommit   string
+	Reactions     map[string]int
+	Text          string
+	Tasks         []*Task
+	PullRequests  []*PullRequest
+	CLs           []*CL
+	Comments      []*Comment2
+	Events        []*Event
+}
+
+type Comment2 struct {
+	ID        int64
+	NodeID    string
+	Author    string
+	Time      time.Time
+	Updated   time.Time
+	Reactions map[string]int
+	Text      string
+}
+
+type PullRequest struct {
+	Number int
+	Title  string
+	State  string
+	Review string
+	Checks string
+}
+
+type CL struct {
+	Number  int
+	URL     string
+	Status  string
+	Subject string
+}
+
+type Event struct {
+	Actor     string
+	Event     string
+	Time      time.Time
+	Assignee  string `json:",omitempty"`
+	Label     string `json:",omitempty"`
+	Milestone string `json:",omitempty"`
+	CommitID  string `json:",omitempty"`
+	From      string `json:",omitempty"`
+	To        string `json:",omitempty"`
+}
+
+func reactionCounts(r *github.Reactions) map[string]int {
+	m := make(map[string]int)
+	if r == nil {
+		return m
+	}
+	for name, n := range map[string]*int{
+		"+1":       r.PlusOne,
+		"-1":       r.MinusOne,
+		"laugh":    r.Laugh,
+		"confused": r.Confused,
+		"heart":    r.Heart,
+		"hooray":   r.Hooray,
+		"rocket":   r.Rocket,
+		"eyes":     r.Eyes,
+	} {
+		if getInt(n) > 0 {
+			m[name] = getInt(n)
+		}
+	}
+	return m
+}
+
+// toJSON2 converts issue to the version 2 schema, without the
+// comments, events, pull requests, state reason, and merge status,
+// which require more API calls. See loadClosedStatus.
+func toJSON2(project string, issue *github.Issue) (*Issue2, error) {
+	j1 := toJSON(project, issue)
+	j := &Issue2{
+		Number:       j1.Number,
+		ID:           issue.GetID(),
+		NodeID:       issue.GetNodeID(),
+		Ref:          j1.Ref,
+		Title:        j1.Title,
+		State:        j1.State,
+		Assignee:     j1.Assignee,
+		Assignees:    []string{},
+		Closed:       j1.Closed,
+		ClosedBy:     getUserLogin(issue.ClosedBy),
+		Labels:       j1.Labels,
+		Milestone:    j1.Milestone,
+		URL:          j1.URL,
+		Reporter:     j1.Reporter,
+		Created:      j1.Created,
+		Updated:      getTime(issue.UpdatedAt),
+		Locked:       issue.GetLocked(),
+		Reactions:    reactionCounts(issue.Reactions),
+		Text:         j1.Text,
+		Tasks:        j1.Tasks,
+		PullRequests: []*PullRequest{},
+		CLs:          []*CL{},
+		Comments:     []*Comment2{},
+		Events:       []*Event{},
+	}
+	j.IsPullRequest = issue.IsPullRequest()
+	for _, u := range issue.Assignees {
+		j.Assignees = append(j.Assignees, getUserLogin(u))
+	}
+	return j, nil
+}
+
+// loadClosedStatus sets the StateReason of the closed issues in list
+// and the Merged and MergeCommit fields of the closed pull requests,
+// which the issue listings do not include. It asks for up to 100
+// at a time in a single GraphQL query, instead of loading each one.
+func loadClosedStatus(project string, list []*Issue2) error {
+	var closed []*Issue2
+	for _, j := range list {
+		if j.State == "closed" {
+			closed = append(closed, j)
+		}
+	}
+	for len(closed) > 0 {
+		batch := closed
+		if len(batch) > 100 {
+			batch = batch[:100]
+		}
+		closed = closed[len(batch):]
+
+		var q strings.Builder
+		q.WriteString("query($owner: String!, $repo: String!) {\n  repository(owner: $owner, name: $repo) {\n")
+		for _, j := range batch {
+			fmt.Fprintf(&q, "    n%d: issueOrPullRequest(number: %d) {\n", j.Number, j.Number)
+			q.WriteString("      ... on Issue { stateReason }\n")
+			q.WriteString("      ... on PullRequest { merged mergeCommit { oid } }\n")
+			q.WriteString("    }\n")
+		}
+		q.WriteString("  }\n}")
+		var data struct {
+			Repository map[string]*struct {
+				StateReason string
+				Merged      bool
+				MergeCommit *struct{ Oid string }
+			}
+		}
+		vars := map[string]interface{}{
+			"owner": projectOwner(project),
+			"repo":  projectRepo(project),
+		}
+		if err := graphQL(q.String(), vars, &data); err != nil {
+			return err
+		}
+		for _, j := range batch {
+			x := data.Repository[fmt.Sprintf("n%d", j.Number)]
+			if x == nil {
+				continue
+			}
+			// GraphQL spells the REST API's "not_planned" as NOT_PLANNED.
+			j.StateReason = strings.ToLower(x.StateReason)
+			j.Merged = x.Merged
+			if x.MergeCommit != nil {
+				j.MergeCommit = x.MergeCommit.Oid
+			}
+		}
+	}
+	return nil
+}
+
+// toJSON2List converts the issues in all to the version 2 schema,
+// as toJSON2 does, adding the status of the closed ones.
+func toJSON2List(project string, all []*github.Issue) ([]*Issue2, error) {
+	list := []*Issue2{} // non-nil for json
+	for _, issue := range all {
+		j, err := toJSON2(project, issue)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, j)
+	}
+	if err := loadClosedStatus(project, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// toJSON2WithHistory converts issue to the version 2 schema,
+// including its comments, events, and linked pull requests.
+func toJSON2WithHistory(project string, issue *github.Issue) (*Issue2, error) {
+	j, err := toJSON2(project, issue)
+	if err != nil {
+		return nil, err
+	}
+	if err := loadClosedStatus(project, []*Issue2{j}); err != nil {
+		return nil, err
+	}
+	owner, repo, n := projectOwner(project), projectRepo(project), getInt(issue.Number)
+	var comments []*github.IssueComment
+	for page := 1; ; {
+		list, resp, err := client.Issues.ListComments(context.TODO(), owner, repo, n, &github.IssueListCommentsOptions{
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: 100,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, list...)
+		for _, com := range list {
+			j.Comments = append(j.Comments, &Comment2{
+				ID:        com.GetID(),
+				NodeID:    com.GetNodeID(),
+				Author:    getUserLogin(com.User),
+				Time:      getTime(com.CreatedAt),
+				Updated:   getTime(com.UpdatedAt),
+				Reactions: reactionCounts(com.Reactions),
+				Text:      getString(com.Body),
+			})
+		}
+		if resp.NextPage < page {
+			break
+		}
+		page = resp.NextPage
+	}
+	for page := 1; ; {
+		list, resp, err := client.Issues.ListIssueEvents(context.TODO(), owner, repo, n, &github.ListOptions{
+			Page:    page,
+			PerPage: 100,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, ev := range list {
+			e := &Event{
+				Actor:     getUserLogin(ev.Actor),
+				Event:     getString(ev.Event),
+				Time:      getTime(ev.CreatedAt),
+				Assignee:  getUserLogin(ev.Assignee),
+				Milestone: getMilestoneTitle(ev.Milestone),
+				CommitID:  getString(ev.CommitID),
+			}
+			if ev.Label != nil {
+				e.Label = getString(ev.Label.Name)
+			}
+			if ev.Rename != nil {
+				e.From, e.To = getString(ev.Rename.From), getString(ev.Rename.To)
+			}
+			j.Events = append(j.Events, e)
+		}
+		if resp.NextPage < page {
+			break
+		}
+		page = resp.NextPage
+	}
+	for _, cl := range loadCLs(comments) {
+		j.CLs = append(j.CLs, &CL{
+			Number:  cl.Number,
+			URL:     cl.URL,
+			Status:  cl.Status,
+			Subject: cl.Subject,
+		})
+	}
+	pulls, err := findLinkedPulls(project, n)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range pulls {
+		j.PullRequests = append(j.PullRequests, &PullRequest{
+			Number: p.Number,
+			Title:  p.Title,
+			State:  p.State,
+			Review: p.Review,
+			Checks: p.Checks,
+		})
+	}
+	return j, nil
+}
+
+func writeJSON(w io.Writer, v interface{}) {
+	data, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		fatal(err)
+	}
+	data = append(data, '\n')
+	w.Write(data)
+}
+
+// showJSON2Issue writes the version 2 JSON for issue.
+// With -header, it omits the comments, events, and pull requests,
+// and with -comments, the events.
+func showJSON2Issue(w io.Writer, project string, issue *github.Issue) {
+	var j *Issue2
+	var err error
+	if *headerFlag {
+		if j, err = toJSON2(project, issue); err == nil {
+			err = loadClosedStatus(project, []*Issue2{j})
+		}
+	} else {
+		j, err = toJSON2WithHistory(project, issue)
+	}
+	if err != nil {
+		fatal(err)
+	}
+	if *commentFlag {
+		j.Events = []*Event{}
+	}
+	writeJSON(w, j)
+}
+
+func showJSON2List(w io.Writer, project string, all []*github.Issue) {
+	list, err := toJSON2List(project, all)
+	if err != nil {
+		fatal(err)
+	}
+	writeJSON(w, list)
+}
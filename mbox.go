@@ -0,0 +1,193 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// The -mbox flag writes issues as mail messages in mbox format.
+// The Message-IDs are those GitHub uses in its notification mail,
+// <owner/repo/issues/N@github.com> for an issue and
+// <owner/repo/issues/N/ID@github.com> for a comment, so that
+// the sendmail command can post replies to either as comments.
+
+// showMbox writes the issues and their comments to w in mboxrd format.
+func showMbox(w io.Writer, project string, issues []*github.Issue) error {
+	for _, issue := range issues {
+		n := getInt(issue.Number)
+		id := fmt.Sprintf("<%s/issues/%d@github.com>", project, n)
+		subject := fmt.Sprintf("[%s] %s (#%d)", project, getString(issue.Title), n)
+		writeMessage(w, project, issue.User, getTime(issue.CreatedAt), subject, id, "", getString(issue.Body), issue)
+
+		for page := 1; ; {
+			list, resp, err := client.Issues.ListComments(context.TODO(), projectOwner(project), projectRepo(project), n, &github.IssueListCommentsOptions{
+				ListOptions: github.ListOptions{
+					Page:    page,
+					PerPage: 100,
+				},
+			})
+			if err != nil {
+				return err
+			}
+			for _, com := range list {
+				cid := fmt.Sprintf("<%s/issues/%d/%d@github.com>", project, n, com.GetID())
+				writeMessage(w, project, com.User, getTime(com.CreatedAt), "Re: "+subject, cid, id, getString(com.Body), nil)
+			}
+			if resp.NextPage < page {
+				break
+			}
+			page = resp.NextPage
+		}
+	}
+	return nil
+}
+
+// mboxFromRE matches the body lines that mboxrd quotes with >.
+var mboxFromRE = regexp.MustCompile(`(?m)^(>*From )`)
+
+// writeMessage writes a single mail message to w, preceded by its mbox
+// From_ line. If parent is not empty, the message is a reply to it.
+// If issue is not nil, the message is the issue itself and its
+// metadata is added as headers.
+func writeMessage(w io.Writer, project string, user *github.User, t time.Time, subject, id, parent, body string, issue *github.Issue) {
+	login := getUserLogin(user)
+	fmt.Fprintf(w, "From %s@users.noreply.github.com %s\n", login, t.UTC().Format(time.ANSIC))
+	fmt.Fprintf(w, "From: %s <%s@users.noreply.github.com>\n", login, login)
+	fmt.Fprintf(w, "To: %s <%s@noreply.github.com>\n", project, projectRepo(project))
+	fmt.Fprintf(w, "Date: %s\n", t.Format(time.RFC1123Z))
+	fmt.Fprintf(w, "Subject: %s\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(w, "Message-ID: %s\n", id)
+	if parent != "" {
+		fmt.Fprintf(w, "In-Reply-To: %s\n", parent)
+		fmt.Fprintf(w, "References: %s\n", parent)
+	}
+	if issue != nil {
+		n := getInt(issue.Number)
+		fmt.Fprintf(w, "List-ID: %s <%s.%s.github.com>\n", project, projectRepo(project), projectOwner(project))
+		fmt.Fprintf(w, "X-Issue-State: %s\n", getString(issue.State))
+		if labels := getLabelNames(issue.Labels); len(labels) > 0 {
+			fmt.Fprintf(w, "Keywords: %s\n", mime.QEncoding.Encode("utf-8", strings.Join(labels, ", ")))
+		}
+		if m := getMilestoneTitle(issue.Milestone); m != "" {
+			fmt.Fprintf(w, "X-Issue-Milestone: %s\n", mime.QEncoding.Encode("utf-8", m))
+		}
+		fmt.Fprintf(w, "X-Issue-URL: %s\n", issueURL(project, n))
+	}
+	fmt.Fprintf(w, "MIME-Version: 1.0\n")
+	fmt.Fprintf(w, "Content-Type: text/plain; charset=utf-8\n")
+	fmt.Fprintf(w, "Content-Transfer-Encoding: 8bit\n")
+	fmt.Fprintf(w, "\n")
+
+	body = strings.ReplaceAll(strings.TrimSpace(body), "\r\n", "\n")
+	if body != "" {
+		fmt.Fprintf(w, "%s\n", mboxFromRE.ReplaceAllString(body, ">$1"))
+	}
+	fmt.Fprintf(w, "\n")
+}
+
+// mailRefRE matches the Message-ID of an issue or comment message.
+var mailRefRE = regexp.MustCompile(`<([A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+)/issues/([0-9]+)(?:/[0-9]+)?@github\.com>`)
+
+// runSendmail posts the mail message on standard input as a comment
+// on the issue it replies to. It ignores its arguments, so that it can be
+// used in place of sendmail, which mail clients run with their own flags.
+func runSendmail(project string, args []string) {
+	msg, err := mail.ReadMessage(bufio.NewReader(os.Stdin))
+	if err != nil {
+		fatal(err)
+	}
+	var proj string
+	var n int
+	for _, h := range []string{"In-Reply-To", "References"} {
+		if m := mailRefRE.FindStringSubmatch(msg.Header.Get(h)); m != nil {
+			proj = m[1]
+			n, _ = strconv.Atoi(m[2])
+			break
+		}
+	}
+	if n == 0 {
+		usageErrorf("message does not reply to an issue")
+	}
+	text, err := mailText(msg.Header, msg.Body)
+	if err != nil {
+		fatal(err)
+	}
+	text = stripQuoted(text)
+	if text == "" {
+		usageErrorf("empty comment")
+	}
+	if err := commentIssue(proj, n, text); err != nil {
+		fatal(err)
+	}
+}
+
+// mailText returns the text of a message with the given header and body,
+// decoding it and, for a multipart message, using its first text/plain part.
+func mailText(h interface{ Get(string) string }, body io.Reader) (string, error) {
+	typ, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		typ = "text/plain"
+	}
+	if strings.HasPrefix(typ, "multipart/") {
+		r := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := r.NextRawPart()
+			if err != nil {
+				if err == io.EOF {
+					return "", fmt.Errorf("message has no text/plain part")
+				}
+				return "", err
+			}
+			if text, err := mailText(p.Header, p); err == nil && text != "" {
+				return text, nil
+			}
+		}
+	}
+	if typ != "text/plain" {
+		return "", nil
+	}
+	switch strings.ToLower(h.Get("Content-Transfer-Encoding")) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(string(data), "\r\n", "\n"), nil
+}
+
+// stripQuoted removes the quoted message at the end of a reply,
+// along with the "On ..., X wrote:" line introducing it.
+func stripQuoted(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	i := len(lines)
+	for i > 0 && (strings.HasPrefix(lines[i-1], ">") || strings.TrimSpace(lines[i-1]) == "") {
+		i--
+	}
+	if i < len(lines) && i > 0 && strings.HasSuffix(strings.TrimSpace(lines[i-1]), "wrote:") {
+		i--
+	}
+	return strings.TrimSpace(strings.Join(lines[:i], "\n"))
+}
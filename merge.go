@@ -0,0 +1,155 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// quoteText returns text as a Markdown block quote.
+func quoteText(text string) string {
+	text = strings.TrimSpace(strings.Replace(text, "\r\n", "\n", -1))
+	if text == "" {
+		text = "(no text)"
+	}
+	return "> " + strings.Replace(text, "\n", "\n> ", -1)
+}
+
+// profileLink returns a Markdown link to the profile of u,
+// which, unlike an @-mention, does not notify the user.
+func profileLink(u *github.User) string {
+	login := getUserLogin(u)
+	return fmt.Sprintf("**[%s](%s%s)**", login, webURL(), login)
+}
+
+// mergeComments returns the comments that copy the body and
+// comments of issue src, quoted and attributed, into another issue,
+// packing as many as fit into each comment.
+func mergeComments(src *github.Issue, comments []*github.IssueComment) []string {
+	n := getInt(src.Number)
+	parts := []string{fmt.Sprintf("%s reported (%s):\n\n%s", profileLink(src.User), getTime(src.CreatedAt).Format(timeFormat), quoteText(getString(src.Body)))}
+	for _, com := range comments {
+		parts = append(parts, fmt.Sprintf("%s commented (%s):\n\n%s", profileLink(com.User), getTime(com.CreatedAt).Format(timeFormat), quoteText(getString(com.Body))))
+	}
+
+	var out []string
+	text := fmt.Sprintf("Merged from #%d.", n)
+	for _, p := range parts {
+		if len(text)+2+len(p) > maxCommentLen && strings.Contains(text, "\n") {
+			out = append(out, text)
+			text = fmt.Sprintf("Merged from #%d (continued).", n)
+		}
+		// A single part too long for a comment is cut off
+		// into a gist by postComment.
+		text += "\n\n" + p
+	}
+	return append(out, text)
+}
+
+func runMerge(project string, args []string) {
+	fs := lookupCommand("merge").flags()
+	parseFlags(fs, args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+	}
+	ns := issueArgs(fs, fs.Args())
+	srcN, dstN := ns[0], ns[1]
+	if srcN == dstN {
+		usageErrorf("cannot merge #%d into itself", srcN)
+	}
+	owner, repo := projectOwner(project), projectRepo(project)
+
+	src, err := getIssue(project, srcN)
+	if err != nil {
+		fatal(err)
+	}
+	dst, err := getIssue(project, dstN)
+	if err != nil {
+		fatal(err)
+	}
+	var comments []*github.IssueComment
+	for page := 1; ; {
+		list, resp, err := client.Issues.ListComments(context.TODO(), owner, repo, srcN, &github.IssueListCommentsOptions{
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: 100,
+			},
+		})
+		if err != nil {
+			fatal(err)
+		}
+		comments = append(comments, list...)
+		if resp.NextPage < page {
+			break
+		}
+		page = resp.NextPage
+	}
+
+	for _, text := range mergeComments(src, comments) {
+		if err := commentIssue(project, dstN, text); err != nil {
+			fatal(err)
+		}
+	}
+
+	// Add the labels of src that dst lacks, and move dst into
+	// src's milestone only if dst is not already in one.
+	have := make(map[string]bool)
+	for _, name := range getLabelNames(dst.Labels) {
+		have[name] = true
+	}
+	var add []string
+	for _, name := range getLabelNames(src.Labels) {
+		if !have[name] {
+			add = append(add, name)
+		}
+	}
+	milestone := ""
+	if src.Milestone != nil && dst.Milestone == nil {
+		milestone = getMilestoneTitle(src.Milestone)
+	}
+	if len(add) > 0 || milestone != "" {
+		change := &hookChange{State: getString(dst.State), AddLabels: add}
+		if milestone != "" {
+			change.Milestone = &milestone
+		}
+		err := putIssue(project, dstN, change, func() ([]string, error) {
+			var did []string
+			if len(add) > 0 {
+				if _, _, err := client.Issues.AddLabelsToIssue(context.TODO(), owner, repo, dstN, add); err != nil {
+					return did, err
+				}
+				did = append(did, "added label"+suffix(len(add))+" "+strings.Join(add, " "))
+			}
+			if milestone != "" {
+				if _, _, err := client.Issues.Edit(context.TODO(), owner, repo, dstN, &github.IssueRequest{Milestone: src.Milestone.Number}); err != nil {
+					return did, err
+				}
+				did = append(did, "set milestone "+milestone)
+			}
+			return did, nil
+		})
+		if err != nil {
+			fatal(err)
+		}
+	}
+
+	if err := closeWithComment(project, srcN, fmt.Sprintf("Duplicate of #%d", dstN)); err != nil {
+		fatal(err)
+	}
+
+	msg := fmt.Sprintf("merged #%d into #%d: copied %d comment%s", srcN, dstN, len(comments), suffix(len(comments)))
+	if len(add) > 0 {
+		msg += ", added label" + suffix(len(add)) + " " + strings.Join(add, " ")
+	}
+	if milestone != "" {
+		msg += ", set milestone " + milestone
+	}
+	log.Print(msg)
+}
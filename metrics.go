@@ -0,0 +1,87 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// metrics records the health of a long-running issue process,
+// for export at /metrics in the Prometheus text format.
+var metrics struct {
+	sync.Mutex
+	apiCalls      int64     // requests sent to GitHub
+	apiErrors     int64     // GitHub requests that failed or returned an error status
+	rateRemaining int64     // rate limit remaining, from the latest response
+	rateKnown     bool      // whether rateRemaining has been set
+	lastSync      time.Time // latest successful refresh of mirrored data
+	staleServed   int64     // responses served from an outdated mirror copy
+	requests      int64     // HTTP requests served
+	webhookErrors int64     // failed chat webhook notifications
+}
+
+// A metricsTransport counts the GitHub API requests made through it
+// and records the rate limit reported by GitHub.
+type metricsTransport struct {
+	transport http.RoundTripper
+}
+
+func newMetricsTransport(t http.RoundTripper) http.RoundTripper {
+	return &metricsTransport{transport: t}
+}
+
+func (t *metricsTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	resp, err := t.transport.RoundTrip(r)
+	if client == nil || r.URL.Host != client.BaseURL.Host {
+		return resp, err
+	}
+	metrics.Lock()
+	metrics.apiCalls++
+	if err != nil || resp.StatusCode >= 400 {
+		metrics.apiErrors++
+	}
+	if resp != nil {
+		if n, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Remaining"), 10, 64); err == nil {
+			metrics.rateRemaining = n
+			metrics.rateKnown = true
+		}
+	}
+	metrics.Unlock()
+	return resp, err
+}
+
+// countRequests wraps h to count the requests it serves.
+func countRequests(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		metrics.Lock()
+		metrics.requests++
+		metrics.Unlock()
+		h.ServeHTTP(w, r)
+	})
+}
+
+func serveMetrics(w http.ResponseWriter, r *http.Request) {
+	metrics.Lock()
+	defer metrics.Unlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	metric := func(name, typ, help string, value interface{}) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, typ, name, value)
+	}
+	metric("issue_github_requests_total", "counter", "Requests sent to the GitHub API.", metrics.apiCalls)
+	metric("issue_github_errors_total", "counter", "GitHub API requests that failed.", metrics.apiErrors)
+	if metrics.rateKnown {
+		metric("issue_github_rate_limit_remaining", "gauge", "GitHub API requests remaining before the rate limit resets.", metrics.rateRemaining)
+	}
+	if !metrics.lastSync.IsZero() {
+		metric("issue_mirror_sync_lag_seconds", "gauge", "Seconds since mirrored data was last refreshed from GitHub.", time.Since(metrics.lastSync).Seconds())
+	}
+	metric("issue_mirror_stale_total", "counter", "Responses served from an outdated mirror copy.", metrics.staleServed)
+	metric("issue_http_requests_total", "counter", "HTTP requests served.", metrics.requests)
+	metric("issue_webhook_errors_total", "counter", "Chat webhook notifications that failed.", metrics.webhookErrors)
+}
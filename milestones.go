@@ -0,0 +1,215 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+)
+
+func runMilestones(project string, args []string) {
+	fs := lookupCommand("milestones").flags()
+	ics := fs.Bool("ics", false, "write an iCalendar feed of milestone due dates")
+	mermaid := fs.Bool("mermaid", false, "write a Mermaid Gantt chart of milestone schedules")
+	state := fs.String("state", "open", "list the milestones in `state`: open, closed, or all")
+	sortBy := fs.String("sort", "due", "sort the milestones by `key`: due or title")
+	before := fs.String("due-before", "", "list only milestones due before `date` (YYYY-MM-DD)")
+	after := fs.String("due-after", "", "list only milestones due on or after `date` (YYYY-MM-DD)")
+	title := fs.String("title", "", "list only milestones with titles matching `regexp`")
+	parseFlags(fs, args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+	}
+	if *ics && *mermaid {
+		usageErrorf("cannot use -ics with -mermaid")
+	}
+	switch *state {
+	case "open", "closed", "all":
+	default:
+		usageErrorf("invalid -state %q: must be open, closed, or all", *state)
+	}
+	if *sortBy != "due" && *sortBy != "title" {
+		usageErrorf("invalid -sort %q: must be due or title", *sortBy)
+	}
+	parseDate := func(name, s string) time.Time {
+		if s == "" {
+			return time.Time{}
+		}
+		t, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			usageErrorf("invalid -%s date %q: want YYYY-MM-DD", name, s)
+		}
+		return t
+	}
+	beforeTime, afterTime := parseDate("due-before", *before), parseDate("due-after", *after)
+	titleRE, err := regexp.Compile(*title)
+	if err != nil {
+		usageErrorf("invalid -title: %v", err)
+	}
+
+	all, err := listMilestones(project, *state)
+	if err != nil {
+		fatal(err)
+	}
+	var list []*github.Milestone
+	for _, m := range all {
+		if !titleRE.MatchString(getString(m.Title)) {
+			continue
+		}
+		if !beforeTime.IsZero() || !afterTime.IsZero() {
+			if m.DueOn == nil {
+				continue
+			}
+			due := m.DueOn.UTC()
+			if !beforeTime.IsZero() && !due.Before(beforeTime) || !afterTime.IsZero() && due.Before(afterTime) {
+				continue
+			}
+		}
+		list = append(list, m)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if *sortBy == "title" {
+			return getString(list[i].Title) < getString(list[j].Title)
+		}
+		di, dj := list[i].DueOn, list[j].DueOn
+		if (di == nil) != (dj == nil) {
+			return di != nil
+		}
+		if di != nil && !di.Equal(*dj) {
+			return di.Before(*dj)
+		}
+		return getString(list[i].Title) < getString(list[j].Title)
+	})
+	if *ics {
+		writeICS(os.Stdout, project, list)
+		return
+	}
+	if *mermaid {
+		writeGantt(os.Stdout, project, list, time.Now())
+		return
+	}
+	for _, m := range list {
+		due := "no due date"
+		if m.DueOn != nil {
+			due = "due " + m.DueOn.UTC().Format("2006-01-02")
+		}
+		if m.GetState() == "closed" {
+			due += ", closed"
+		}
+		open, closed := m.GetOpenIssues(), m.GetClosedIssues()
+		fmt.Printf("%s\t%s\t%d open, %d closed (%d%% complete)\n", getString(m.Title), due, open, closed, percentClosed(open, closed))
+	}
+}
+
+// percentClosed returns the percentage of a milestone's issues
+// that are closed. A milestone with no issues is complete.
+func percentClosed(open, closed int) int {
+	if open+closed == 0 {
+		return 100
+	}
+	return closed * 100 / (open + closed)
+}
+
+// writeICS writes an iCalendar feed with an all-day event
+// on the due date of each milestone that has one.
+func writeICS(w io.Writer, project string, list []*github.Milestone) {
+	var lines []string
+	add := func(format string, args ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}
+	add("BEGIN:VCALENDAR")
+	add("VERSION:2.0")
+	add("PRODID:-//issue//%s milestones//EN", project)
+	add("X-WR-CALNAME:%s", icsText(project+" milestones"))
+	for _, m := range list {
+		if m.DueOn == nil {
+			continue
+		}
+		due := m.DueOn.UTC()
+		add("BEGIN:VEVENT")
+		add("UID:%s/milestone/%d@github.com", project, m.GetNumber())
+		add("DTSTAMP:%s", m.GetUpdatedAt().UTC().Format("20060102T150405Z"))
+		add("DTSTART;VALUE=DATE:%s", due.Format("20060102"))
+		add("DTEND;VALUE=DATE:%s", due.AddDate(0, 0, 1).Format("20060102"))
+		add("SUMMARY:%s", icsText(fmt.Sprintf("%s %s due", project, getString(m.Title))))
+		desc := fmt.Sprintf("%d open issues, %d closed.", m.GetOpenIssues(), m.GetClosedIssues())
+		if d := strings.TrimSpace(m.GetDescription()); d != "" {
+			desc += "\n\n" + d
+		}
+		add("DESCRIPTION:%s", icsText(desc))
+		add("URL:%s", m.GetHTMLURL())
+		add("END:VEVENT")
+	}
+	add("END:VCALENDAR")
+	for _, line := range lines {
+		io.WriteString(w, icsFold(line))
+	}
+}
+
+// writeGantt writes a Mermaid Gantt chart with a bar for each milestone
+// that has a due date, running from its creation to its due date and
+// labeled with its progress. A milestone with no open issues is drawn
+// as done, and an overdue one with open issues as critical.
+func writeGantt(w io.Writer, project string, list []*github.Milestone, now time.Time) {
+	fmt.Fprintf(w, "gantt\n")
+	fmt.Fprintf(w, "\ttitle %s milestones\n", project)
+	fmt.Fprintf(w, "\tdateFormat YYYY-MM-DD\n")
+	for _, m := range list {
+		if m.DueOn == nil {
+			continue
+		}
+		due := m.DueOn.UTC()
+		start := m.GetCreatedAt().UTC()
+		if start.After(due) {
+			start = due
+		}
+		open, closed := m.GetOpenIssues(), m.GetClosedIssues()
+		pct := percentClosed(open, closed)
+		tag := "active, "
+		switch {
+		case open == 0:
+			tag = "done, "
+		case due.Before(now):
+			tag = "crit, "
+		}
+		// Colons and semicolons separate fields in Mermaid task lines.
+		title := strings.NewReplacer(":", " ", ";", " ", "#", " ").Replace(getString(m.Title))
+		fmt.Fprintf(w, "\tsection %s\n", title)
+		fmt.Fprintf(w, "\t%d of %d closed (%d%%) :%sm%d, %s, %s\n", closed, open+closed, pct, tag,
+			m.GetNumber(), start.Format("2006-01-02"), due.Format("2006-01-02"))
+	}
+}
+
+// icsText escapes s for use as an iCalendar TEXT value.
+func icsText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// icsFold returns the content line, terminated by CRLF, folded so that
+// no line is longer than 75 bytes, without splitting UTF-8 sequences.
+func icsFold(line string) string {
+	var b strings.Builder
+	max := 75
+	for len(line) > max {
+		i := max
+		for i > 0 && line[i]&0xC0 == 0x80 {
+			i--
+		}
+		b.WriteString(line[:i])
+		b.WriteString("\r\n ")
+		line = line[i:]
+		max = 74 // continuation lines begin with a space
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+	return b.String()
+}
@@ -0,0 +1,114 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A netrcLine is a machine entry in a .netrc file.
+type netrcLine struct {
+	machine  string // "" for the default entry
+	login    string
+	password string
+}
+
+// parseNetrc parses the contents of a .netrc file,
+// skipping macro definitions.
+func parseNetrc(data string) []netrcLine {
+	var lines []netrcLine
+	var l *netrcLine
+	inMacro := false
+	for _, line := range strings.Split(data, "\n") {
+		if inMacro {
+			if strings.TrimSpace(line) == "" {
+				inMacro = false
+			}
+			continue
+		}
+		f := strings.Fields(line)
+		for i := 0; i < len(f); i++ {
+			switch f[i] {
+			case "machine", "default":
+				lines = append(lines, netrcLine{})
+				l = &lines[len(lines)-1]
+				if f[i] == "machine" && i+1 < len(f) {
+					i++
+					l.machine = f[i]
+				}
+			case "login", "password", "account":
+				if l == nil || i+1 == len(f) {
+					continue
+				}
+				i++
+				if f[i-1] == "login" {
+					l.login = f[i]
+				} else if f[i-1] == "password" {
+					l.password = f[i]
+				}
+			case "macdef":
+				inMacro = true
+				i = len(f)
+			}
+		}
+	}
+	return lines
+}
+
+// netrcFile returns the name of the user's .netrc file:
+// $NETRC if set, as curl and the go command allow, or else
+// .netrc (_netrc on Windows) in the home directory.
+func netrcFile() (string, error) {
+	if file := os.Getenv("NETRC"); file != "" {
+		return file, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	if os.PathSeparator == '\\' {
+		return filepath.Join(home, "_netrc"), nil
+	}
+	return filepath.Join(home, ".netrc"), nil
+}
+
+// netrcToken returns the token for host from the user's .netrc file,
+// or "" if the file has no entry for host. A token can be recorded
+// either as the password, with any login, or, as some tools write it,
+// as the login with the password x-oauth-basic.
+func netrcToken(host string) (string, error) {
+	file, err := netrcFile()
+	if err != nil {
+		return "", err
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	for _, l := range parseNetrc(string(data)) {
+		if l.machine != host {
+			continue
+		}
+		fi, err := os.Stat(file)
+		if err != nil {
+			return "", err
+		}
+		if fi.Mode()&0077 != 0 {
+			return "", fmt.Errorf("%s mode is %#o, want %#o", file, fi.Mode()&0777, fi.Mode()&0700)
+		}
+		if l.password == "x-oauth-basic" || l.password == "" {
+			return l.login, nil
+		}
+		return l.password, nil
+	}
+	return "", nil
+}
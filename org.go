@@ -0,0 +1,78 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// orgTagRE matches the characters not allowed in Org mode tags.
+var orgTagRE = regexp.MustCompile(`[^A-Za-z0-9_@#%]+`)
+
+// showOrg writes the issues as an Org mode document,
+// one heading per issue, with the body text if withText is set.
+// Open issues are TODO items and closed ones DONE,
+// labels become tags, and the milestone due date is the scheduled date.
+func showOrg(w io.Writer, project string, issues []*github.Issue, withText bool) {
+	fmt.Fprintf(w, "#+TITLE: %s issues\n", project)
+	fmt.Fprintf(w, "#+TODO: TODO | DONE\n")
+	for _, issue := range issues {
+		state := "TODO"
+		if getString(issue.State) == "closed" {
+			state = "DONE"
+		}
+		var tags []string
+		for _, name := range getLabelNames(issue.Labels) {
+			tags = append(tags, strings.Trim(orgTagRE.ReplaceAllString(name, "_"), "_"))
+		}
+		fmt.Fprintf(w, "\n* %s %s", state, getString(issue.Title))
+		if len(tags) > 0 {
+			fmt.Fprintf(w, " :%s:", strings.Join(tags, ":"))
+		}
+		fmt.Fprintf(w, "\n")
+
+		var planning []string
+		if issue.ClosedAt != nil {
+			planning = append(planning, "CLOSED: "+getTime(issue.ClosedAt).Format("[2006-01-02 Mon 15:04]"))
+		}
+		if m := issue.Milestone; m != nil && m.DueOn != nil && state == "TODO" {
+			planning = append(planning, "SCHEDULED: "+m.DueOn.Format("<2006-01-02 Mon>"))
+		}
+		if len(planning) > 0 {
+			fmt.Fprintf(w, "  %s\n", strings.Join(planning, " "))
+		}
+
+		fmt.Fprintf(w, "  :PROPERTIES:\n")
+		fmt.Fprintf(w, "  :ISSUE: %s#%d\n", project, getInt(issue.Number))
+		fmt.Fprintf(w, "  :URL: %s\n", issueURL(project, getInt(issue.Number)))
+		if a := getUserLogin(issue.Assignee); a != "" {
+			fmt.Fprintf(w, "  :ASSIGNEE: %s\n", a)
+		}
+		if m := getMilestoneTitle(issue.Milestone); m != "" {
+			fmt.Fprintf(w, "  :MILESTONE: %s\n", m)
+		}
+		fmt.Fprintf(w, "  :REPORTER: %s\n", getUserLogin(issue.User))
+		fmt.Fprintf(w, "  :CREATED: %s\n", getTime(issue.CreatedAt).Format("[2006-01-02 Mon 15:04]"))
+		fmt.Fprintf(w, "  :END:\n")
+
+		if withText {
+			text := strings.TrimSpace(getString(issue.Body))
+			if text != "" {
+				for _, line := range strings.Split(text, "\n") {
+					// A line beginning with * would start a new heading.
+					if strings.HasPrefix(line, "*") {
+						line = " " + line
+					}
+					fmt.Fprintf(w, "%s\n", strings.TrimRight(line, " \t\r"))
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,334 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// An ownersRule is a line from a CODEOWNERS file, or from the
+// path-labels configuration setting, which uses the same syntax.
+type ownersRule struct {
+	pattern string
+	re      *regexp.Regexp
+	names   []string // owners or labels
+	line    int
+}
+
+// parseOwners parses the rules in a CODEOWNERS file.
+func parseOwners(data string) ([]*ownersRule, error) {
+	var rules []*ownersRule
+	for i, line := range strings.Split(data, "\n") {
+		if j := strings.Index(line, "#"); j >= 0 {
+			line = line[:j]
+		}
+		f := strings.Fields(line)
+		if len(f) == 0 {
+			continue
+		}
+		re, err := ownersPatternRE(f[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		rules = append(rules, &ownersRule{pattern: f[0], re: re, names: f[1:], line: i + 1})
+	}
+	return rules, nil
+}
+
+// ownersPatternRE returns a regexp matching the file paths that
+// match the CODEOWNERS pattern pat, which follows the gitignore rules:
+// a pattern containing a slash other than at its end is relative to
+// the repository root, a trailing slash matches only directories,
+// * and ? match within a path element, and ** matches across them.
+func ownersPatternRE(pat string) (*regexp.Regexp, error) {
+	anchored := strings.Contains(strings.TrimSuffix(pat, "/"), "/")
+	pat = strings.TrimPrefix(pat, "/")
+	dir := strings.HasSuffix(pat, "/")
+	pat = strings.TrimSuffix(pat, "/")
+
+	var b strings.Builder
+	if anchored {
+		b.WriteString("^")
+	} else {
+		b.WriteString("(^|/)")
+	}
+	for i := 0; i < len(pat); i++ {
+		switch c := pat[i]; {
+		case strings.HasPrefix(pat[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pat[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	switch {
+	case dir:
+		b.WriteString("/")
+	case strings.HasSuffix(pat, "/*"):
+		// Matches only the files directly in the directory.
+		b.WriteString("$")
+	default:
+		b.WriteString("(/|$)")
+	}
+	return regexp.Compile(b.String())
+}
+
+// matchOwners returns the last of the rules matching file, or nil.
+func matchOwners(rules []*ownersRule, file string) *ownersRule {
+	var match *ownersRule
+	for _, r := range rules {
+		if r.re.MatchString(file) {
+			match = r
+		}
+	}
+	return match
+}
+
+// loadCodeowners fetches and parses the project's CODEOWNERS file,
+// from any of the locations where GitHub looks for it.
+func loadCodeowners(project string) ([]*ownersRule, error) {
+	for _, name := range []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"} {
+		file, _, resp, err := client.Repositories.GetContents(context.TODO(), projectOwner(project), projectRepo(project), name, nil)
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				continue
+			}
+			return nil, err
+		}
+		data, err := file.GetContent()
+		if err != nil {
+			return nil, err
+		}
+		rules, err := parseOwners(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		return rules, nil
+	}
+	return nil, fmt.Errorf("%s has no CODEOWNERS file", project)
+}
+
+// loadRepoFiles returns the set of file paths in the project's default branch,
+// or nil if the tree is too large for GitHub to list in one request.
+func loadRepoFiles(project string) (map[string]bool, error) {
+	var paths []string
+	if !readCache(project, "files", cacheMaxAge, &paths) {
+		tree, _, err := client.Git.GetTree(context.TODO(), projectOwner(project), projectRepo(project), "HEAD", true)
+		if err != nil {
+			return nil, err
+		}
+		if tree.GetTruncated() {
+			return nil, nil
+		}
+		for _, e := range tree.Entries {
+			if e.GetType() == "blob" {
+				paths = append(paths, e.GetPath())
+			}
+		}
+		writeCache(project, "files", paths)
+	}
+	files := make(map[string]bool)
+	for _, p := range paths {
+		files[p] = true
+	}
+	return files, nil
+}
+
+// mentionedPathRE matches text that may be a file path: a word
+// containing a slash or ending in a file extension.
+var mentionedPathRE = regexp.MustCompile(`[A-Za-z0-9_.@+$-]*(?:/[A-Za-z0-9_.@+-]+)+|[A-Za-z0-9_@+-]+\.[A-Za-z][A-Za-z0-9]{0,5}\b`)
+
+// mentionedFiles returns the repository files mentioned in text,
+// such as in a stack trace or a GitHub link. An absolute path or URL
+// is matched by its longest suffix that names a file in the repository.
+// If files is nil, any relative path with an extension is accepted.
+func mentionedFiles(text string, files map[string]bool) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, s := range mentionedPathRE.FindAllString(text, -1) {
+		s = strings.TrimLeft(strings.TrimPrefix(s, "./"), "/")
+		file := ""
+		if files == nil {
+			if strings.Contains(s, "/") && strings.Contains(s[strings.LastIndex(s, "/"):], ".") && !strings.Contains(s, "..") {
+				file = s
+			}
+		} else {
+			for p := s; p != ""; {
+				if files[p] {
+					file = p
+					break
+				}
+				i := strings.Index(p, "/")
+				if i < 0 {
+					break
+				}
+				p = p[i+1:]
+			}
+		}
+		if file != "" && !seen[file] {
+			seen[file] = true
+			out = append(out, file)
+		}
+	}
+	return out
+}
+
+func runSuggestOwner(project string, args []string) {
+	fs := lookupCommand("suggest-owner").flags()
+	apply := fs.Bool("apply", false, "apply the suggestions, after asking for confirmation")
+	yes := fs.Bool("y", false, "with -apply, do not ask for confirmation")
+	parseFlags(fs, args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+	}
+	n := issueArgs(fs, fs.Args())[0]
+	if *apply && !*yes && !canPrompt() {
+		usageErrorf("-apply asks for confirmation in a terminal; use -y to apply without asking")
+	}
+
+	rules, err := loadCodeowners(project)
+	if err != nil {
+		fatal(err)
+	}
+	var labelRules []*ownersRule
+	if lines := loadConfig().PathLabels; len(lines) > 0 {
+		if labelRules, err = parseOwners(strings.Join(lines, "\n")); err != nil {
+			file, _ := configFile()
+			fatalf("%s: path-labels: %v", file, err)
+		}
+	}
+	files, err := loadRepoFiles(project)
+	if err != nil {
+		fatal(err)
+	}
+
+	issue, _, err := client.Issues.Get(context.TODO(), projectOwner(project), projectRepo(project), n)
+	if err != nil {
+		fatal(err)
+	}
+	text := getString(issue.Body)
+	for page := 1; ; {
+		list, resp, err := client.Issues.ListComments(context.TODO(), projectOwner(project), projectRepo(project), n, &github.IssueListCommentsOptions{
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: 100,
+			},
+		})
+		if err != nil {
+			fatal(err)
+		}
+		for _, com := range list {
+			text += "\n" + getString(com.Body)
+		}
+		if resp.NextPage < page {
+			break
+		}
+		page = resp.NextPage
+	}
+
+	mentioned := mentionedFiles(text, files)
+	if len(mentioned) == 0 {
+		fmt.Printf("Issue #%d mentions no files in %s.\n", n, project)
+		exit(exitNoMatch)
+	}
+
+	// Count how many mentioned files each owner and label covers,
+	// so that the suggestions are listed most relevant first.
+	ownerCount := make(map[string]int)
+	labelCount := make(map[string]int)
+	fmt.Printf("Files mentioned in #%d:\n", n)
+	for _, file := range mentioned {
+		r := matchOwners(rules, file)
+		if r == nil || len(r.names) == 0 {
+			fmt.Printf("\t%s\t(no owner)\n", file)
+		} else {
+			fmt.Printf("\t%s\t%s\t(CODEOWNERS line %d: %s)\n", file, strings.Join(r.names, " "), r.line, r.pattern)
+			for _, name := range r.names {
+				// Issues can be assigned to users and, through
+				// teamAssignee, teams, but not to email addresses.
+				if strings.HasPrefix(name, "@") {
+					ownerCount[name]++
+				}
+			}
+		}
+		if r := matchOwners(labelRules, file); r != nil {
+			for _, name := range r.names {
+				labelCount[name]++
+			}
+		}
+	}
+	byCount := func(m map[string]int) []string {
+		var list []string
+		for name := range m {
+			list = append(list, name)
+		}
+		sort.Slice(list, func(i, j int) bool {
+			if m[list[i]] != m[list[j]] {
+				return m[list[i]] > m[list[j]]
+			}
+			return list[i] < list[j]
+		})
+		return list
+	}
+	owners, labels := byCount(ownerCount), byCount(labelCount)
+	fmt.Printf("Suggested assignees: %s\n", strings.Join(owners, " "))
+	if labelRules != nil {
+		fmt.Printf("Suggested labels: %s\n", strings.Join(labels, " "))
+	}
+	if !*apply || len(owners) == 0 && len(labels) == 0 {
+		return
+	}
+
+	if !*yes {
+		fmt.Fprintf(os.Stderr, "apply to #%d? [y/N] ", n)
+		line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if ans := strings.ToLower(strings.TrimSpace(line)); ans != "y" && ans != "yes" {
+			return
+		}
+	}
+	for i, owner := range owners {
+		owners[i] = strings.TrimPrefix(owner, "@")
+		if isTeam(owner) {
+			if owners[i], err = teamAssignee(project, owner, canPrompt() && !*yes); err != nil {
+				fatal(err)
+			}
+		}
+	}
+	err = putIssue(project, n, &hookChange{AddAssignees: owners, AddLabels: labels}, func() ([]string, error) {
+		var did []string
+		if len(owners) > 0 {
+			if _, _, err := client.Issues.AddAssignees(context.TODO(), projectOwner(project), projectRepo(project), n, owners); err != nil {
+				return did, err
+			}
+			did = append(did, "assigned "+strings.Join(owners, " "))
+		}
+		if len(labels) > 0 {
+			if _, _, err := client.Issues.AddLabelsToIssue(context.TODO(), projectOwner(project), projectRepo(project), n, labels); err != nil {
+				return did, err
+			}
+			did = append(did, "added label"+suffix(len(labels))+" "+strings.Join(labels, " "))
+		}
+		return did, nil
+	})
+	if err != nil {
+		fatal(err)
+	}
+}
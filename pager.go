@@ -0,0 +1,50 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+)
+
+// startPager starts the user's pager, $PAGER if set or else less,
+// and returns a writer connected to its standard input along with
+// a function that closes the writer and waits for the pager to exit.
+// If the pager cannot be started, startPager returns standard output,
+// logging the error only if $PAGER was set, since not having less
+// installed is no reason to complain.
+func startPager() (io.Writer, func()) {
+	pager := os.Getenv("PAGER")
+	if pager == "" {
+		pager = "less"
+	}
+	if pager == "cat" {
+		return os.Stdout, func() {}
+	}
+
+	cmd := userCommand(pager)
+	// Like git, ask less to exit if the text fits on one screen,
+	// pass through color and hyperlink escapes, and not clear the screen.
+	if os.Getenv("LESS") == "" {
+		cmd.Env = append(os.Environ(), "LESS=FRX")
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	w, err := cmd.StdinPipe()
+	if err != nil {
+		return os.Stdout, func() {}
+	}
+	if err := cmd.Start(); err != nil {
+		if os.Getenv("PAGER") != "" {
+			log.Printf("starting pager: %v", err)
+		}
+		return os.Stdout, func() {}
+	}
+	return w, func() {
+		w.Close()
+		cmd.Wait()
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// An IssuePatch is a change to an issue, read as JSON by "issue -json -e <n>".
+// Its fields have the same names as those of Issue; fields that are
+// omitted or null are left unchanged.
+// If you make changes to the struct, copy them back into the doc comment.
+type IssuePatch struct {
+	Title     *string
+	State     *string
+	Assignee  *string
+	Labels    *[]string
+	Milestone *string
+	Text      *string
+	Comment   string
+}
+
+// readOnlyFields are the Issue fields that may appear in a patch,
+// as when a program edits the output of -json and sends it back,
+// but are ignored.
+var readOnlyFields = map[string]bool{
+	"Number":   true,
+	"Ref":      true,
+	"Closed":   true,
+	"URL":      true,
+	"Reporter": true,
+	"Created":  true,
+	"Tasks":    true,
+	"Comments": true,
+}
+
+// readIssuePatch reads a JSON IssuePatch from r,
+// rejecting any fields it does not recognize.
+func readIssuePatch(r io.Reader) (*IssuePatch, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("parsing patch: %v", err)
+	}
+	for name := range fields {
+		if readOnlyFields[name] {
+			delete(fields, name)
+		}
+	}
+	data, _ = json.Marshal(fields)
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.DisallowUnknownFields()
+	p := new(IssuePatch)
+	if err := dec.Decode(p); err != nil {
+		return nil, fmt.Errorf("parsing patch: %v", err)
+	}
+	return p, nil
+}
+
+// applyIssuePatch applies p to issue n, returning the updated issue.
+func applyIssuePatch(project string, n int, p *IssuePatch) (*github.Issue, error) {
+	owner, repo := projectOwner(project), projectRepo(project)
+	var edit github.IssueRequest
+	edit.Title = p.Title
+	edit.State = p.State
+	edit.Assignee = p.Assignee
+	edit.Labels = p.Labels
+	edit.Body = p.Text
+	if edit.Labels != nil && *edit.Labels == nil {
+		edit.Labels = &[]string{}
+	}
+	removeMilestone := false
+	if p.Milestone != nil {
+		if *p.Milestone == "" {
+			removeMilestone = true
+		} else {
+			var errbuf strings.Builder
+			edit.Milestone = findMilestone(&errbuf, project, p.Milestone)
+			if edit.Milestone == nil {
+				return nil, fmt.Errorf("%s", strings.TrimSpace(errbuf.String()))
+			}
+		}
+	}
+
+	change := &hookChange{
+		Title:     edit.Title,
+		NewState:  edit.State,
+		Assignee:  edit.Assignee,
+		Labels:    edit.Labels,
+		Milestone: p.Milestone,
+		Comment:   p.Comment,
+	}
+	var issue *github.Issue
+	err := putIssue(project, n, change, func() ([]string, error) {
+		var did []string
+		if removeMilestone {
+			if _, _, err := client.Issues.RemoveMilestone(context.TODO(), owner, repo, n); err != nil {
+				return did, fmt.Errorf("removing milestone: %v", err)
+			}
+			did = append(did, "removed milestone")
+		}
+		var err error
+		if edit == (github.IssueRequest{}) {
+			issue, _, err = client.Issues.Get(context.TODO(), owner, repo, n)
+			if err != nil {
+				return did, err
+			}
+		} else {
+			issue, _, err = client.Issues.Edit(context.TODO(), owner, repo, n, &edit)
+			if err != nil {
+				return did, fmt.Errorf("updating issue: %v", err)
+			}
+			did = append(did, "updated metadata")
+		}
+		// Post the comment only once the changes it may describe are made.
+		if p.Comment != "" {
+			if err := postComment(project, n, p.Comment); err != nil {
+				return did, fmt.Errorf("posting comment: %v", err)
+			}
+			did = append(did, "saved comment")
+		}
+		return did, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return issue, nil
+}
+
+// editJSON applies a patch read from standard input to issue n
+// and prints the updated issue as JSON.
+func editJSON(project string, n int) {
+	p, err := readIssuePatch(os.Stdin)
+	if err != nil {
+		usageErrorf("%v", err)
+	}
+	issue, err := applyIssuePatch(project, n, p)
+	if err != nil {
+		fatal(err)
+	}
+	updateIssueCache(project, issue)
+	data, err := json.MarshalIndent(toJSON(project, issue), "", "\t")
+	if err != nil {
+		fatal(err)
+	}
+	os.Stdout.Write(append(data, '\n'))
+}
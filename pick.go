@@ -0,0 +1,276 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// A pickItem is an issue known locally, offered by the pick command.
+type pickItem struct {
+	Number int
+	Title  string
+	Labels []string
+}
+
+// text returns the line showing the item, which is also the text
+// that the pattern is matched against.
+func (p *pickItem) text() string {
+	s := fmt.Sprintf("%d %s", p.Number, p.Title)
+	if len(p.Labels) > 0 {
+		s += " [" + strings.Join(p.Labels, " ") + "]"
+	}
+	return s
+}
+
+// loadPickItems returns the locally known issues: the project's open
+// issues, listed at most once a day, or again if refresh is set, along with
+// any other issues in the -serve mirror.
+func loadPickItems(project string, refresh bool) ([]*pickItem, error) {
+	var items []*pickItem
+	if refresh || !readCache(project, "pick", cacheMaxAge, &items) {
+		all, err := listRepoIssues(project, github.IssueListByRepoOptions{State: "open"})
+		if err != nil {
+			return nil, err
+		}
+		items = nil
+		for _, issue := range all {
+			items = append(items, &pickItem{getInt(issue.Number), getString(issue.Title), getLabelNames(issue.Labels)})
+		}
+		writeCache(project, "pick", items)
+	}
+
+	seen := make(map[int]bool)
+	for _, p := range items {
+		seen[p.Number] = true
+	}
+	add := func(list []*Issue) {
+		for _, issue := range list {
+			if !seen[issue.Number] {
+				seen[issue.Number] = true
+				items = append(items, &pickItem{issue.Number, issue.Title, issue.Labels})
+			}
+		}
+	}
+	if dir, err := cacheFile(project, "serve"); err == nil {
+		dir = strings.TrimSuffix(dir, ".json")
+		files, _ := filepath.Glob(filepath.Join(dir, "issue", "*.v1.json"))
+		for _, file := range files {
+			var issue Issue
+			if data, err := ioutil.ReadFile(file); err == nil && json.Unmarshal(data, &issue) == nil {
+				add([]*Issue{&issue})
+			}
+		}
+		files, _ = filepath.Glob(filepath.Join(dir, "search", "*.v1.json"))
+		for _, file := range files {
+			var list []*Issue
+			if data, err := ioutil.ReadFile(file); err == nil && json.Unmarshal(data, &list) == nil {
+				add(list)
+			}
+		}
+	}
+	return items, nil
+}
+
+// fuzzyScore reports whether text matches the fuzzy pattern, which is
+// a list of space-separated terms whose letters must all appear in order
+// in text, ignoring case. A lower score is a better match: a term found
+// as a substring scores zero, and otherwise each letter skipped between
+// the first and last letters of the term's match costs one.
+func fuzzyScore(text, pattern string) (int, bool) {
+	text = strings.ToLower(text)
+	score := 0
+	for _, term := range strings.Fields(strings.ToLower(pattern)) {
+		if strings.Contains(text, term) {
+			continue
+		}
+		best := -1
+		for start := 0; start < len(text); start++ {
+			// Greedily match the term beginning at start.
+			i, j := start, 0
+			first := -1
+			for i < len(text) && j < len(term) {
+				r, size := utf8.DecodeRuneInString(text[i:])
+				t, tsize := utf8.DecodeRuneInString(term[j:])
+				if r == t {
+					if first < 0 {
+						first = i
+					}
+					j += tsize
+				}
+				i += size
+			}
+			if j < len(term) {
+				break
+			}
+			gaps := i - first - len(term)
+			if best < 0 || gaps < best {
+				best = gaps
+			}
+			start = first
+		}
+		if best < 0 {
+			return 0, false
+		}
+		score += best
+	}
+	return score, true
+}
+
+// pickMatches returns the items matching pattern, best first,
+// and, among equally good matches, newest first.
+func pickMatches(items []*pickItem, pattern string) []*pickItem {
+	type match struct {
+		item  *pickItem
+		score int
+	}
+	var matches []match
+	for _, p := range items {
+		if score, ok := fuzzyScore(p.text(), pattern); ok {
+			matches = append(matches, match{p, score})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score < matches[j].score
+		}
+		return matches[i].item.Number > matches[j].item.Number
+	})
+	var out []*pickItem
+	for _, m := range matches {
+		out = append(out, m.item)
+	}
+	return out
+}
+
+// pickInteractive lets the user narrow the items by typing a pattern,
+// starting with pattern, and choose one, drawing on standard error.
+// It returns nil if the user cancels.
+func pickInteractive(items []*pickItem, pattern string) (*pickItem, error) {
+	t := &tui{out: bufio.NewWriter(os.Stderr)}
+	if err := t.start(); err != nil {
+		return nil, err
+	}
+	defer t.stop()
+
+	text := []rune(pattern)
+	sel, top := 0, 0
+	for {
+		matches := pickMatches(items, string(text))
+		if sel >= len(matches) {
+			sel = len(matches) - 1
+		}
+		if sel < 0 {
+			sel = 0
+		}
+
+		t.size()
+		height := t.rows - 1
+		if sel < top {
+			top = sel
+		}
+		if sel >= top+height {
+			top = sel - height + 1
+		}
+		fmt.Fprintf(t.out, "\x1b[1;1H%s", fit(fmt.Sprintf("> %s  %d/%d", string(text), len(matches), len(items)), t.cols))
+		for row := 0; row < height; row++ {
+			line := ""
+			if i := top + row; i < len(matches) {
+				line = matches[i].text()
+			}
+			if top+row == sel {
+				fmt.Fprintf(t.out, "\x1b[%d;1H\x1b[7m%s\x1b[0m", row+2, fit(line, t.cols))
+			} else {
+				fmt.Fprintf(t.out, "\x1b[%d;1H%s", row+2, fit(line, t.cols))
+			}
+		}
+		fmt.Fprintf(t.out, "\x1b[1;%dH\x1b[?25h", 3+len(text))
+		t.out.Flush()
+
+		k, err := t.key()
+		if err != nil {
+			return nil, err
+		}
+		switch k {
+		case "enter":
+			if len(matches) == 0 {
+				return nil, nil
+			}
+			return matches[sel], nil
+		case "esc", "ctrl-c":
+			return nil, nil
+		case "up", "\x10": // ^P
+			sel--
+		case "down", "\x0e": // ^N
+			sel++
+		case "pgup":
+			sel -= height
+		case "pgdn":
+			sel += height
+		case "backspace":
+			if len(text) > 0 {
+				text = text[:len(text)-1]
+				sel = 0
+			}
+		case "\x15": // ^U
+			text, sel = nil, 0
+		default:
+			if r, size := utf8.DecodeRuneInString(k); size == len(k) && r >= ' ' {
+				text = append(text, r)
+				sel = 0
+			}
+		}
+	}
+}
+
+func runPick(project string, args []string) {
+	fs := lookupCommand("pick").flags()
+	open := fs.Bool("o", false, "open the chosen issue in the web browser instead of printing its number")
+	refresh := fs.Bool("refresh", false, "list the open issues again instead of using the cached list")
+	parseFlags(fs, args)
+	pattern := strings.Join(fs.Args(), " ")
+
+	items, err := loadPickItems(project, *refresh)
+	if err != nil {
+		fatal(err)
+	}
+
+	// Without a terminal, act as a filter, printing all the matches.
+	if !canPrompt() {
+		matches := pickMatches(items, pattern)
+		for _, p := range matches {
+			fmt.Printf("%d\t%s\n", p.Number, p.Title)
+		}
+		if len(matches) == 0 {
+			exit(exitNoMatch)
+		}
+		return
+	}
+
+	p, err := pickInteractive(items, pattern)
+	if err != nil {
+		fatal(err)
+	}
+	if p == nil {
+		exit(exitNoMatch)
+	}
+	if *open {
+		if err := openBrowser(issueURL(project, p.Number)); err != nil {
+			fatal(err)
+		}
+		return
+	}
+	fmt.Println(p.Number)
+}
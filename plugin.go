@@ -0,0 +1,81 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Like git, issue runs an external command issue-name, found in $PATH,
+// for "issue name" when name is not one of its own commands, so that
+// teams can add commands without changing issue itself.
+
+// pluginNameRE matches the names that can be external commands.
+// It excludes issue numbers and queries like "label:bug".
+var pluginNameRE = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// lookupPlugin returns the path of the external command for name,
+// or "" if there is none.
+func lookupPlugin(name string) string {
+	if !pluginNameRE.MatchString(name) || lookupCommand(name) != nil {
+		return ""
+	}
+	path, err := exec.LookPath("issue-" + name)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// listPlugins returns the names of the external commands in $PATH,
+// for the usage message.
+func listPlugins() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		files, _ := filepath.Glob(filepath.Join(dir, "issue-*"))
+		for _, file := range files {
+			name := strings.TrimPrefix(filepath.Base(file), "issue-")
+			if seen[name] || lookupPlugin(name) != file {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runPlugin runs the external command at path with args,
+// passing it the project and credentials in its environment,
+// and exits with the command's exit status.
+func runPlugin(path, project string, args []string) {
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"ISSUE_PROJECT="+project,
+		"ISSUE_TOKEN="+auth.current(),
+		"ISSUE_API_URL="+client.BaseURL.String())
+	if self, err := os.Executable(); err == nil {
+		cmd.Env = append(cmd.Env, "ISSUE="+self)
+	}
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() >= 0 {
+			exit(exitErr.ExitCode())
+		}
+		fatal(err)
+	}
+	exit(0)
+}
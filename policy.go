@@ -0,0 +1,290 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// A policy is a triage policy from the configuration file:
+// actions to take on the open issues meeting its conditions.
+type policy struct {
+	Name  string `yaml:"name"`
+	Query string `yaml:"query"` // search narrowing the issues considered
+	If    struct {
+		Labels    []string `yaml:"labels"`    // has all these labels
+		NoLabels  []string `yaml:"no-labels"` // has none of these labels
+		Milestone string   `yaml:"milestone"` // in this milestone, or "none"
+		Assigned  *bool    `yaml:"assigned"`  // has or lacks assignees
+		Age       string   `yaml:"age"`       // created at least this long ago
+		Inactive  string   `yaml:"inactive"`  // not updated for at least this long
+	} `yaml:"if"`
+	Then struct {
+		Comment string   `yaml:"comment"`
+		Label   []string `yaml:"label"` // +name adds, -name removes
+		Close   bool     `yaml:"close"`
+		Ping    []string `yaml:"ping"` // author, assignees, @login, or @org/team
+	} `yaml:"then"`
+
+	age, inactive time.Duration
+}
+
+// check validates the policy and parses its durations.
+func (p *policy) check() error {
+	if p.Name == "" {
+		return fmt.Errorf("policy has no name")
+	}
+	var err error
+	if p.age, err = parseAge(p.If.Age); err != nil {
+		return fmt.Errorf("policy %s: age: %v", p.Name, err)
+	}
+	if p.inactive, err = parseAge(p.If.Inactive); err != nil {
+		return fmt.Errorf("policy %s: inactive: %v", p.Name, err)
+	}
+	for _, l := range p.Then.Label {
+		if !strings.HasPrefix(l, "+") && !strings.HasPrefix(l, "-") {
+			return fmt.Errorf("policy %s: label %q must begin with + or -", p.Name, l)
+		}
+	}
+	for _, who := range p.Then.Ping {
+		if who != "author" && who != "assignees" && !strings.HasPrefix(who, "@") {
+			return fmt.Errorf("policy %s: cannot ping %q: want author, assignees, or @login", p.Name, who)
+		}
+	}
+	if p.Then.Comment == "" && len(p.Then.Label) == 0 && !p.Then.Close && len(p.Then.Ping) == 0 {
+		return fmt.Errorf("policy %s has no actions", p.Name)
+	}
+	return nil
+}
+
+// parseAge parses a duration like "36h", "30d", or "2w".
+// The empty string is a zero duration.
+func parseAge(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if strings.HasSuffix(s, suffix) {
+			n, err := strconv.Atoi(strings.TrimSuffix(s, suffix))
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+	return time.ParseDuration(s)
+}
+
+// matches reports whether the issue meets the policy's conditions at time now.
+func (p *policy) matches(issue *github.Issue, now time.Time) bool {
+	have := make(map[string]bool)
+	for _, name := range getLabelNames(issue.Labels) {
+		have[name] = true
+	}
+	for _, name := range p.If.Labels {
+		if !have[name] {
+			return false
+		}
+	}
+	for _, name := range p.If.NoLabels {
+		if have[name] {
+			return false
+		}
+	}
+	switch m := p.If.Milestone; {
+	case m == "":
+	case m == "none":
+		if issue.Milestone != nil {
+			return false
+		}
+	default:
+		if getMilestoneTitle(issue.Milestone) != m {
+			return false
+		}
+	}
+	if p.If.Assigned != nil && *p.If.Assigned != (len(issue.Assignees) > 0) {
+		return false
+	}
+	if p.age > 0 && now.Sub(getTime(issue.CreatedAt)) < p.age {
+		return false
+	}
+	if p.inactive > 0 && now.Sub(getTime(issue.UpdatedAt)) < p.inactive {
+		return false
+	}
+	return true
+}
+
+// apply takes the policy's actions on issue: it posts the comment,
+// mentioning the users to ping, then changes the labels and closes
+// the issue.
+func (p *policy) apply(project string, issue *github.Issue) error {
+	n := getInt(issue.Number)
+	var mentions []string
+	for _, who := range p.Then.Ping {
+		switch who {
+		case "author":
+			mentions = append(mentions, "@"+getUserLogin(issue.User))
+		case "assignees":
+			for _, u := range issue.Assignees {
+				mentions = append(mentions, "@"+getUserLogin(u))
+			}
+		default:
+			mentions = append(mentions, who)
+		}
+	}
+	text := strings.TrimSpace(p.Then.Comment)
+	if len(mentions) > 0 {
+		if text == "" {
+			text = "This issue needs attention."
+		}
+		text = strings.Join(mentions, " ") + ": " + text
+	}
+	change := &hookChange{State: getString(issue.State), Comment: text}
+	for _, l := range p.Then.Label {
+		if strings.HasPrefix(l, "+") {
+			change.AddLabels = append(change.AddLabels, l[1:])
+		} else {
+			change.RemoveLabels = append(change.RemoveLabels, l[1:])
+		}
+	}
+	closed := "closed"
+	if p.Then.Close {
+		change.NewState = &closed
+	}
+	return putIssue(project, n, change, func() ([]string, error) {
+		var did []string
+		if text != "" {
+			if err := postComment(project, n, text); err != nil {
+				return did, err
+			}
+			did = append(did, "saved comment")
+		}
+		for _, l := range change.RemoveLabels {
+			_, err := client.Issues.RemoveLabelForIssue(context.TODO(), projectOwner(project), projectRepo(project), n, l)
+			if err != nil && !isNotFound(err) {
+				return did, err
+			}
+			did = append(did, "removed label "+l)
+		}
+		if add := change.AddLabels; len(add) > 0 {
+			if _, _, err := client.Issues.AddLabelsToIssue(context.TODO(), projectOwner(project), projectRepo(project), n, add); err != nil {
+				return did, err
+			}
+			did = append(did, "added label"+suffix(len(add))+" "+strings.Join(add, " "))
+		}
+		if p.Then.Close {
+			if _, _, err := client.Issues.Edit(context.TODO(), projectOwner(project), projectRepo(project), n, &github.IssueRequest{State: &closed}); err != nil {
+				return did, err
+			}
+			did = append(did, "closed")
+		}
+		return did, nil
+	})
+}
+
+// actions returns a summary of the policy's actions.
+func (p *policy) actions() string {
+	var f []string
+	if p.Then.Comment != "" || len(p.Then.Ping) > 0 {
+		f = append(f, "comment")
+	}
+	for _, who := range p.Then.Ping {
+		f = append(f, "ping:"+who)
+	}
+	f = append(f, p.Then.Label...)
+	if p.Then.Close {
+		f = append(f, "close")
+	}
+	return strings.Join(f, " ")
+}
+
+// isNotFound reports whether err is a GitHub 404 response.
+func isNotFound(err error) bool {
+	e, ok := err.(*github.ErrorResponse)
+	return ok && e.Response != nil && e.Response.StatusCode == 404
+}
+
+func runPolicy(project string, args []string) {
+	fs := lookupCommand("policy").flags()
+	dryRun := fs.Bool("n", false, "print the actions without taking them")
+	parseFlags(fs, args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+	}
+	policies := loadConfig().Policies
+	if len(policies) == 0 {
+		file, _ := configFile()
+		fatalf("no policies configured in %s", file)
+	}
+
+	switch fs.Arg(0) {
+	default:
+		fs.Usage()
+	case "list":
+		for _, p := range policies {
+			fmt.Printf("%s\t%s\n", p.Name, p.actions())
+		}
+		return
+	case "run":
+	}
+
+	// Run the named policies, or all of them.
+	run := policies
+	if names := fs.Args()[1:]; len(names) > 0 {
+		run = nil
+		for _, name := range names {
+			var found *policy
+			for _, p := range policies {
+				if p.Name == name {
+					found = p
+				}
+			}
+			if found == nil {
+				usageErrorf("unknown policy %s", name)
+			}
+			run = append(run, found)
+		}
+	}
+
+	now := time.Now()
+	failed := false
+	for _, p := range run {
+		all, err := searchIssues(project, savedQuery(p.Query))
+		if err != nil {
+			fatal(err)
+		}
+		sort.Slice(all, func(i, j int) bool { return getInt(all[i].Number) < getInt(all[j].Number) })
+		count := 0
+		for _, issue := range all {
+			if !p.matches(issue, now) {
+				continue
+			}
+			count++
+			fmt.Printf("%s: #%d\t%s\n", p.Name, getInt(issue.Number), getString(issue.Title))
+			if *dryRun {
+				continue
+			}
+			if err := p.apply(project, issue); err != nil {
+				fmt.Printf("\terror: %v\n", err)
+				failed = true
+			}
+		}
+		verb := "acted on"
+		if *dryRun {
+			verb = "would act on"
+		}
+		fmt.Printf("%s: %s %d issue%s: %s\n", p.Name, verb, count, suffix(count), p.actions())
+	}
+	if failed {
+		exit(exitError)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// A progressBar draws a live progress bar on a terminal
+// while a long bulk operation runs.
+type progressBar struct {
+	mu    sync.Mutex
+	w     io.Writer
+	what  string // description, like "updating"
+	done  int
+	total int
+	drawn bool // the bar is on the current line of w
+}
+
+func newProgressBar(w io.Writer, what string) *progressBar {
+	return &progressBar{w: w, what: what}
+}
+
+// update redraws the bar to show done of total steps complete.
+func (b *progressBar) update(done, total int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.done, b.total = done, total
+	b.draw()
+}
+
+func (b *progressBar) draw() {
+	const width = 40
+	n := width
+	if b.total > 0 {
+		n = width * b.done / b.total
+	}
+	fmt.Fprintf(b.w, "\r%s [%s%s] %d/%d", b.what, strings.Repeat("=", n), strings.Repeat(" ", width-n), b.done, b.total)
+	b.drawn = true
+}
+
+// clear erases the bar. It must be called with b.mu locked.
+func (b *progressBar) clear() {
+	if b.drawn {
+		fmt.Fprintf(b.w, "\r\x1b[K")
+		b.drawn = false
+	}
+}
+
+// log logs a message, printing it above the bar.
+func (b *progressBar) log(s string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	redraw := b.drawn
+	b.clear()
+	log.Print(s)
+	if redraw {
+		b.draw()
+	}
+}
+
+// finish erases the bar once the operation is over.
+func (b *progressBar) finish() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clear()
+}
+
+// progressPages is the number of pages a fetch must take
+// before a pageProgress begins to show its progress.
+const progressPages = 3
+
+// A pageProgress shows the progress of a long paginated fetch
+// on standard error, so that issue does not appear to hang.
+type pageProgress struct {
+	what  string // description, like "comments on #1234"
+	pages int
+	items int
+	shown bool
+}
+
+func newPageProgress(what string) *pageProgress {
+	return &pageProgress{what: what}
+}
+
+// add records a fetched page holding the given number of items,
+// along with the API response that returned it.
+func (p *pageProgress) add(items int, resp *github.Response) {
+	p.pages++
+	p.items += items
+	if p.pages < progressPages || !isTerminal(os.Stderr) || *serveFlag != "" || *stdioFlag || *tuiFlag {
+		return
+	}
+	remaining := ""
+	if resp != nil && resp.Rate.Limit > 0 {
+		remaining = fmt.Sprintf(", %d API requests remaining", resp.Rate.Remaining)
+	}
+	fmt.Fprintf(os.Stderr, "\rissue: fetching %s: %d pages, %d items%s\x1b[K", p.what, p.pages, p.items, remaining)
+	p.shown = true
+}
+
+// done erases the progress line, if it was shown.
+func (p *pageProgress) done() {
+	if p.shown {
+		fmt.Fprintf(os.Stderr, "\r\x1b[K")
+		p.shown = false
+	}
+}
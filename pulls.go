@@ -0,0 +1,184 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// A linkedPull is a pull request that declares it fixes an issue.
+type linkedPull struct {
+	Number int
+	Title  string
+	State  string // open, closed, merged, or draft
+	Review string // approved, changes requested, review required
+	Checks string // success, pending, failure, or none
+}
+
+// fixesRE matches the closing keywords GitHub recognizes in pull request text.
+var fixesRE = regexp.MustCompile(`(?i)\b(?:close[sd]?|fix(?:e[sd])?|resolve[sd]?)\s*:?\s+(?:([A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+))?#([0-9]+)\b`)
+
+// pullFixes reports whether the pull request body declares that it fixes
+// issue n in project.
+func pullFixes(body, project string, n int) bool {
+	for _, m := range fixesRE.FindAllStringSubmatch(body, -1) {
+		if m[1] != "" && !strings.EqualFold(m[1], project) {
+			continue
+		}
+		if m[2] == fmt.Sprint(n) {
+			return true
+		}
+	}
+	return false
+}
+
+// findLinkedPulls returns the pull requests in project that declare
+// that they fix issue n, along with their review and check status.
+func findLinkedPulls(project string, n int) ([]*linkedPull, error) {
+	var found []*linkedPull
+	for page := 1; ; {
+		x, resp, err := client.Search.Issues(context.TODO(), fmt.Sprintf("type:pr repo:%s %d in:body", project, n), &github.SearchOptions{
+			ListOptions: github.ListOptions{
+				Page:    page,
+				PerPage: 100,
+			},
+		})
+		if err != nil {
+			return found, err
+		}
+		for _, issue := range x.Issues {
+			if !pullFixes(getString(issue.Body), project, n) {
+				continue
+			}
+			p, err := loadLinkedPull(project, getInt(issue.Number))
+			if err != nil {
+				return found, err
+			}
+			found = append(found, p)
+		}
+		if resp.NextPage < page {
+			break
+		}
+		page = resp.NextPage
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].Number < found[j].Number })
+	return found, nil
+}
+
+func loadLinkedPull(project string, n int) (*linkedPull, error) {
+	owner, repo := projectOwner(project), projectRepo(project)
+	pr, _, err := client.PullRequests.Get(context.TODO(), owner, repo, n)
+	if err != nil {
+		return nil, err
+	}
+	p := &linkedPull{
+		Number: n,
+		Title:  getString(pr.Title),
+		State:  getString(pr.State),
+		Review: "review required",
+		Checks: "none",
+	}
+	switch {
+	case pr.GetMerged():
+		p.State = "merged"
+	case pr.GetDraft() && p.State == "open":
+		p.State = "draft"
+	}
+
+	// Only each reviewer's most recent decisive review counts.
+	reviews, _, err := client.PullRequests.ListReviews(context.TODO(), owner, repo, n, &github.ListOptions{PerPage: 100})
+	if err != nil {
+		return nil, err
+	}
+	latest := make(map[string]string)
+	for _, r := range reviews {
+		switch state := getString(r.State); state {
+		case "APPROVED", "CHANGES_REQUESTED", "DISMISSED":
+			latest[getUserLogin(r.User)] = state
+		}
+	}
+	for _, state := range latest {
+		if state == "CHANGES_REQUESTED" {
+			p.Review = "changes requested"
+			break
+		}
+		if state == "APPROVED" {
+			p.Review = "approved"
+		}
+	}
+
+	sha := pr.GetHead().GetSHA()
+	if sha == "" {
+		return p, nil
+	}
+	var states []string
+	status, _, err := client.Repositories.GetCombinedStatus(context.TODO(), owner, repo, sha, nil)
+	if err != nil {
+		return nil, err
+	}
+	if status.GetTotalCount() > 0 {
+		states = append(states, status.GetState())
+	}
+	runs, _, err := client.Checks.ListCheckRunsForRef(context.TODO(), owner, repo, sha, &github.ListCheckRunsOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, run := range runs.CheckRuns {
+		if run.GetStatus() != "completed" {
+			states = append(states, "pending")
+			continue
+		}
+		switch run.GetConclusion() {
+		case "success", "neutral", "skipped":
+			states = append(states, "success")
+		default:
+			states = append(states, "failure")
+		}
+	}
+	p.Checks = combineChecks(states)
+	return p, nil
+}
+
+// combineChecks reduces a list of check states to a single state
+// the way GitHub's combined status does: any failure fails the whole,
+// and otherwise any pending check leaves the whole pending.
+func combineChecks(states []string) string {
+	if len(states) == 0 {
+		return "none"
+	}
+	combined := "success"
+	for _, s := range states {
+		switch s {
+		case "failure", "error":
+			return "failure"
+		case "pending":
+			combined = "pending"
+		}
+	}
+	return combined
+}
+
+// printLinkedPulls prints a PR: header line for each pull request
+// that declares it fixes the issue.
+// The lines are informational only, so a failed lookup is reported
+// in the header rather than failing the whole issue display.
+func printLinkedPulls(w io.Writer, project string, issue *github.Issue) {
+	pulls, err := findLinkedPulls(project, getInt(issue.Number))
+	for _, p := range pulls {
+		fmt.Fprintf(w, "PR: #%d %s, %s, checks %s\n", p.Number, p.State, p.Review, p.Checks)
+	}
+	if err != nil {
+		fmt.Fprintf(w, "PR: error finding pull requests: %v\n", err)
+	}
+}
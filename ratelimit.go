@@ -0,0 +1,344 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// The rate limits apply to the token, which may also be used by
+// other programs, so issue keeps its own count of the requests it
+// makes in each rate limit window, shared by all its invocations.
+
+// A windowUsage is the number of requests issue has made
+// in one rate limit window for a resource.
+type windowUsage struct {
+	Reset int64 // end of the window, in Unix seconds
+	Used  int
+}
+
+var apiUsage struct {
+	sync.Mutex
+	loaded  bool
+	m       map[string]*windowUsage // by resource: core, search, graphql
+	unsaved map[string]*windowUsage // requests counted in m but not saved
+	saved   time.Time               // when the counts were last saved
+}
+
+// usageFile returns the name of the file holding the request counts.
+func usageFile() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "issue", "ratelimit.json"), nil
+}
+
+// loadUsage returns the saved request counts, by resource.
+// It must be called with apiUsage locked.
+func loadUsage() map[string]*windowUsage {
+	if !apiUsage.loaded {
+		apiUsage.loaded = true
+		apiUsage.m = make(map[string]*windowUsage)
+		if file, err := usageFile(); err == nil {
+			if data, err := ioutil.ReadFile(file); err == nil {
+				json.Unmarshal(data, &apiUsage.m)
+			}
+		}
+	}
+	return apiUsage.m
+}
+
+// saveUsage adds the requests counted by this run since the last save
+// to the counts in the file, which other invocations may have updated
+// meanwhile, and writes the result back.
+func saveUsage() {
+	apiUsage.Lock()
+	defer apiUsage.Unlock()
+	saveUsageLocked()
+}
+
+func saveUsageLocked() {
+	apiUsage.saved = time.Now()
+	if len(apiUsage.unsaved) == 0 {
+		return
+	}
+	file, err := usageFile()
+	if err != nil {
+		return
+	}
+	m := make(map[string]*windowUsage)
+	if data, err := ioutil.ReadFile(file); err == nil {
+		json.Unmarshal(data, &m)
+	}
+	for resource, d := range apiUsage.unsaved {
+		switch u := m[resource]; {
+		case u == nil || u.Reset < d.Reset:
+			m[resource] = &windowUsage{Reset: d.Reset, Used: d.Used}
+		case u.Reset == d.Reset:
+			u.Used += d.Used
+		}
+	}
+	apiUsage.m, apiUsage.loaded, apiUsage.unsaved = m, true, nil
+
+	// Replace the file in one step, so that
+	// other invocations never read half of it.
+	data, err := json.Marshal(m)
+	if err != nil {
+		return
+	}
+	os.MkdirAll(filepath.Dir(file), 0700)
+	f, err := ioutil.TempFile(filepath.Dir(file), "ratelimit-*.json")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), file)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+}
+
+// The -max-requests flag sets a budget for the requests made by
+// a single run of issue, protecting tokens shared by an organization
+// from runaway bulk operations.
+var apiRun struct {
+	sync.Mutex
+	total   int
+	limit   int            // requests allowed before stopping or asking
+	counts  map[string]int // by category: read, write, search, graphql
+	ops     int            // operations begun and not ended
+	stopped bool           // the budget ran out and was not raised
+}
+
+// requestCategory returns the budget category of a GitHub API request,
+// or "" if r is not a GitHub API request.
+func requestCategory(r *http.Request) string {
+	if client == nil || r.URL.Host != client.BaseURL.Host && r.URL.Host != client.UploadURL.Host {
+		return ""
+	}
+	path := strings.TrimPrefix(r.URL.Path, client.BaseURL.Path)
+	switch {
+	case strings.HasPrefix(path, "search/"):
+		return "search"
+	case path == "graphql" || r.URL.Path == "/api/graphql": // github.com or Enterprise Server
+		return "graphql"
+	case r.Method == "GET" || r.Method == "HEAD":
+		return "read"
+	}
+	return "write"
+}
+
+// spendRequest counts a request in the given category against
+// the -max-requests budget. When the budget is used up, spendRequest
+// asks on standard error whether to allow as many requests again,
+// or, if it cannot ask, returns an error. Requests made during an
+// operation, between beginOperation and endOperation, are never
+// stopped, so that the operation is not left half done.
+func spendRequest(category string) error {
+	apiRun.Lock()
+	defer apiRun.Unlock()
+	if apiRun.ops == 0 {
+		if err := allowRequests(1); err != nil {
+			return err
+		}
+	}
+	apiRun.total++
+	apiRun.counts[category]++
+	return nil
+}
+
+// beginOperation checks that the -max-requests budget allows n more
+// requests, asking as spendRequest does if not, before an operation
+// that must not be stopped partway, such as the updates of an issue.
+// If it returns nil, the caller must call endOperation when done.
+func beginOperation(n int) error {
+	apiRun.Lock()
+	defer apiRun.Unlock()
+	if err := allowRequests(n); err != nil {
+		return err
+	}
+	apiRun.ops++
+	return nil
+}
+
+// endOperation ends an operation begun by beginOperation.
+func endOperation() {
+	apiRun.Lock()
+	defer apiRun.Unlock()
+	apiRun.ops--
+}
+
+// budgetStopped reports whether the -max-requests budget has run out
+// and will not be raised, so that no more operations should begin.
+func budgetStopped() bool {
+	apiRun.Lock()
+	defer apiRun.Unlock()
+	return apiRun.stopped
+}
+
+// allowRequests returns an error if the budget does not allow
+// n more requests and the user does not agree to raise it.
+// It must be called with apiRun locked.
+func allowRequests(n int) error {
+	if apiRun.counts == nil {
+		apiRun.counts = make(map[string]int)
+		apiRun.limit = *maxRequests
+	}
+	if *maxRequests <= 0 {
+		return nil
+	}
+	for !apiRun.stopped && apiRun.total+n > apiRun.limit {
+		if !canPrompt() {
+			apiRun.stopped = true
+			break
+		}
+		fmt.Fprintf(os.Stderr, "issue: made %s; allow %d more? [y/N] ", requestSummary(), *maxRequests)
+		line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if ans := strings.ToLower(strings.TrimSpace(line)); ans != "y" && ans != "yes" {
+			apiRun.stopped = true
+			break
+		}
+		apiRun.limit += *maxRequests
+	}
+	if apiRun.stopped {
+		return fmt.Errorf("stopping after %d API requests (-max-requests)", apiRun.total)
+	}
+	return nil
+}
+
+// requestSummary returns a description of the API requests made so far,
+// like "12 API requests (8 read, 2 search, 2 write)".
+// It must be called with apiRun locked.
+func requestSummary() string {
+	var f []string
+	for c, n := range apiRun.counts {
+		f = append(f, fmt.Sprintf("%d %s", n, c))
+	}
+	sort.Strings(f)
+	s := fmt.Sprintf("%d API request%s", apiRun.total, suffix(apiRun.total))
+	if len(f) > 0 {
+		s += " (" + strings.Join(f, ", ") + ")"
+	}
+	return s
+}
+
+// printRequestSummary prints the API requests made by this run
+// on standard error, if -max-requests is set.
+func printRequestSummary() {
+	if *maxRequests <= 0 {
+		return
+	}
+	apiRun.Lock()
+	defer apiRun.Unlock()
+	fmt.Fprintf(os.Stderr, "issue: made %s\n", requestSummary())
+}
+
+// A usageTransport enforces the -max-requests budget and counts
+// the GitHub API requests made through it against the rate limit
+// window that GitHub reports for each.
+type usageTransport struct {
+	transport http.RoundTripper
+}
+
+func newUsageTransport(t http.RoundTripper) http.RoundTripper {
+	return &usageTransport{transport: t}
+}
+
+func (t *usageTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if c := requestCategory(r); c != "" {
+		if err := spendRequest(c); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := t.transport.RoundTrip(r)
+	if err != nil {
+		return resp, err
+	}
+	resource := resp.Header.Get("X-RateLimit-Resource")
+	reset, _ := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if resource == "" || reset == 0 || r.URL.Path == "/rate_limit" {
+		return resp, err
+	}
+
+	apiUsage.Lock()
+	defer apiUsage.Unlock()
+	if apiUsage.unsaved == nil {
+		apiUsage.unsaved = make(map[string]*windowUsage)
+	}
+	if apiUsage.saved.IsZero() {
+		apiUsage.saved = time.Now()
+	}
+	for _, m := range []map[string]*windowUsage{loadUsage(), apiUsage.unsaved} {
+		u := m[resource]
+		if u == nil || u.Reset != reset {
+			u = &windowUsage{Reset: reset}
+			m[resource] = u
+		}
+		u.Used++
+	}
+	// The counts are saved when issue exits, and, for the
+	// modes that run for a long time, once a minute.
+	if time.Since(apiUsage.saved) > time.Minute {
+		saveUsageLocked()
+	}
+	return resp, err
+}
+
+func runRateLimit(project string, args []string) {
+	fs := lookupCommand("ratelimit").flags()
+	parseFlags(fs, args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+	}
+	limits, _, err := client.RateLimits(context.TODO())
+	if err != nil {
+		fatal(err)
+	}
+
+	apiUsage.Lock()
+	m := loadUsage()
+	apiUsage.Unlock()
+	now := time.Now()
+	fmt.Printf("resource\tremaining\treset\tused by issue\n")
+	for _, x := range []struct {
+		name string
+		rate *github.Rate
+	}{
+		{"core", limits.Core},
+		{"search", limits.Search},
+		{"graphql", limits.GraphQL},
+	} {
+		if x.rate == nil {
+			continue
+		}
+		used := 0
+		if u := m[x.name]; u != nil && u.Reset == x.rate.Reset.Unix() {
+			used = u.Used
+		}
+		reset := x.rate.Reset.Time
+		fmt.Printf("%s\t%d/%d\t%s (in %v)\t%d\n", x.name, x.rate.Remaining, x.rate.Limit,
+			reset.Local().Format("15:04:05"), reset.Sub(now).Round(time.Second), used)
+	}
+}
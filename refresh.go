@@ -0,0 +1,240 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// Fine-grained personal access tokens and GitHub App tokens expire.
+// If the configuration gives a token-command or a github-app, issue
+// replaces an expired token and retries the request that failed.
+
+// A tokenSource holds the GitHub token used for API requests.
+// The token can be read at any time, without waiting for a refresh,
+// so that requests made while refreshing, like those that mint an
+// app token, can use it too.
+type tokenSource struct {
+	mu      sync.Mutex   // held while checking or refreshing the token
+	token   atomic.Value // string
+	expires time.Time    // zero if unknown
+}
+
+// canRefreshToken reports whether the configuration
+// says how to get a new token.
+func canRefreshToken() bool {
+	c := loadConfig()
+	return c.TokenCommand != "" || c.GitHubApp.ID != 0
+}
+
+// Token returns the current token, first replacing it
+// if it is known to be about to expire.
+func (s *tokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.expires.IsZero() && time.Until(s.expires) < time.Minute && canRefreshToken() {
+		if err := s.refreshLocked(); err != nil {
+			return nil, err
+		}
+	}
+	return &oauth2.Token{AccessToken: s.current()}, nil
+}
+
+// current returns the current token.
+func (s *tokenSource) current() string {
+	token, _ := s.token.Load().(string)
+	return token
+}
+
+// set replaces the current token.
+func (s *tokenSource) set(token string) {
+	s.token.Store(token)
+}
+
+// refresh replaces the token, unless it has already
+// been replaced since a request using stale failed.
+func (s *tokenSource) refresh(stale string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current() != stale {
+		return nil
+	}
+	return s.refreshLocked()
+}
+
+func (s *tokenSource) refreshLocked() error {
+	c := loadConfig()
+	var token string
+	var expires time.Time
+	var err error
+	switch {
+	case c.TokenCommand != "":
+		token, err = runTokenCommand(c.TokenCommand)
+	case c.GitHubApp.ID != 0:
+		token, expires, err = mintAppToken(c.GitHubApp.ID, c.GitHubApp.Installation, c.GitHubApp.KeyFile)
+	default:
+		return errors.New("token expired; set token-command or github-app in the configuration to refresh it")
+	}
+	if err != nil {
+		return fmt.Errorf("refreshing token: %v", err)
+	}
+	s.set(token)
+	s.expires = expires
+	return nil
+}
+
+// runTokenCommand runs the token-command and returns the token it prints.
+func runTokenCommand(command string) (string, error) {
+	cmd := userCommand(command)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("token-command: %v", err)
+	}
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", errors.New("token-command printed no token")
+	}
+	return token, nil
+}
+
+// mintAppToken returns a new installation access token for the
+// GitHub App, authenticating as the app with a JSON Web Token
+// signed by its private key, along with the token's expiration time.
+func mintAppToken(appID, installation int64, keyFile string) (string, time.Time, error) {
+	data, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return "", time.Time{}, fmt.Errorf("%s: no PEM private key", keyFile)
+	}
+	var key *rsa.PrivateKey
+	if key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+		k, err8 := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if key, _ = k.(*rsa.PrivateKey); err8 != nil || key == nil {
+			return "", time.Time{}, fmt.Errorf("%s: %v", keyFile, err)
+		}
+	}
+
+	// The JWT is valid for ten minutes at most; backdate it
+	// a minute to allow for clock skew.
+	now := time.Now()
+	enc := base64.RawURLEncoding
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": appID,
+	})
+	signed := enc.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`)) + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	jwt := signed + "." + enc.EncodeToString(sig)
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("%sapp/installations/%d/access_tokens", client.BaseURL, installation), nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return "", time.Time{}, fmt.Errorf("minting app token: %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	var t struct {
+		Token     string
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.Unmarshal(body, &t); err != nil {
+		return "", time.Time{}, fmt.Errorf("minting app token: %v", err)
+	}
+	return t.Token, t.ExpiresAt, nil
+}
+
+// A refreshTransport sends API requests with the token from source.
+// If GitHub rejects the token as expired, the transport refreshes it
+// and sends the request again, so that the caller sees no failure.
+type refreshTransport struct {
+	source *tokenSource
+	base   *oauth2.Transport
+}
+
+func newRefreshTransport(source *tokenSource) *refreshTransport {
+	return &refreshTransport{source, &oauth2.Transport{Source: source}}
+}
+
+func (t *refreshTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	stale := t.source.current()
+	resp, err := t.base.RoundTrip(r)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || !canRefreshToken() || !tokenExpired(resp) {
+		return resp, err
+	}
+	if err := t.source.refresh(stale); err != nil {
+		log.Print(err)
+		return resp, nil
+	}
+	if r.Body != nil && r.GetBody == nil {
+		// The request cannot be sent again,
+		// but later ones will use the new token.
+		return resp, nil
+	}
+	retry := r.Clone(r.Context())
+	if r.GetBody != nil {
+		if retry.Body, err = r.GetBody(); err != nil {
+			return resp, nil
+		}
+	}
+	resp.Body.Close()
+	return t.base.RoundTrip(retry)
+}
+
+// tokenExpired reports whether the 401 response resp rejected an expired
+// token: GitHub gives the expiration time of fine-grained and app tokens
+// in a header, and rejects tokens it no longer accepts as "Bad credentials".
+func tokenExpired(resp *http.Response) bool {
+	if exp := resp.Header.Get("GitHub-Authentication-Token-Expiration"); exp != "" {
+		for _, layout := range []string{"2006-01-02 15:04:05 MST", "2006-01-02 15:04:05 -0700"} {
+			if t, err := time.Parse(layout, exp); err == nil && !t.After(time.Now()) {
+				return true
+			}
+		}
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+	return err == nil && bytes.Contains(data, []byte("Bad credentials"))
+}
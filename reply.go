@@ -0,0 +1,92 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A reply to a comment, started by issue -e -reply-to or by the acme
+// Reply command, is an ordinary new comment whose text begins with
+// a link to the comment being answered and a quotation of it.
+
+// replyDraft returns the start of a reply to the comment with the
+// given ID on issue n: a line linking to the comment's permalink,
+// followed by the comment's text, quoted.
+func replyDraft(project string, n int, id int64) (string, error) {
+	com, _, err := client.Issues.GetComment(context.TODO(), projectOwner(project), projectRepo(project), id)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasSuffix(com.GetIssueURL(), fmt.Sprintf("/issues/%d", n)) {
+		return "", fmt.Errorf("comment %d is not on issue #%d", id, n)
+	}
+	url := com.GetHTMLURL()
+	if url == "" {
+		url = fmt.Sprintf("%s#issuecomment-%d", issueURL(project, n), id)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "In reply to [@%s's comment](%s):\n\n", getUserLogin(com.User), url)
+	body := strings.Replace(strings.TrimSpace(com.GetBody()), "\r\n", "\n", -1)
+	for _, line := range strings.Split(body, "\n") {
+		if line == "" {
+			b.WriteString(">\n")
+			continue
+		}
+		b.WriteString("> " + line + "\n")
+	}
+	b.WriteString("\n")
+	return b.String(), nil
+}
+
+// insertReply returns the text of an issue window with the reply draft
+// placed where a new comment is written, just before the issue's report.
+func insertReply(text []byte, draft string) []byte {
+	i := bytes.Index(text, []byte("\nReported by "))
+	if i < 0 {
+		return append(text, draft...)
+	}
+	var b bytes.Buffer
+	b.Write(text[:i+1])
+	b.WriteString(draft)
+	b.WriteString("\n")
+	b.Write(text[i+1:])
+	return b.Bytes()
+}
+
+// isBareReply reports whether comment is a reply draft with nothing
+// added to it, which is not worth posting.
+func isBareReply(comment string) bool {
+	lines := strings.Split(comment, "\n")
+	if !strings.HasPrefix(lines[0], "In reply to [@") || !strings.HasSuffix(lines[0], "):") {
+		return false
+	}
+	for _, line := range lines[1:] {
+		if line != "" && !strings.HasPrefix(line, ">") {
+			return false
+		}
+	}
+	return true
+}
+
+// commentIDRE matches the ID in a comment header line or permalink.
+var commentIDRE = regexp.MustCompile(`^Comment by .* \[([0-9]+)\]|#issuecomment-([0-9]+)`)
+
+// parseCommentID returns the comment ID in text, which is a comment
+// header line, as printed by commentHeader, a permalink, or the ID itself.
+func parseCommentID(text string) (int64, bool) {
+	text = strings.TrimSpace(text)
+	if m := commentIDRE.FindStringSubmatch(text); m != nil {
+		text = m[1] + m[2]
+	}
+	text = strings.Trim(text, "[]")
+	id, err := strconv.ParseInt(text, 10, 64)
+	return id, err == nil && id > 0
+}
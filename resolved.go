@@ -0,0 +1,235 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// The resolved-by command answers "which release has the fix?"
+// Finding the tags that contain a commit is cheap in a local clone
+// and expensive through the API, so resolved-by uses the clone in the
+// current directory when it has the commit, and otherwise compares
+// the commit with the project's GitHub releases.
+
+// closingCommit returns the commit that closed issue n, along with the
+// closed event, or an empty commit if the issue was closed by hand.
+func closingCommit(project string, n int) (string, *github.IssueEvent, error) {
+	var closed *github.IssueEvent
+	for page := 1; ; {
+		list, resp, err := client.Issues.ListIssueEvents(context.TODO(), projectOwner(project), projectRepo(project), n, &github.ListOptions{
+			Page:    page,
+			PerPage: 100,
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		for _, ev := range list {
+			switch getString(ev.Event) {
+			case "closed":
+				closed = ev
+			case "reopened":
+				closed = nil
+			}
+		}
+		if resp.NextPage < page {
+			break
+		}
+		page = resp.NextPage
+	}
+	if closed == nil {
+		return "", nil, fmt.Errorf("#%d is not closed", n)
+	}
+	return getString(closed.CommitID), closed, nil
+}
+
+// haveLocalCommit reports whether the git repository
+// in the current directory has the commit.
+func haveLocalCommit(sha string) bool {
+	return exec.Command("git", "cat-file", "-e", sha+"^{commit}").Run() == nil
+}
+
+// firstRelease returns the earliest release tag containing the commit,
+// or "" if no release contains it yet.
+func firstRelease(project, sha string) (string, error) {
+	if haveLocalCommit(sha) {
+		out, err := exec.Command("git", "tag", "--contains", sha, "--sort=creatordate").Output()
+		if err != nil {
+			return "", fmt.Errorf("git tag: %v", err)
+		}
+		tags := strings.Fields(string(out))
+		if len(tags) == 0 {
+			return "", nil
+		}
+		return tags[0], nil
+	}
+
+	commit, _, err := client.Git.GetCommit(context.TODO(), projectOwner(project), projectRepo(project), sha)
+	if err != nil {
+		return "", err
+	}
+	date := commit.GetCommitter().GetDate()
+	var releases []*github.RepositoryRelease
+	for page := 1; ; {
+		list, resp, err := client.Repositories.ListReleases(context.TODO(), projectOwner(project), projectRepo(project), &github.ListOptions{
+			Page:    page,
+			PerPage: 100,
+		})
+		if err != nil {
+			return "", err
+		}
+		for _, r := range list {
+			if !r.GetDraft() && r.GetCreatedAt().After(date) {
+				releases = append(releases, r)
+			}
+		}
+		if resp.NextPage < page {
+			break
+		}
+		page = resp.NextPage
+	}
+	sort.Slice(releases, func(i, j int) bool {
+		return releases[i].GetCreatedAt().Before(releases[j].GetCreatedAt().Time)
+	})
+	for _, r := range releases {
+		cmp, _, err := client.Repositories.CompareCommits(context.TODO(), projectOwner(project), projectRepo(project), sha, r.GetTagName(), nil)
+		if err != nil {
+			return "", err
+		}
+		if s := cmp.GetStatus(); s == "ahead" || s == "identical" {
+			return r.GetTagName(), nil
+		}
+	}
+	return "", nil
+}
+
+// cherryPicks returns the commits whose messages say they were
+// cherry-picked from the commit, as git cherry-pick -x records.
+func cherryPicks(project, sha string) ([]string, error) {
+	grep := "cherry picked from commit " + sha
+	if haveLocalCommit(sha) {
+		out, err := exec.Command("git", "log", "--all", "--format=%H", "--fixed-strings", "--grep="+grep).Output()
+		if err != nil {
+			return nil, fmt.Errorf("git log: %v", err)
+		}
+		return strings.Fields(string(out)), nil
+	}
+	x, _, err := client.Search.Commits(context.TODO(), fmt.Sprintf("repo:%s %q", project, grep), nil)
+	if err != nil {
+		return nil, err
+	}
+	var list []string
+	for _, c := range x.Commits {
+		if strings.Contains(c.GetCommit().GetMessage(), grep) {
+			list = append(list, c.GetSHA())
+		}
+	}
+	return list, nil
+}
+
+// backportIssues returns the backport issues for issue n: those with
+// "backport" in their titles that refer to #n, like the issues that
+// golang/go creates for each release branch.
+func backportIssues(project string, n int) ([]*github.Issue, error) {
+	x, _, err := client.Search.Issues(context.TODO(), fmt.Sprintf("repo:%s backport %d in:title,body", project, n), &github.SearchOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	})
+	if err != nil {
+		return nil, err
+	}
+	ref := regexp.MustCompile(fmt.Sprintf(`#%d\b`, n))
+	var list []*github.Issue
+	for _, issue := range x.Issues {
+		if getInt(issue.Number) != n && strings.Contains(strings.ToLower(getString(issue.Title)), "backport") && ref.MatchString(getString(issue.Body)) {
+			list = append(list, issue)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return getInt(list[i].Number) < getInt(list[j].Number) })
+	return list, nil
+}
+
+// describeCommit returns a description of the commit: its abbreviated
+// hash and subject, the pull requests that merged it, and the first
+// release containing it.
+func describeCommit(project, sha string) string {
+	s := "commit " + sha
+	if len(sha) > 7 {
+		s = "commit " + sha[:7]
+	}
+	if commit, _, err := client.Git.GetCommit(context.TODO(), projectOwner(project), projectRepo(project), sha); err == nil {
+		subject, _, _ := strings.Cut(commit.GetMessage(), "\n")
+		s += " (" + subject + ")"
+	}
+	if pulls, _, err := client.PullRequests.ListPullRequestsWithCommit(context.TODO(), projectOwner(project), projectRepo(project), sha, nil); err == nil {
+		for _, p := range pulls {
+			if p.MergedAt != nil {
+				s += fmt.Sprintf(" in PR #%d", p.GetNumber())
+			}
+		}
+	}
+	tag, err := firstRelease(project, sha)
+	switch {
+	case err != nil:
+		s += fmt.Sprintf(", release unknown: %v", err)
+	case tag == "":
+		s += ", not yet released"
+	default:
+		s += ", first released in " + tag
+	}
+	return s
+}
+
+func runResolvedBy(project string, args []string) {
+	fs := lookupCommand("resolved-by").flags()
+	parseFlags(fs, args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+	}
+	n := issueArgs(fs, fs.Args())[0]
+	sha, closed, err := closingCommit(project, n)
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Printf("#%d closed by %s (%s)\n", n, getUserLogin(closed.Actor), formatTime(getTime(closed.CreatedAt)))
+	shown := make(map[string]bool) // commits already described
+	if sha == "" {
+		fmt.Printf("Fix: none; the issue was closed without a commit\n")
+	} else {
+		fmt.Printf("Fix: %s\n", describeCommit(project, sha))
+		picks, err := cherryPicks(project, sha)
+		if err != nil {
+			fmt.Printf("Backport: error finding cherry-picks: %v\n", err)
+		}
+		for _, pick := range picks {
+			shown[pick] = true
+			fmt.Printf("Backport: %s\n", describeCommit(project, pick))
+		}
+	}
+
+	backports, err := backportIssues(project, n)
+	if err != nil {
+		fmt.Printf("Backport: error finding backport issues: %v\n", err)
+	}
+	for _, b := range backports {
+		line := fmt.Sprintf("#%d %s, %s", getInt(b.Number), getString(b.Title), getString(b.State))
+		if getString(b.State) == "closed" {
+			switch sha, _, err := closingCommit(project, getInt(b.Number)); {
+			case err != nil || sha == "":
+			case shown[sha]:
+				line += ", fixed by commit " + sha[:7]
+			default:
+				line += ", fixed by " + describeCommit(project, sha)
+			}
+		}
+		fmt.Printf("Backport: %s\n", line)
+	}
+}
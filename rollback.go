@@ -0,0 +1,142 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// With -atomic, a bulk edit records the state of each issue before
+// updating it, and if any update fails, it restores the issues it
+// already changed, so that the edit applies to all issues or none.
+
+// An issueState is the state of an issue before a bulk edit changed it.
+type issueState struct {
+	number      int
+	state       string
+	stateReason string
+	assignees   []string
+	labels      []string
+	milestone   *int
+	typ         string
+	updated     time.Time // when the issue was last updated
+	comment     int64     // ID of the comment the edit posted, or 0
+}
+
+// saveIssueState fetches the current state of issue n.
+func saveIssueState(project string, n int) (*issueState, error) {
+	var x struct {
+		github.Issue
+		issueExtra
+	}
+	_, err := getJSON(fmt.Sprintf("repos/%s/%s/issues/%d", projectOwner(project), projectRepo(project), n), &x)
+	if err != nil {
+		return nil, err
+	}
+	s := &issueState{
+		number:      n,
+		state:       getString(x.State),
+		stateReason: x.StateReason,
+		assignees:   []string{},
+		labels:      getLabelNames(x.Labels),
+		typ:         x.typeName(),
+		updated:     getTime(x.UpdatedAt),
+	}
+	for _, u := range x.Assignees {
+		s.assignees = append(s.assignees, getUserLogin(u))
+	}
+	if s.labels == nil {
+		s.labels = []string{}
+	}
+	if x.Milestone != nil {
+		s.milestone = x.Milestone.Number
+	}
+	return s, nil
+}
+
+// restore undoes a bulk edit of the issue: it deletes the comment
+// the edit posted, if any, and then restores the state, assignees,
+// labels, milestone, and, if withType is set, the type.
+// Comments posted by anyone else, including the user in other
+// sessions, are left alone.
+func (s *issueState) restore(project string, withType bool) error {
+	if s.comment != 0 {
+		resp, err := client.Issues.DeleteComment(context.TODO(), projectOwner(project), projectRepo(project), s.comment)
+		if err != nil && (resp == nil || resp.StatusCode != http.StatusNotFound) {
+			return err
+		}
+		s.comment = 0
+	}
+
+	// The github package cannot clear a milestone or type,
+	// which needs an explicit null, so make the request directly.
+	edit := map[string]interface{}{
+		"state":     s.state,
+		"assignees": s.assignees,
+		"labels":    s.labels,
+		"milestone": s.milestone,
+	}
+	if s.state == "closed" && s.stateReason != "" {
+		edit["state_reason"] = s.stateReason
+	}
+	if withType {
+		var typ interface{}
+		if s.typ != "" {
+			typ = s.typ
+		}
+		edit["type"] = typ
+	}
+	req, err := client.NewRequest("PATCH", fmt.Sprintf("repos/%s/%s/issues/%d", projectOwner(project), projectRepo(project), s.number), edit)
+	if err != nil {
+		return err
+	}
+	if _, err := client.Do(context.TODO(), req, nil); err != nil {
+		return err
+	}
+	if withType {
+		cacheIssueType(project, s.number, s.typ)
+	}
+	return nil
+}
+
+// editsType reports whether the header of the bulk edit text
+// changes the issue type, which must then be restored too.
+func editsType(text []byte) bool {
+	for _, line := range strings.Split(string(text), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, "Type:") {
+			return true
+		}
+	}
+	return false
+}
+
+// rollback restores the issues whose states were saved
+// before a failed bulk edit, reporting errors through status.
+// It returns whether each issue was restored.
+func rollback(project string, saved []*issueState, text []byte, status func(string)) []bool {
+	withType := editsType(text)
+	restored := make([]bool, len(saved))
+	for i, s := range saved {
+		if s == nil {
+			continue
+		}
+		if err := s.restore(project, withType); err != nil {
+			status(fmt.Sprintf("restoring #%d: %v", s.number, err))
+			continue
+		}
+		restored[i] = true
+	}
+	return restored
+}
@@ -0,0 +1,179 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v45/github"
+)
+
+var editsTypeTests = []struct {
+	text string
+	want bool
+}{
+	{"", false},
+	{"Milestone: Go1.20\nLabels: bug\n\n1\tTitle\n", false},
+	{"Milestone: Go1.20\nType: Bug\n\n1\tTitle\n", true},
+	{"  Type: Feature\n", true},
+	{"Labels: bug\n\nType: Bug\n", false},
+	{"Typo: x\n", false},
+}
+
+func TestEditsType(t *testing.T) {
+	for _, tt := range editsTypeTests {
+		if got := editsType([]byte(tt.text)); got != tt.want {
+			t.Errorf("editsType(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+// fakeAPI starts a server standing in for the GitHub API, which
+// answers DELETE requests with deleteStatus and all others with 200,
+// and logs each request as method, path, and body.
+// It points client at the server until the test ends.
+func fakeAPI(t *testing.T, deleteStatus int) *[]string {
+	var log []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		log = append(log, strings.TrimSpace(r.Method+" "+r.URL.Path+" "+string(body)))
+		if r.Method == "DELETE" {
+			w.WriteHeader(deleteStatus)
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	useServer(t, srv)
+	return &log
+}
+
+// useServer points client at srv until the test ends.
+func useServer(t *testing.T, srv *httptest.Server) {
+	old := client
+	client = github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(srv.URL + "/")
+	t.Cleanup(func() {
+		client = old
+		srv.Close()
+	})
+}
+
+// patch returns the logged PATCH request of issue 7 setting edit.
+func patch(edit map[string]interface{}) string {
+	data, _ := json.Marshal(edit)
+	return "PATCH /repos/golang/go/issues/7 " + string(data)
+}
+
+func intPtr(i int) *int { return &i }
+
+var restoreTests = []struct {
+	name         string
+	state        issueState
+	withType     bool
+	deleteStatus int
+	err          bool
+	log          []string
+}{
+	{
+		name:  "open",
+		state: issueState{number: 7, state: "open", assignees: []string{}, labels: []string{"bug"}},
+		log: []string{
+			patch(map[string]interface{}{"state": "open", "assignees": []string{}, "labels": []string{"bug"}, "milestone": nil}),
+		},
+	},
+	{
+		name:  "closed",
+		state: issueState{number: 7, state: "closed", stateReason: "not_planned", assignees: []string{"rsc"}, labels: []string{}, milestone: intPtr(3)},
+		log: []string{
+			patch(map[string]interface{}{"state": "closed", "state_reason": "not_planned", "assignees": []string{"rsc"}, "labels": []string{}, "milestone": 3}),
+		},
+	},
+	{
+		name:     "type",
+		state:    issueState{number: 7, state: "open", assignees: []string{}, labels: []string{}, typ: "Bug"},
+		withType: true,
+		log: []string{
+			patch(map[string]interface{}{"state": "open", "assignees": []string{}, "labels": []string{}, "milestone": nil, "type": "Bug"}),
+		},
+	},
+	{
+		name:     "no type",
+		state:    issueState{number: 7, state: "open", assignees: []string{}, labels: []string{}},
+		withType: true,
+		log: []string{
+			patch(map[string]interface{}{"state": "open", "assignees": []string{}, "labels": []string{}, "milestone": nil, "type": nil}),
+		},
+	},
+	{
+		name:         "comment",
+		state:        issueState{number: 7, state: "open", assignees: []string{}, labels: []string{}, comment: 42},
+		deleteStatus: http.StatusNoContent,
+		log: []string{
+			"DELETE /repos/golang/go/issues/comments/42",
+			patch(map[string]interface{}{"state": "open", "assignees": []string{}, "labels": []string{}, "milestone": nil}),
+		},
+	},
+	{
+		name:         "comment already deleted",
+		state:        issueState{number: 7, state: "open", assignees: []string{}, labels: []string{}, comment: 42},
+		deleteStatus: http.StatusNotFound,
+		log: []string{
+			"DELETE /repos/golang/go/issues/comments/42",
+			patch(map[string]interface{}{"state": "open", "assignees": []string{}, "labels": []string{}, "milestone": nil}),
+		},
+	},
+	{
+		name:         "delete fails",
+		state:        issueState{number: 7, state: "open", assignees: []string{}, labels: []string{}, comment: 42},
+		deleteStatus: http.StatusForbidden,
+		err:          true,
+		log: []string{
+			"DELETE /repos/golang/go/issues/comments/42",
+		},
+	},
+}
+
+func TestRestore(t *testing.T) {
+	for _, tt := range restoreTests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := fakeAPI(t, tt.deleteStatus)
+			s := tt.state
+			err := s.restore("golang/go", tt.withType)
+			if (err != nil) != tt.err {
+				t.Errorf("restore: %v, want error %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(*log, tt.log) {
+				t.Errorf("requests:\n\t%s\nwant:\n\t%s", strings.Join(*log, "\n\t"), strings.Join(tt.log, "\n\t"))
+			}
+			if err == nil && s.comment != 0 {
+				t.Errorf("comment %d not cleared after restore", s.comment)
+			}
+		})
+	}
+}
+
+func TestRollback(t *testing.T) {
+	fakeAPI(t, http.StatusForbidden)
+	saved := []*issueState{
+		{number: 7, state: "open", assignees: []string{}, labels: []string{}},
+		nil, // not yet changed
+		{number: 8, state: "open", assignees: []string{}, labels: []string{}, comment: 42},
+	}
+	var status []string
+	restored := rollback("golang/go", saved, []byte("Labels: bug\n"), func(s string) { status = append(status, s) })
+	if want := []bool{true, false, false}; !reflect.DeepEqual(restored, want) {
+		t.Errorf("rollback = %v, want %v", restored, want)
+	}
+	if len(status) != 1 || !strings.HasPrefix(status[0], "restoring #8: ") {
+		t.Errorf("rollback status = %q, want one error restoring #8", status)
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// The -stdio-server flag runs a JSON-RPC 2.0 server on standard input
+// and output, one message per line, for editor plugins.
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string { return e.Message }
+
+// JSON-RPC error codes.
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcFailed         = -32000 // the GitHub operation failed
+)
+
+// rpcParams are the parameters of all methods.
+// Project defaults to the -p flag.
+type rpcParams struct {
+	Project string `json:"project"`
+	Number  int    `json:"number"`
+	Query   string `json:"query"`
+	Text    string `json:"text"`
+}
+
+// An rpcServer holds the issues opened by the client,
+// so that a later put applies the edits relative to the issue
+// as it was shown, as Put does in acme.
+type rpcServer struct {
+	mu     sync.Mutex
+	opened map[projectAndNumber]*github.Issue
+}
+
+func stdioServer(r io.Reader, w io.Writer) {
+	s := &rpcServer{opened: make(map[projectAndNumber]*github.Issue)}
+	enc := json.NewEncoder(w)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var req rpcRequest
+		resp := &rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null")}
+		if err := json.Unmarshal(line, &req); err != nil {
+			resp.Error = &rpcError{rpcParseError, err.Error()}
+			enc.Encode(resp)
+			continue
+		}
+		if req.ID != nil {
+			resp.ID = req.ID
+		}
+		result, err := s.call(req.Method, req.Params)
+		if err != nil {
+			e, ok := err.(*rpcError)
+			if !ok {
+				e = &rpcError{rpcFailed, err.Error()}
+			}
+			resp.Error = e
+		} else {
+			resp.Result = result
+		}
+		if req.ID == nil {
+			continue // notification
+		}
+		enc.Encode(resp)
+	}
+	if err := scanner.Err(); err != nil {
+		fatal(err)
+	}
+}
+
+// call runs the named method. The methods are:
+//
+//	open    {number}       the issue's text, as in an acme issue window
+//	search  {query}        the matching issues, as with -json
+//	put     {number, text} apply the edited text of an opened issue
+//	comment {number, text} post a comment
+func (s *rpcServer) call(method string, raw json.RawMessage) (interface{}, error) {
+	var p rpcParams
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil, &rpcError{rpcInvalidParams, err.Error()}
+		}
+	}
+	if p.Project == "" {
+		p.Project = *project
+	}
+	if strings.Count(p.Project, "/") != 1 {
+		return nil, &rpcError{rpcInvalidParams, "invalid project: must be owner/repo"}
+	}
+	needNumber := func() error {
+		if p.Number <= 0 {
+			return &rpcError{rpcInvalidParams, "missing issue number"}
+		}
+		return nil
+	}
+
+	switch method {
+	case "open":
+		if err := needNumber(); err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		issue, err := showIssue(&buf, p.Project, p.Number, false)
+		if err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		s.opened[projectAndNumber{p.Project, p.Number}] = issue
+		s.mu.Unlock()
+		return map[string]string{"text": buf.String()}, nil
+
+	case "search":
+		all, err := searchIssues(p.Project, p.Query)
+		if err != nil {
+			return nil, err
+		}
+		sort.Sort(issuesByTitle(all))
+		list := []*Issue{} // non-nil for json
+		for _, issue := range all {
+			list = append(list, toJSON(p.Project, issue))
+		}
+		return list, nil
+
+	case "put":
+		if err := needNumber(); err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		old := s.opened[projectAndNumber{p.Project, p.Number}]
+		s.mu.Unlock()
+		if old == nil {
+			return nil, &rpcError{rpcInvalidParams, fmt.Sprintf("issue %s#%d is not open", p.Project, p.Number)}
+		}
+		if _, _, err := writeIssue(p.Project, old, []byte(p.Text), false); err != nil {
+			return nil, err
+		}
+		// Reopen, so the client can show the result and later edits
+		// apply relative to it.
+		return s.call("open", raw)
+
+	case "comment":
+		if err := needNumber(); err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(p.Text) == "" {
+			return nil, &rpcError{rpcInvalidParams, "empty comment"}
+		}
+		if err := commentIssue(p.Project, p.Number, p.Text); err != nil {
+			return nil, err
+		}
+		return map[string]string{"url": issueURL(p.Project, p.Number)}, nil
+	}
+	return nil, &rpcError{rpcMethodNotFound, "unknown method " + method}
+}
@@ -0,0 +1,314 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"9fans.net/go/plan9"
+	"9fans.net/go/plumb"
+	"github.com/google/go-github/v45/github"
+)
+
+// Sam has no interface like acme's for programs to manage windows,
+// so -sam mode writes each of what would be an acme window to a file
+// in a temporary directory, asks the plumber to open the file in sam,
+// and watches for sam to write the file back, applying the changes
+// as Put does in acme.
+
+// A samFile is a file opened in sam, standing in for an acme window.
+// Files are created by the plumbing goroutine and checked by the main
+// one, so mu guards the fields that change.
+type samFile struct {
+	name    string // file name, relative to samFiles.dir
+	project string
+	mode    int // modeSingle, modeQuery, modeCreate, or modeBulk
+
+	mu    sync.Mutex
+	issue *github.Issue     // issue being edited, or common state of bulk edit
+	shown map[int]time.Time // update times of issues in bulk edit
+	mtime time.Time         // modification time when last written by issue
+}
+
+var samFiles struct {
+	sync.Mutex
+	dir string
+	m   map[string]*samFile
+}
+
+// samListRE matches the issue references at the start of list file lines.
+var samListRE = regexp.MustCompile(`(?m)^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+#([0-9]+)\t`)
+
+func samMode() {
+	dir, err := ioutil.TempDir("", "issue-sam-")
+	if err != nil {
+		fatal(err)
+	}
+	samFiles.dir = dir
+	samFiles.m = make(map[string]*samFile)
+
+	// As on the command line, the arguments are one query.
+	q := strings.Join(flag.Args(), " ")
+	if q == "" {
+		q = "all"
+	}
+	if err := samLook(*project, q); err != nil {
+		fatal(err)
+	}
+
+	go servePlumb(func(project, what string) bool {
+		if err := samLook(project, what); err != nil {
+			log.Print(err)
+			return false
+		}
+		return true
+	}, func(s string) { log.Print(s) })
+
+	for range time.Tick(time.Second) {
+		samCheck()
+	}
+}
+
+// samLook opens a sam file for what in project:
+// an issue number, "new" for a new issue, or a query.
+func samLook(project, what string) error {
+	if n, _ := strconv.Atoi(strings.TrimPrefix(what, "#")); n > 0 {
+		name := fmt.Sprintf("%s/%d", project, n)
+		if samShow(name) {
+			return nil
+		}
+		var buf bytes.Buffer
+		issue, err := showIssue(&buf, project, n, true)
+		if err != nil {
+			return err
+		}
+		return samCreate(&samFile{name: name, project: project, mode: modeSingle, issue: issue}, buf.Bytes())
+	}
+	if what == "new" {
+		f := &samFile{name: samUnique(project + "/new"), project: project, mode: modeCreate, issue: new(github.Issue)}
+		return samCreate(f, []byte(createTemplate))
+	}
+
+	name, q := project+"/all", ""
+	if what != "all" {
+		name, q = samUnique(project+"/search"), what
+	}
+	if samShow(name) {
+		return nil
+	}
+	all, err := searchIssues(project, q)
+	if err != nil {
+		return err
+	}
+	sort.Sort(issuesByTitle(all))
+	var buf bytes.Buffer
+	for _, issue := range all {
+		fmt.Fprintf(&buf, "%s#%d\t%s\n", project, getInt(issue.Number), getString(issue.Title))
+	}
+	return samCreate(&samFile{name: name, project: project, mode: modeQuery}, buf.Bytes())
+}
+
+// samUnique returns a file name beginning with name
+// that is not already in use.
+func samUnique(name string) string {
+	samFiles.Lock()
+	defer samFiles.Unlock()
+	unique := name
+	for i := 2; samFiles.m[unique] != nil; i++ {
+		unique = fmt.Sprintf("%s.%d", name, i)
+	}
+	return unique
+}
+
+// samShow opens the named file in sam if it already exists,
+// reporting whether it did.
+func samShow(name string) bool {
+	samFiles.Lock()
+	f := samFiles.m[name]
+	samFiles.Unlock()
+	if f == nil {
+		return false
+	}
+	samOpen(f)
+	return true
+}
+
+// samCreate writes data to the file f, starts watching it, and opens it in sam.
+func samCreate(f *samFile, data []byte) error {
+	f.mu.Lock()
+	err := samWrite(f, data)
+	f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	samFiles.Lock()
+	samFiles.m[f.name] = f
+	samFiles.Unlock()
+	samOpen(f)
+	return nil
+}
+
+// samWrite replaces the contents of the file f with data.
+// It must be called with f.mu locked.
+func samWrite(f *samFile, data []byte) error {
+	file := filepath.Join(samFiles.dir, filepath.FromSlash(f.name))
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(file, data, 0600); err != nil {
+		return err
+	}
+	fi, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	f.mtime = fi.ModTime()
+	return nil
+}
+
+// samOpen asks the plumber to open the file f in the editor,
+// as the B command does.
+func samOpen(f *samFile) {
+	fid, err := plumb.Open("send", plan9.OWRITE)
+	if err != nil {
+		log.Printf("plumb: %v", err)
+		return
+	}
+	defer fid.Close()
+	m := &plumb.Message{
+		Src:  "issue",
+		Dst:  "edit",
+		Dir:  samFiles.dir,
+		Type: "text",
+		Data: []byte(filepath.Join(samFiles.dir, filepath.FromSlash(f.name))),
+	}
+	if err := m.Send(fid); err != nil {
+		log.Printf("plumb: %v", err)
+	}
+}
+
+// samCheck applies the changes in any files that sam has written
+// since they were last checked.
+func samCheck() {
+	samFiles.Lock()
+	var files []*samFile
+	for _, f := range samFiles.m {
+		files = append(files, f)
+	}
+	samFiles.Unlock()
+
+	for _, f := range files {
+		f.mu.Lock()
+		samCheckFile(f)
+		f.mu.Unlock()
+	}
+}
+
+// samCheckFile applies the changes in the file f if sam has written it.
+// It must be called with f.mu locked.
+func samCheckFile(f *samFile) {
+	file := filepath.Join(samFiles.dir, filepath.FromSlash(f.name))
+	fi, err := os.Stat(file)
+	if err != nil || !fi.ModTime().After(f.mtime) {
+		return
+	}
+	f.mtime = fi.ModTime()
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	if err := samPut(f, data); err != nil {
+		log.Printf("%s: %v", f.name, err)
+	}
+}
+
+// samPut applies the contents data written to the file f.
+// Writing an issue or bulk edit file updates the issues, after which
+// the file is rewritten with their new state, to be reloaded in sam with e.
+// Writing a new issue file creates the issue and opens it.
+// Writing a list file opens a bulk edit of the issues still listed.
+// It must be called with f.mu locked.
+func samPut(f *samFile, data []byte) error {
+	switch f.mode {
+	case modeSingle:
+		if _, _, err := writeIssue(f.project, f.issue, data, false); err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		issue, err := showIssue(&buf, f.project, getInt(f.issue.Number), true)
+		if err != nil {
+			return err
+		}
+		f.issue = issue
+		if err := samWrite(f, buf.Bytes()); err != nil {
+			return err
+		}
+		log.Printf("%s: updated; reload with e", f.name)
+
+	case modeCreate:
+		issue, _, err := writeIssue(f.project, f.issue, data, false)
+		if err != nil {
+			return err
+		}
+		samFiles.Lock()
+		delete(samFiles.m, f.name)
+		samFiles.Unlock()
+		log.Printf("%s created", issueURL(f.project, getInt(issue.Number)))
+		return samLook(f.project, fmt.Sprint(getInt(issue.Number)))
+
+	case modeQuery:
+		var ids []int
+		for _, m := range samListRE.FindAllStringSubmatch(string(data), -1) {
+			n, _ := strconv.Atoi(m[1])
+			ids = append(ids, n)
+		}
+		if len(ids) == 0 {
+			return fmt.Errorf("found no issues in list")
+		}
+		issues, err := bulkReadIssuesCached(f.project, ids)
+		if err != nil {
+			return err
+		}
+		base, text, shown := bulkEditStart(issues)
+		return samCreate(&samFile{name: samUnique(f.project + "/bulk"), project: f.project, mode: modeBulk, issue: base, shown: shown}, text)
+
+	case modeBulk:
+		ids, results, err := bulkWriteIssue(f.project, f.issue, data, f.shown, func(s string) { log.Printf("%s: %s", f.name, s) }, nil)
+		printBulkResults(os.Stderr, results)
+		if err != nil {
+			return err
+		}
+		var issues []*github.Issue
+		for _, n := range ids {
+			issue, _, err := client.Issues.Get(context.TODO(), projectOwner(f.project), projectRepo(f.project), n)
+			if err != nil {
+				return err
+			}
+			updateIssueCache(f.project, issue)
+			issues = append(issues, issue)
+		}
+		base, text, shown := bulkEditStart(issues)
+		f.issue, f.shown = base, shown
+		if err := samWrite(f, text); err != nil {
+			return err
+		}
+		log.Printf("%s: updated %d issue%s; reload with e", f.name, len(ids), suffix(len(ids)))
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"time"
+)
+
+// A report is a scheduled report from the configuration file:
+// the issues matching a query, written periodically to standard
+// output, a file, or the chat webhooks.
+type report struct {
+	Name    string `yaml:"name"`
+	Query   string `yaml:"query"` // saved query name or query
+	Every   string `yaml:"every"` // interval, like "12h" or "1d"
+	At      string `yaml:"at"`    // time of day, like "09:00"
+	File    string `yaml:"file"`
+	Webhook bool   `yaml:"webhook"`
+
+	every time.Duration
+	at    time.Duration // since midnight, or -1 if unset
+}
+
+// check validates the report and parses its schedule.
+func (r *report) check() error {
+	if r.Name == "" {
+		return fmt.Errorf("report has no name")
+	}
+	var err error
+	if r.every, err = parseAge(r.Every); err != nil {
+		return fmt.Errorf("report %s: every: %v", r.Name, err)
+	}
+	r.at = -1
+	if r.At != "" {
+		t, err := time.Parse("15:04", r.At)
+		if err != nil {
+			return fmt.Errorf("report %s: at: want HH:MM", r.Name)
+		}
+		r.at = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+		if r.every == 0 {
+			r.every = 24 * time.Hour
+		}
+		if r.every%(24*time.Hour) != 0 {
+			return fmt.Errorf("report %s: every must be a number of days when at is set", r.Name)
+		}
+	}
+	if r.every <= 0 {
+		return fmt.Errorf("report %s: missing every or at", r.Name)
+	}
+	return nil
+}
+
+// next returns the time the report is next due,
+// given when it last ran (the zero time if never) and the time now.
+func (r *report) next(last, now time.Time) time.Time {
+	if r.at < 0 {
+		if last.IsZero() {
+			return now
+		}
+		return last.Add(r.every)
+	}
+	// The first time of day r.at after the earliest next run.
+	after := now
+	if !last.IsZero() {
+		after = last.Add(r.every - 24*time.Hour)
+	}
+	y, m, d := after.Date()
+	t := time.Date(y, m, d, 0, 0, 0, 0, time.Local).Add(r.at)
+	if !t.After(after) {
+		t = time.Date(y, m, d+1, 0, 0, 0, 0, time.Local).Add(r.at)
+	}
+	return t
+}
+
+// run runs the report, writing the results to its destinations.
+func (r *report) run(project string, c *config) error {
+	q := savedQuery(r.Query)
+	all, err := searchIssues(project, q)
+	if err != nil {
+		return err
+	}
+	sort.Sort(issuesByTitle(all))
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s: %d issue%s matching %s in %s (%s)\n", r.Name, len(all), suffix(len(all)), describeQuery(r.Query), project, time.Now().In(timeZone).Format(timeLayout()))
+	for _, issue := range all {
+		fmt.Fprintf(&buf, "%s\t%s\n", issueURL(project, getInt(issue.Number)), getString(issue.Title))
+	}
+
+	if r.File == "" && !r.Webhook {
+		os.Stdout.Write(append(buf.Bytes(), '\n'))
+	}
+	if r.File != "" {
+		if err := ioutil.WriteFile(r.File, buf.Bytes(), 0666); err != nil {
+			return err
+		}
+	}
+	if r.Webhook {
+		notify(c, buf.String())
+	}
+	return nil
+}
+
+func runSchedule(project string, args []string) {
+	fs := lookupCommand("schedule").flags()
+	once := fs.Bool("once", false, "run every report once now and exit")
+	parseFlags(fs, args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+	}
+	c := loadConfig()
+	if len(c.Reports) == 0 {
+		file, _ := configFile()
+		fatalf("no reports configured in %s", file)
+	}
+
+	if *once {
+		failed := false
+		for _, r := range c.Reports {
+			if err := r.run(project, c); err != nil {
+				log.Printf("%s: %v", r.Name, err)
+				failed = true
+			}
+		}
+		if failed {
+			exit(exitError)
+		}
+		return
+	}
+
+	// The last run times are saved in the cache, so that restarting
+	// the command does not rerun reports or skip them.
+	last := make(map[string]time.Time)
+	readCache(project, "schedule", anyAge, &last)
+	for {
+		now := time.Now()
+		var wake time.Time
+		for _, r := range c.Reports {
+			next := r.next(last[r.Name], now)
+			if !next.After(now) {
+				if err := r.run(project, c); err != nil {
+					log.Printf("%s: %v", r.Name, err)
+				}
+				last[r.Name] = now
+				writeCache(project, "schedule", last)
+				next = r.next(now, now)
+			}
+			if wake.IsZero() || next.Before(wake) {
+				wake = next
+			}
+		}
+		time.Sleep(time.Until(wake))
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// serveMaxAge is how long serve mode answers a request from its
+// mirror of earlier responses before asking GitHub again.
+const serveMaxAge = time.Minute
+
+// anyAge is a maximum cache age that accepts any cached data.
+const anyAge = time.Duration(1<<63 - 1)
+
+// A server answers read-only HTTP requests about a project's issues,
+// using the same JSON schema as the -json flag.
+// Responses are mirrored in the user's cache directory, so that recent
+// results are served without contacting GitHub, and older ones are
+// served when GitHub cannot be reached.
+type server struct {
+	project string
+}
+
+func serveMode(project, addr string) {
+	s := &server{project: project}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/issue/", s.serveIssue)
+	mux.HandleFunc("/search", s.serveSearch)
+	mux.HandleFunc("/feed", s.serveFeed)
+	mux.HandleFunc("/metrics", serveMetrics)
+	mux.HandleFunc("/", s.serveHTML)
+	log.Printf("serving %s on %s", project, addr)
+	fatal(http.ListenAndServe(addr, countRequests(mux)))
+}
+
+// jsonVersionParam returns the JSON schema version requested by
+// the json form value, which defaults to 1.
+func jsonVersionParam(r *http.Request) (jsonVersion, error) {
+	v := jsonVersion(1)
+	if s := r.FormValue("json"); s != "" {
+		if err := v.Set(s); err != nil || v == 0 {
+			return 0, fmt.Errorf("invalid json version %q", s)
+		}
+	}
+	return v, nil
+}
+
+// serveIssue serves /issue/N, the issue numbered N with its comments.
+func (s *server) serveIssue(w http.ResponseWriter, r *http.Request) {
+	n, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/issue/"))
+	if err != nil || n <= 0 {
+		http.NotFound(w, r)
+		return
+	}
+	v, err := jsonVersionParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.serveJSON(w, func() (json.RawMessage, bool, error) { return s.issue(n, v) })
+}
+
+// serveSearch serves /search?q=query, the list of issues matching query.
+func (s *server) serveSearch(w http.ResponseWriter, r *http.Request) {
+	v, err := jsonVersionParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q := r.FormValue("q")
+	s.serveJSON(w, func() (json.RawMessage, bool, error) { return s.search(q, v) })
+}
+
+func (s *server) serveJSON(w http.ResponseWriter, load func() (json.RawMessage, bool, error)) {
+	data, stale, err := load()
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+	if stale {
+		w.Header().Set("Warning", staleWarning)
+	}
+	data, err = json.MarshalIndent(data, "", "\t")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(append(data, '\n'))
+}
+
+const staleWarning = `110 - "Response is Stale"`
+
+// serveError reports err, which came from GitHub, to the HTTP client.
+func serveError(w http.ResponseWriter, err error) {
+	code := http.StatusBadGateway
+	var e *github.ErrorResponse
+	if errors.As(err, &e) && e.Response != nil && e.Response.StatusCode == http.StatusNotFound {
+		code = http.StatusNotFound
+	}
+	http.Error(w, err.Error(), code)
+}
+
+// issue returns the JSON for the issue numbered n, with comments,
+// in schema version v.
+func (s *server) issue(n int, v jsonVersion) (json.RawMessage, bool, error) {
+	return s.mirrored(fmt.Sprintf("serve/issue/%d.v%d", n, v), func() (interface{}, error) {
+		issue, _, err := client.Issues.Get(context.TODO(), projectOwner(s.project), projectRepo(s.project), n)
+		if err != nil {
+			return nil, err
+		}
+		if v == 2 {
+			return toJSON2WithHistory(s.project, issue)
+		}
+		return toJSONWithComments(s.project, issue)
+	})
+}
+
+// search returns the JSON for the list of issues matching q,
+// in schema version v.
+func (s *server) search(q string, v jsonVersion) (json.RawMessage, bool, error) {
+	return s.mirrored(fmt.Sprintf("serve/search/%x.v%d", sha256.Sum256([]byte(q)), v), func() (interface{}, error) {
+		all, err := searchIssues(s.project, q)
+		if err != nil {
+			return nil, err
+		}
+		sort.Sort(issuesByTitle(all))
+		if v == 2 {
+			return toJSON2List(s.project, all)
+		}
+		list := []*Issue{} // non-nil for json
+		for _, issue := range all {
+			list = append(list, toJSON(s.project, issue))
+		}
+		return list, nil
+	})
+}
+
+// mirrored returns the JSON encoding of the value returned by load,
+// using the named mirrored copy instead if it is recent or if load fails.
+// The boolean result reports whether the data is an old copy.
+func (s *server) mirrored(name string, load func() (interface{}, error)) (json.RawMessage, bool, error) {
+	var data json.RawMessage
+	if readCache(s.project, name, serveMaxAge, &data) {
+		return data, false, nil
+	}
+	v, err := load()
+	if err != nil {
+		if readCache(s.project, name, anyAge, &data) {
+			metrics.Lock()
+			metrics.staleServed++
+			metrics.Unlock()
+			return data, true, nil
+		}
+		return nil, false, err
+	}
+	data, err = json.Marshal(v)
+	if err != nil {
+		return nil, false, err
+	}
+	writeCache(s.project, name, data)
+	metrics.Lock()
+	metrics.lastSync = time.Now()
+	metrics.Unlock()
+	return data, false, nil
+}
@@ -0,0 +1,57 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Printing an issue in full records a read mark: the time it was printed.
+// The read marks are kept in the cache directory, so that -since last
+// can show only what is new since the issue was last read.
+
+// sinceLayouts are the forms of dates accepted by -since.
+var sinceLayouts = []string{"2006-01-02", timeFormat, time.RFC3339}
+
+// parseSince parses a -since value other than "last":
+// a date, in the -tz time zone unless it says otherwise,
+// or an age like "36h" or "7d", meaning that long before now.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	for _, layout := range sinceLayouts {
+		if t, err := time.ParseInLocation(layout, s, timeZone); err == nil {
+			return t, nil
+		}
+	}
+	age, err := parseAge(s)
+	if err != nil || age <= 0 {
+		return time.Time{}, fmt.Errorf("invalid time %q: want a date like 2024-01-01, an age like 7d, or last", s)
+	}
+	return now.Add(-age), nil
+}
+
+// issueSince returns the time from which -since prints the activity on
+// issue n, or the zero time to print all of it, as when -since is not set
+// or is "last" and issue n has no read mark.
+func issueSince(project string, n int) time.Time {
+	switch *sinceFlag {
+	case "":
+		return time.Time{}
+	case "last":
+		var marks map[int]time.Time
+		readCache(project, "read", anyAge, &marks)
+		return marks[n]
+	}
+	t, _ := parseSince(*sinceFlag, time.Now()) // checked in main
+	return t
+}
+
+// markRead records t as the time issue n was last read.
+func markRead(project string, n int, t time.Time) {
+	marks := make(map[int]time.Time)
+	readCache(project, "read", anyAge, &marks)
+	marks[n] = t
+	writeCache(project, "read", marks)
+}
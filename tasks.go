@@ -0,0 +1,277 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// taskRE matches a GitHub task list item: a list item
+// whose text begins with a [ ] or [x] checkbox.
+var taskRE = regexp.MustCompile(`^(\s*(?:[-*+]|[0-9]+[.)])\s+\[)([ xX])(\]\s+)(.*)$`)
+
+// parseTasks returns the task list items in the issue body,
+// skipping any that appear inside fenced code blocks.
+func parseTasks(body string) []*Task {
+	var tasks []*Task
+	inCode := false
+	for i, line := range strings.Split(strings.Replace(body, "\r\n", "\n", -1), "\n") {
+		if _, ok := isFence(line); ok {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			continue
+		}
+		m := taskRE.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		tasks = append(tasks, &Task{
+			Done: m[2] != " ",
+			Text: strings.TrimSpace(m[4]),
+			line: i,
+		})
+	}
+	return tasks
+}
+
+// taskProgress returns the number of completed tasks and the total.
+func taskProgress(tasks []*Task) (done, total int) {
+	for _, t := range tasks {
+		if t.Done {
+			done++
+		}
+	}
+	return done, len(tasks)
+}
+
+// taskSummary returns the "3/7" summary of the tasks in body,
+// or the empty string if body has no task list.
+func taskSummary(body string) string {
+	done, total := taskProgress(parseTasks(body))
+	if total == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d/%d", done, total)
+}
+
+// setTaskBody returns body with the done state of the task on the given line
+// set according to op, which is "check", "uncheck", or "toggle".
+func setTaskBody(body string, t *Task, op string) (string, error) {
+	lines := strings.Split(body, "\n")
+	if t.line >= len(lines) {
+		return "", fmt.Errorf("task %q not found", t.Text)
+	}
+	m := taskRE.FindStringSubmatch(lines[t.line])
+	if m == nil {
+		return "", fmt.Errorf("task %q not found", t.Text)
+	}
+	done := t.Done
+	switch op {
+	case "check":
+		done = true
+	case "uncheck":
+		done = false
+	case "toggle":
+		done = !done
+	default:
+		return "", fmt.Errorf("unknown task operation %q", op)
+	}
+	box := " "
+	if done {
+		box = "x"
+	}
+	lines[t.line] = m[1] + box + m[3] + m[4]
+	return strings.Join(lines, "\n"), nil
+}
+
+// updateTask applies op to task t in the body of issue and saves the result.
+// The issue argument must be the version of the issue that t was parsed from;
+// if the body has been edited on GitHub since then, updateTask refuses to
+// overwrite the concurrent edit.
+func updateTask(project string, issue *github.Issue, t *Task, op string) (*github.Issue, error) {
+	n := getInt(issue.Number)
+	body, err := setTaskBody(getString(issue.Body), t, op)
+	if err != nil {
+		return nil, err
+	}
+	cur, _, err := client.Issues.Get(context.TODO(), projectOwner(project), projectRepo(project), n)
+	if err != nil {
+		return nil, err
+	}
+	if getString(cur.Body) != getString(issue.Body) {
+		return nil, fmt.Errorf("#%d was edited since it was loaded; reload and try again", n)
+	}
+	updated, _, err := client.Issues.Edit(context.TODO(), projectOwner(project), projectRepo(project), n, &github.IssueRequest{
+		Body: &body,
+	})
+	if err != nil {
+		return nil, err
+	}
+	updateIssueCache(project, updated)
+	return updated, nil
+}
+
+// findTaskLine returns the task in body shown on a line of displayed
+// text, such as a line selected in an acme issue window. The display
+// wraps long lines, so the text is matched against the task's source
+// line as wrap displays it: the line may be any one of the wrapped
+// lines, or all of them together.
+func findTaskLine(body, line string) (*Task, error) {
+	text := strings.Join(strings.Fields(line), " ")
+	if text == "" {
+		return nil, fmt.Errorf("no task selected")
+	}
+	src := strings.Split(strings.Replace(body, "\r\n", "\n", -1), "\n")
+	var found *Task
+	for _, t := range parseTasks(body) {
+		match := strings.Join(strings.Fields(src[t.line]), " ") == text
+		for _, l := range strings.Split(wrap(src[t.line], ""), "\n") {
+			match = match || strings.Join(strings.Fields(l), " ") == text
+		}
+		if !match {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("ambiguous task: %s", text)
+		}
+		found = t
+	}
+	if found == nil {
+		return nil, fmt.Errorf("not a task line: %s", text)
+	}
+	return found, nil
+}
+
+func runTask(project string, args []string) {
+	c := lookupCommand("task")
+	fs := c.flags()
+	parseFlags(fs, args)
+	if fs.NArg() != 1 && fs.NArg() != 3 {
+		fs.Usage()
+	}
+	n, err := strconv.Atoi(fs.Arg(0))
+	if err != nil || n <= 0 {
+		fs.Usage()
+	}
+	issue, _, err := client.Issues.Get(context.TODO(), projectOwner(project), projectRepo(project), n)
+	if err != nil {
+		fatal(err)
+	}
+	tasks := parseTasks(getString(issue.Body))
+
+	if fs.NArg() == 1 {
+		for i, t := range tasks {
+			box := " "
+			if t.Done {
+				box = "x"
+			}
+			fmt.Printf("%d\t[%s] %s\n", i+1, box, t.Text)
+		}
+		return
+	}
+
+	op := fs.Arg(1)
+	i, err := strconv.Atoi(fs.Arg(2))
+	if err != nil {
+		fs.Usage()
+	}
+	if i < 1 || i > len(tasks) {
+		usageErrorf("#%d has %d task%s; no task %d", n, len(tasks), suffix(len(tasks)), i)
+	}
+	if _, err := updateTask(project, issue, tasks[i-1], op); err != nil {
+		fatal(err)
+	}
+}
+
+// issueRefRE matches task text that already refers to a single issue.
+var issueRefRE = regexp.MustCompile(`^(?:[\w.-]+/[\w.-]+)?#[0-9]+$|^https://github\.com/[\w.-]+/[\w.-]+/issues/[0-9]+$`)
+
+func runSplit(project string, args []string) {
+	fs := lookupCommand("split").flags()
+	dryRun := fs.Bool("n", false, "print the issues that would be created without creating them")
+	parseFlags(fs, args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+	}
+	n := issueArgs(fs, fs.Args())[0]
+	owner, repo := projectOwner(project), projectRepo(project)
+	issue, _, err := client.Issues.Get(context.TODO(), owner, repo, n)
+	if err != nil {
+		fatal(err)
+	}
+
+	var split []*Task
+	for _, t := range parseTasks(getString(issue.Body)) {
+		if !t.Done && !issueRefRE.MatchString(t.Text) {
+			split = append(split, t)
+		}
+	}
+	if len(split) == 0 {
+		fmt.Printf("#%d has no unchecked tasks to split out\n", n)
+		exit(exitNoMatch)
+	}
+	if *dryRun {
+		for _, t := range split {
+			fmt.Printf("%s\n", t.Text)
+		}
+		return
+	}
+
+	// Create the child issues, inheriting labels and milestone.
+	labels := getLabelNames(issue.Labels)
+	var milestone *int
+	if issue.Milestone != nil {
+		milestone = issue.Milestone.Number
+	}
+	body := getString(issue.Body)
+	lines := strings.Split(body, "\n")
+	var created []string
+	for _, t := range split {
+		title := t.Text
+		text := fmt.Sprintf("Split from #%d.", n)
+		child, _, err := client.Issues.Create(context.TODO(), owner, repo, &github.IssueRequest{
+			Title:     &title,
+			Body:      &text,
+			Labels:    &labels,
+			Milestone: milestone,
+		})
+		if err != nil {
+			if len(created) > 0 {
+				log.Printf("created %s before failing", strings.Join(created, " "))
+			}
+			fatal(err)
+		}
+		ref := fmt.Sprintf("#%d", getInt(child.Number))
+		created = append(created, ref)
+		fmt.Printf("%s\t%s\n", ref, title)
+		m := taskRE.FindStringSubmatch(lines[t.line])
+		lines[t.line] = m[1] + m[2] + m[3] + ref
+	}
+
+	// Replace the items by links to the new issues,
+	// unless the body was edited meanwhile.
+	cur, _, err := client.Issues.Get(context.TODO(), owner, repo, n)
+	if err != nil {
+		fatal(err)
+	}
+	if getString(cur.Body) != body {
+		fatalf("#%d was edited while splitting; replace its tasks with %s by hand", n, strings.Join(created, " "))
+	}
+	body = strings.Join(lines, "\n")
+	updated, _, err := client.Issues.Edit(context.TODO(), owner, repo, n, &github.IssueRequest{Body: &body})
+	if err != nil {
+		fatal(err)
+	}
+	updateIssueCache(project, updated)
+}
@@ -0,0 +1,36 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+var parseTasksTests = []struct {
+	body  string
+	tasks []Task
+}{
+	{"", nil},
+	{"- [ ] one\n- [x] two\n", []Task{{false, "one", 0}, {true, "two", 1}}},
+	{"1. [X] one\r\n* [ ]  two  \r\n", []Task{{true, "one", 0}, {false, "two", 1}}},
+	{"- [] not a task\n-[ ] nor this\n", nil},
+	{"```\n- [ ] code\n```\n- [ ] task\n", []Task{{false, "task", 3}}},
+	{"~~~\n- [ ] code\n~~~\n- [ ] task\n", []Task{{false, "task", 3}}},
+	{"  ```go\n- [ ] code\n  ```\n- [x] task\n", []Task{{true, "task", 3}}},
+	{"~~~\n- [ ] code, never closed\n", nil},
+}
+
+func TestParseTasks(t *testing.T) {
+	for _, tt := range parseTasksTests {
+		var tasks []Task
+		for _, task := range parseTasks(tt.body) {
+			tasks = append(tasks, *task)
+		}
+		if !reflect.DeepEqual(tasks, tt.tasks) {
+			t.Errorf("parseTasks(%q) = %+v, want %+v", tt.body, tasks, tt.tasks)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// twTime is the time format of Taskwarrior's JSON.
+const twTime = "20060102T150405Z"
+
+// A twTask is a task in Taskwarrior's JSON import format.
+type twTask struct {
+	UUID        string         `json:"uuid"`
+	Description string         `json:"description"`
+	Status      string         `json:"status"`
+	Entry       string         `json:"entry"`
+	End         string         `json:"end,omitempty"`
+	Project     string         `json:"project,omitempty"`
+	Tags        []string       `json:"tags,omitempty"`
+	Annotations []twAnnotation `json:"annotations,omitempty"`
+}
+
+type twAnnotation struct {
+	Entry       string `json:"entry"`
+	Description string `json:"description"`
+}
+
+// twTagRE matches the characters not allowed in Taskwarrior tags.
+var twTagRE = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// urlNamespace is the RFC 4122 name space for URLs.
+var urlNamespace = []byte{0x6b, 0xa7, 0xb8, 0x11, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+
+// issueUUID returns the task UUID for issue n in project:
+// a name-based (version 5) UUID of the issue URL,
+// so that repeated syncs update the same task.
+func issueUUID(project string, n int) string {
+	h := sha1.New()
+	h.Write(urlNamespace)
+	fmt.Fprintf(h, "https://github.com/%s/issues/%d", project, n)
+	u := h.Sum(nil)[:16]
+	u[6] = u[6]&0x0f | 0x50
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+func issueTask(project string, issue *github.Issue) *twTask {
+	n := getInt(issue.Number)
+	t := &twTask{
+		UUID:        issueUUID(project, n),
+		Description: getString(issue.Title),
+		Status:      "pending",
+		Entry:       getTime(issue.CreatedAt).UTC().Format(twTime),
+		Project:     getMilestoneTitle(issue.Milestone),
+		Annotations: []twAnnotation{{
+			Entry:       getTime(issue.CreatedAt).UTC().Format(twTime),
+			Description: issueURL(project, n),
+		}},
+	}
+	if getString(issue.State) == "closed" {
+		t.Status = "completed"
+		t.End = getTime(issue.ClosedAt).UTC().Format(twTime)
+	}
+	for _, name := range getLabelNames(issue.Labels) {
+		t.Tags = append(t.Tags, strings.Trim(twTagRE.ReplaceAllString(name, "_"), "_"))
+	}
+	return t
+}
+
+// twStatus returns the status of each existing Taskwarrior task, by UUID.
+func twStatus() (map[string]string, error) {
+	out, err := exec.Command("task", "rc.verbose=nothing", "rc.confirmation=off", "rc.json.array=on", "export").Output()
+	if err != nil {
+		return nil, fmt.Errorf("task export: %v", err)
+	}
+	var tasks []twTask
+	if err := json.Unmarshal(out, &tasks); err != nil {
+		return nil, fmt.Errorf("task export: %v", err)
+	}
+	status := make(map[string]string)
+	for _, t := range tasks {
+		status[t.UUID] = t.Status
+	}
+	return status, nil
+}
+
+func runTWSync(project string, args []string) {
+	fs := lookupCommand("tw-sync").flags()
+	closeDone := fs.Bool("close", false, "close the issues whose tasks have been completed")
+	dryRun := fs.Bool("n", false, "print the tasks to import instead of importing them")
+	parseFlags(fs, args)
+
+	all, err := searchIssues(project, strings.Join(fs.Args(), " "))
+	if err != nil {
+		fatal(err)
+	}
+	status, err := twStatus()
+	if err != nil {
+		fatal(err)
+	}
+
+	tasks := []*twTask{} // non-nil for json
+	failed := false
+	for _, issue := range all {
+		t := issueTask(project, issue)
+		if status[t.UUID] == "completed" && t.Status == "pending" {
+			// Completed locally but still open on GitHub.
+			if !*closeDone {
+				continue
+			}
+			n := getInt(issue.Number)
+			if *dryRun {
+				log.Printf("would close #%d", n)
+				continue
+			}
+			state := "closed"
+			err := putIssue(project, n, &hookChange{State: getString(issue.State), NewState: &state}, func() ([]string, error) {
+				var err error
+				issue, _, err = client.Issues.Edit(context.TODO(), projectOwner(project), projectRepo(project), n, &github.IssueRequest{State: &state})
+				if err != nil {
+					return nil, err
+				}
+				return []string{"closed"}, nil
+			})
+			if err != nil {
+				log.Printf("#%d: %v", n, err)
+				failed = true
+				continue
+			}
+			log.Printf("closed #%d", n)
+			t = issueTask(project, issue)
+		}
+		tasks = append(tasks, t)
+	}
+
+	data, err := json.MarshalIndent(tasks, "", "\t")
+	if err != nil {
+		fatal(err)
+	}
+	data = append(data, '\n')
+	if *dryRun {
+		os.Stdout.Write(data)
+	} else {
+		cmd := exec.Command("task", "rc.verbose=nothing", "rc.confirmation=off", "import", "-")
+		cmd.Stdin = bytes.NewReader(data)
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			fatalf("task import: %v", err)
+		}
+		log.Printf("synced %d issue%s", len(tasks), suffix(len(tasks)))
+	}
+	if failed {
+		exit(exitError)
+	}
+}
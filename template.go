@@ -0,0 +1,458 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+	"gopkg.in/yaml.v3"
+)
+
+// An issueTemplate is one of a repository's issue templates:
+// either a Markdown template, whose text becomes the issue body,
+// or an issue form, whose fields are filled in one by one.
+type issueTemplate struct {
+	Name        string           `yaml:"name"`
+	About       string           `yaml:"about"`       // Markdown templates
+	Description string           `yaml:"description"` // issue forms
+	Title       string           `yaml:"title"`
+	Labels      stringList       `yaml:"labels"`
+	Assignees   stringList       `yaml:"assignees"`
+	Body        []*templateField `yaml:"body"` // issue forms
+
+	file string
+	text string // body of a Markdown template
+}
+
+// A templateField is an element of an issue form.
+type templateField struct {
+	Type       string `yaml:"type"` // markdown, input, textarea, dropdown, or checkboxes
+	ID         string `yaml:"id"`
+	Attributes struct {
+		Label       string            `yaml:"label"`
+		Description string            `yaml:"description"`
+		Placeholder string            `yaml:"placeholder"`
+		Value       string            `yaml:"value"`
+		Render      string            `yaml:"render"`
+		Multiple    bool              `yaml:"multiple"`
+		Options     []*templateOption `yaml:"options"`
+	} `yaml:"attributes"`
+	Validations struct {
+		Required bool `yaml:"required"`
+	} `yaml:"validations"`
+}
+
+// A templateOption is a dropdown choice or a checkbox.
+// Dropdown options are written as plain strings.
+type templateOption struct {
+	Label    string `yaml:"label"`
+	Required bool   `yaml:"required"`
+}
+
+func (o *templateOption) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		o.Label = n.Value
+		return nil
+	}
+	type option templateOption
+	return n.Decode((*option)(o))
+}
+
+// noResponse is the text GitHub uses for an unanswered form field.
+const noResponse = "_No response_"
+
+// loadTemplates fetches the issue templates in the project's
+// .github/ISSUE_TEMPLATE directory.
+func loadTemplates(project string) ([]*issueTemplate, error) {
+	const dir = ".github/ISSUE_TEMPLATE"
+	_, list, resp, err := client.Repositories.GetContents(context.TODO(), projectOwner(project), projectRepo(project), dir, nil)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var templates []*issueTemplate
+	for _, entry := range list {
+		name := entry.GetName()
+		ext := path.Ext(name)
+		if entry.GetType() != "file" || ext != ".md" && ext != ".yml" && ext != ".yaml" || strings.TrimSuffix(name, ext) == "config" {
+			continue
+		}
+		file, _, _, err := client.Repositories.GetContents(context.TODO(), projectOwner(project), projectRepo(project), entry.GetPath(), nil)
+		if err != nil {
+			return nil, err
+		}
+		data, err := file.GetContent()
+		if err != nil {
+			return nil, err
+		}
+		t := &issueTemplate{file: name}
+		if ext == ".md" {
+			// The front matter has the same syntax as for create -f,
+			// with more fields.
+			data = strings.Replace(data, "\r\n", "\n", -1)
+			if strings.HasPrefix(data, "---\n") {
+				if i := strings.Index(data[4:], "\n---\n"); i >= 0 {
+					if err := yaml.Unmarshal([]byte(data[4:4+i+1]), t); err != nil {
+						return nil, fmt.Errorf("%s: %v", name, err)
+					}
+					data = data[4+i+len("\n---\n"):]
+				}
+			}
+			t.Body = nil
+			t.text = data
+		} else if err := yaml.Unmarshal([]byte(data), t); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		if t.Name == "" {
+			t.Name = strings.TrimSuffix(name, ext)
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+// findTemplate returns the project's issue template with the given
+// name or file name, ignoring case.
+func findTemplate(project, name string) (*issueTemplate, error) {
+	templates, err := loadTemplates(project)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, t := range templates {
+		if strings.EqualFold(t.Name, name) || strings.EqualFold(t.file, name) || strings.EqualFold(strings.TrimSuffix(t.file, path.Ext(t.file)), name) {
+			return t, nil
+		}
+		names = append(names, strconv.Quote(t.Name))
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%s has no issue templates", project)
+	}
+	return nil, fmt.Errorf("%s has no issue template %q; templates are %s", project, name, strings.Join(names, ", "))
+}
+
+// fields returns the fields of the form that take input.
+func (t *issueTemplate) fields() []*templateField {
+	var out []*templateField
+	for _, f := range t.Body {
+		if f.Type != "markdown" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// marker returns the fill-in marker left in the editor for the field.
+func (f *templateField) marker() string {
+	text := f.Attributes.Placeholder
+	if f.Type == "dropdown" {
+		var opts []string
+		for _, o := range f.Attributes.Options {
+			opts = append(opts, o.Label)
+		}
+		text = "one of: " + strings.Join(opts, ", ")
+	}
+	if text == "" {
+		text = f.Attributes.Description
+	}
+	if text == "" {
+		text = "fill in"
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	if f.Validations.Required {
+		text += " (required)"
+	}
+	return "<" + text + ">"
+}
+
+// answer formats the response to a field as GitHub does in the issue body.
+func (f *templateField) answer(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return noResponse
+	}
+	if f.Attributes.Render != "" {
+		return "```" + f.Attributes.Render + "\n" + value + "\n```"
+	}
+	return value
+}
+
+// formBody assembles the issue body from the answers to the form's
+// fields, in order.
+func formBody(t *issueTemplate, answers []string) string {
+	var buf bytes.Buffer
+	for i, f := range t.fields() {
+		fmt.Fprintf(&buf, "### %s\n\n%s\n\n", f.Attributes.Label, answers[i])
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// editorText returns the text of the template for the system editor:
+// a Markdown template's text, or the form's sections, each holding
+// the field's default value or a fill-in marker.
+func (t *issueTemplate) editorText() string {
+	if t.Body == nil {
+		return t.text
+	}
+	var answers []string
+	for _, f := range t.fields() {
+		switch f.Type {
+		case "checkboxes":
+			var lines []string
+			for _, o := range f.Attributes.Options {
+				lines = append(lines, "- [ ] "+o.Label)
+			}
+			answers = append(answers, strings.Join(lines, "\n"))
+		case "dropdown":
+			answers = append(answers, f.marker())
+		default:
+			if v := f.Attributes.Value; v != "" {
+				answers = append(answers, f.answer(v))
+			} else {
+				answers = append(answers, f.marker())
+			}
+		}
+	}
+	return formBody(t, answers)
+}
+
+// checkForm reports the required fields of the form left unanswered
+// in body, which was made from editorText.
+func checkForm(t *issueTemplate, body string) error {
+	// Split the body into sections by their ### headings.
+	sections := make(map[string]string)
+	label := ""
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, "### ") {
+			label = strings.TrimSpace(line[4:])
+			continue
+		}
+		sections[label] += line + "\n"
+	}
+	var missing []string
+	for _, f := range t.fields() {
+		text, ok := sections[f.Attributes.Label]
+		text = strings.TrimSpace(text)
+		if f.Type == "checkboxes" {
+			for _, o := range f.Attributes.Options {
+				if o.Required && !strings.Contains(text, "- [x] "+o.Label) && !strings.Contains(text, "- [X] "+o.Label) {
+					missing = append(missing, fmt.Sprintf("%s: %s", f.Attributes.Label, o.Label))
+				}
+			}
+			continue
+		}
+		if !f.Validations.Required {
+			continue
+		}
+		if !ok || text == "" || text == noResponse || text == f.marker() {
+			missing = append(missing, f.Attributes.Label)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("required fields not filled in: %s", strings.Join(missing, "; "))
+	}
+	return nil
+}
+
+// clearMarkers returns body, made from editorText, with the fill-in
+// markers left in optional fields replaced as GitHub would.
+func clearMarkers(t *issueTemplate, body string) string {
+	for _, f := range t.fields() {
+		body = strings.Replace(body, "\n"+f.marker()+"\n", "\n"+noResponse+"\n", -1)
+	}
+	return body
+}
+
+// promptForm asks for the value of each of the form's fields on w,
+// reading the answers from r, and returns the assembled issue body.
+func promptForm(t *issueTemplate, r *bufio.Reader, w io.Writer) (string, error) {
+	readLine := func() (string, error) {
+		line, err := r.ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("template %s not completed", t.Name)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+	var answers []string
+	for _, f := range t.fields() {
+		a := f.Attributes
+		fmt.Fprintf(w, "\n%s", a.Label)
+		if f.Validations.Required {
+			fmt.Fprintf(w, " (required)")
+		}
+		fmt.Fprintf(w, "\n")
+		if a.Description != "" {
+			fmt.Fprintf(w, "%s\n", strings.TrimSpace(a.Description))
+		}
+		for {
+			var answer, problem string
+			switch f.Type {
+			case "checkboxes":
+				var lines []string
+				for _, o := range a.Options {
+					fmt.Fprintf(w, "  %s? [y/N] ", o.Label)
+					line, err := readLine()
+					if err != nil {
+						return "", err
+					}
+					box := " "
+					if ans := strings.ToLower(strings.TrimSpace(line)); ans == "y" || ans == "yes" {
+						box = "X"
+					} else if o.Required {
+						problem = o.Label + " is required"
+					}
+					lines = append(lines, fmt.Sprintf("- [%s] %s", box, o.Label))
+				}
+				answer = strings.Join(lines, "\n")
+			case "dropdown":
+				for i, o := range a.Options {
+					fmt.Fprintf(w, "  %d. %s\n", i+1, o.Label)
+				}
+				if a.Multiple {
+					fmt.Fprintf(w, "choices (numbers separated by spaces): ")
+				} else {
+					fmt.Fprintf(w, "choice: ")
+				}
+				line, err := readLine()
+				if err != nil {
+					return "", err
+				}
+				var chosen []string
+				for _, s := range strings.Fields(line) {
+					i, err := strconv.Atoi(s)
+					if err != nil || i < 1 || i > len(a.Options) || !a.Multiple && len(chosen) > 0 {
+						chosen = nil
+						problem = "invalid choice"
+						break
+					}
+					chosen = append(chosen, a.Options[i-1].Label)
+				}
+				answer = strings.Join(chosen, ", ")
+			case "textarea":
+				fmt.Fprintf(w, "(end with a line containing only a period)\n")
+				var lines []string
+				for {
+					line, err := readLine()
+					if err != nil {
+						return "", err
+					}
+					if line == "." {
+						break
+					}
+					lines = append(lines, line)
+				}
+				answer = strings.Join(lines, "\n")
+			default:
+				if a.Value != "" {
+					fmt.Fprintf(w, "[%s] ", a.Value)
+				}
+				line, err := readLine()
+				if err != nil {
+					return "", err
+				}
+				if answer = strings.TrimSpace(line); answer == "" {
+					answer = a.Value
+				}
+			}
+			if strings.TrimSpace(answer) == "" && f.Validations.Required && problem == "" {
+				problem = a.Label + " is required"
+			}
+			if problem == "" {
+				answers = append(answers, f.answer(answer))
+				break
+			}
+			fmt.Fprintf(w, "%s; try again\n", problem)
+		}
+	}
+	return formBody(t, answers), nil
+}
+
+// promptTemplate prompts on the terminal for the title, if meta has none,
+// and the fields of the issue form t, returning the issue body.
+// It fills in the template's labels and assignees unless meta has its own.
+func promptTemplate(t *issueTemplate, meta *issueFile) (string, error) {
+	r := bufio.NewReader(os.Stdin)
+	if len(meta.Labels) == 0 {
+		meta.Labels = t.Labels
+	}
+	if meta.Assignee == "" && len(meta.Assignees) == 0 {
+		meta.Assignees = t.Assignees
+	}
+	for meta.Title == "" {
+		fmt.Fprintf(os.Stderr, "Title: %s", t.Title)
+		line, err := r.ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("no title given")
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			meta.Title = t.Title + line
+		}
+	}
+	return promptForm(t, r, os.Stderr)
+}
+
+// createFromTemplate creates an issue by filling in the template t
+// in the system editor, editing it again until the form's required
+// fields are filled in. The metadata in meta, from the command line,
+// overrides the template's.
+func createFromTemplate(project string, t *issueTemplate, meta *issueFile) {
+	title, labels, assignees := t.Title, t.Labels, t.Assignees
+	if meta.Title != "" {
+		title = meta.Title
+	}
+	if len(meta.Labels) > 0 {
+		labels = meta.Labels
+	}
+	if meta.Assignee != "" {
+		assignees = stringList{meta.Assignee}
+	}
+	assignee := ""
+	if len(assignees) > 0 {
+		assignee = assignees[0]
+	}
+	text := []byte(fmt.Sprintf("Title: %s\nAssignee: %s\nLabels: %s\nMilestone: %s\n\n%s\n",
+		title, assignee, strings.Join(labels, " "), meta.Milestone, strings.TrimSpace(t.editorText())))
+	var formErr error
+	for {
+		updated := editText(text)
+		if bytes.Equal(text, updated) {
+			if formErr != nil {
+				fatal(formErr)
+			}
+			log.Print("no changes made")
+			return
+		}
+		text = updated
+		body := ""
+		if i := bytes.Index(updated, []byte("\n\n")); i >= 0 {
+			body = string(updated[i+2:])
+		}
+		if formErr = checkForm(t, body); formErr == nil {
+			if t.Body != nil {
+				text = []byte(string(updated[:len(updated)-len(body)]) + clearMarkers(t, body))
+			}
+			break
+		}
+		log.Printf("%v; editing again", formErr)
+	}
+	issue, _, err := writeIssue(project, new(github.Issue), text, false)
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Println(issueURL(project, getInt(issue.Number)))
+}
@@ -0,0 +1,274 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// termLinks reports whether output should render issue numbers
+// and URLs as OSC 8 terminal hyperlinks.
+// It is set by main when standard output is a capable terminal.
+var termLinks bool
+
+// isTerminal reports whether f is a terminal.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// supportsHyperlinks reports whether the terminal described by
+// the environment is likely to understand OSC 8 hyperlinks.
+// Terminals that do not understand them usually ignore them,
+// but a few print them as garbage, so stay away from those.
+func supportsHyperlinks() bool {
+	switch term := os.Getenv("TERM"); {
+	case term == "", term == "dumb", term == "linux", strings.HasPrefix(term, "vt"):
+		return false
+	}
+	return true
+}
+
+// hyperlink returns text marked up as a hyperlink to url,
+// or text unchanged if hyperlinks are disabled.
+func hyperlink(url, text string) string {
+	if !termLinks {
+		return text
+	}
+	return "\x1b]8;;" + url + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
+
+func issueURL(project string, n int) string {
+	return fmt.Sprintf("%s%s/%s/issues/%d", webURL(), projectOwner(project), projectRepo(project), n)
+}
+
+// linkRE matches the text that linkify turns into hyperlinks:
+// URLs and references to issues, either #nnnn or owner/repo#nnnn.
+var linkRE = regexp.MustCompile(`https?://[^\s<>"'\]]*[^\s<>"'\].,;:!?)]|(?:\b([A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+))?#([0-9]+)\b`)
+
+// linkify returns text with URLs and issue references made into hyperlinks.
+// Issue references without an explicit repository refer to project.
+func linkify(project, text string) string {
+	if !termLinks {
+		return text
+	}
+	return linkRE.ReplaceAllStringFunc(text, func(s string) string {
+		m := linkRE.FindStringSubmatch(s)
+		if m[2] == "" {
+			return hyperlink(s, s)
+		}
+		repo := project
+		if m[1] != "" {
+			repo = m[1]
+		}
+		return hyperlink(webURL()+repo+"/issues/"+m[2], s)
+	})
+}
+
+var wrapCache struct {
+	once  sync.Once
+	width int
+}
+
+// wrapWidth returns the column at which wrap breaks lines,
+// or 0 if it should not break them: the -wrap setting,
+// or 100 in acme, or the width of the terminal on standard output,
+// less a tab for the indentation, or else 70, as in -tui's narrow pane.
+func wrapWidth() int {
+	wrapCache.once.Do(func() {
+		switch {
+		case *noWrap:
+			wrapCache.width = 0
+		case *wrapFlag > 0:
+			wrapCache.width = *wrapFlag
+		case *acmeFlag:
+			wrapCache.width = 100
+		default:
+			wrapCache.width = 70
+			if w := terminalWidth(); w > 0 && !*tuiFlag {
+				wrapCache.width = w - 8
+				if wrapCache.width < 20 {
+					wrapCache.width = 20
+				}
+			}
+		}
+	})
+	return wrapCache.width
+}
+
+// terminalWidth returns the width of the terminal on standard output,
+// from $COLUMNS or else stty, or 0 if output is not to a terminal.
+func terminalWidth() int {
+	if !isTerminal(os.Stdout) {
+		return 0
+	}
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	cmd := exec.Command("stty", "size")
+	cmd.Stdin = os.Stdout
+	out, err := cmd.Output()
+	if err != nil {
+		return 0
+	}
+	f := strings.Fields(string(out))
+	if len(f) != 2 {
+		return 0
+	}
+	n, _ := strconv.Atoi(f[1])
+	return n
+}
+
+// termColor reports whether output should be colored.
+// It is set by main according to the -color flag.
+var termColor bool
+
+// useColor reports whether to color output written to standard output,
+// given the -color flag setting: "always", "never", or "auto".
+// In auto mode, output is colored when it goes to a terminal
+// and the NO_COLOR environment variable is unset (see https://no-color.org/).
+func useColor(mode string) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	return os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb" && isTerminal(os.Stdout)
+}
+
+const (
+	sgrReset   = "\x1b[0m"
+	sgrBold    = "\x1b[1m"
+	sgrDim     = "\x1b[2m"
+	sgrRed     = "\x1b[31m"
+	sgrGreen   = "\x1b[32m"
+	sgrMagenta = "\x1b[35m"
+	sgrCyan    = "\x1b[36m"
+)
+
+// colorize returns s wrapped in the given SGR escape sequence,
+// or s unchanged if color is disabled.
+func colorize(sgr, s string) string {
+	if !termColor || s == "" {
+		return s
+	}
+	return sgr + s + sgrReset
+}
+
+// stateColor returns the escape sequence for an issue state.
+func stateColor(state string) string {
+	if state == "closed" {
+		return sgrRed
+	}
+	return sgrGreen
+}
+
+// labelColor returns the escape sequence that draws text
+// in the label's repository color, using black or white text
+// for contrast, or the empty string if the color is unknown.
+func labelColor(hex string) string {
+	var r, g, b int
+	if _, err := fmt.Sscanf(hex, "%02x%02x%02x", &r, &g, &b); err != nil {
+		return ""
+	}
+	fg := "38;2;255;255;255"
+	if r*299+g*587+b*114 > 128*1000 {
+		fg = "38;2;0;0;0"
+	}
+	return fmt.Sprintf("\x1b[48;2;%d;%d;%d;%sm", r, g, b, fg)
+}
+
+// colorIssue colors the printed form of issue:
+// header names, the state, the labels, the lines introducing
+// the report, each comment, and each event, and the code blocks
+// and stack traces in the text.
+func colorIssue(text string, issue *github.Issue) string {
+	if !termColor {
+		return text
+	}
+	colors := make(map[string]string)
+	for _, lab := range issue.Labels {
+		colors[getString(lab.Name)] = labelColor(getString(lab.Color))
+	}
+	lines := strings.SplitAfter(text, "\n")
+	header := true
+	var code *highlighter // current code block or stack trace
+	fenced := false
+	for i, line := range lines {
+		if !header {
+			// Highlight fenced code blocks and stack traces
+			// in the indented text of the report and comments.
+			body := strings.TrimSuffix(line, "\n")
+			text := strings.TrimPrefix(body, "\t")
+			indent, nl := body[:len(body)-len(text)], line[len(body):]
+			if strings.HasPrefix(body, "Reported by ") || strings.HasPrefix(body, "Comment by ") {
+				code, fenced = nil, false
+			}
+			if info, ok := isFence(text); ok {
+				if fenced {
+					code, fenced = nil, false
+				} else {
+					code, fenced = newHighlighter(info, lines[i+1:]), true
+				}
+				lines[i] = indent + colorize(sgrDim, text) + nl
+				continue
+			}
+			if !fenced {
+				if code != nil && text != "" && !isTraceLine(text) {
+					code = nil
+				}
+				if code == nil && traceStartRE.MatchString(text) {
+					code = &highlighter{kind: "trace"}
+				}
+			}
+			if code != nil {
+				lines[i] = indent + code.line(text) + nl
+				continue
+			}
+		}
+		if strings.TrimSpace(line) == "" {
+			header = false
+			continue
+		}
+		if header {
+			j := strings.Index(line, ":")
+			if j < 0 {
+				continue
+			}
+			key, val := line[:j+1], strings.TrimSuffix(line[j+1:], "\n")
+			switch key {
+			case "State:":
+				val = " " + colorize(stateColor(strings.TrimSpace(val)), strings.TrimSpace(val))
+			case "Labels:":
+				var out []string
+				for _, name := range strings.Fields(val) {
+					if c := colors[name]; c != "" {
+						name = colorize(c, " "+name+" ")
+					}
+					out = append(out, name)
+				}
+				val = " " + strings.Join(out, " ")
+			}
+			lines[i] = colorize(sgrBold, key) + val + "\n"
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "Reported by "), strings.HasPrefix(line, "Comment by "):
+			lines[i] = colorize(sgrBold+sgrCyan, strings.TrimSuffix(line, "\n")) + "\n"
+		case strings.HasPrefix(line, "* "):
+			lines[i] = colorize(sgrDim, strings.TrimSuffix(line, "\n")) + "\n"
+		}
+	}
+	return strings.Join(lines, "")
+}
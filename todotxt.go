@@ -0,0 +1,102 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// todoTagRE matches the characters not allowed in todo.txt tags.
+var todoTagRE = regexp.MustCompile(`\s+`)
+
+// todoLine returns the todo.txt line for issue:
+// its creation date and title, the project as a +project tag,
+// its labels as @context tags, and an issue:owner/repo#n key
+// identifying the issue.
+func todoLine(project string, issue *github.Issue) string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %s +%s", getTime(issue.CreatedAt).Format("2006-01-02"), strings.Join(strings.Fields(getString(issue.Title)), " "), project)
+	for _, name := range getLabelNames(issue.Labels) {
+		fmt.Fprintf(&buf, " @%s", todoTagRE.ReplaceAllString(name, "_"))
+	}
+	fmt.Fprintf(&buf, " %s", todoKey(project, getInt(issue.Number)))
+	return buf.String()
+}
+
+func todoKey(project string, n int) string {
+	return fmt.Sprintf("issue:%s#%d", project, n)
+}
+
+func runTodo(project string, args []string) {
+	fs := lookupCommand("todo").flags()
+	file := fs.String("o", "", "update the issue lines in todo.txt `file` instead of printing them")
+	parseFlags(fs, args)
+
+	q := strings.Join(fs.Args(), " ")
+	if q == "" {
+		me, err := selfLogin()
+		if err != nil {
+			fatal(err)
+		}
+		q = "assignee:" + me
+	}
+	all, err := searchIssues(project, q)
+	if err != nil {
+		fatal(err)
+	}
+	sort.Slice(all, func(i, j int) bool { return getInt(all[i].Number) < getInt(all[j].Number) })
+	var lines []string
+	for _, issue := range all {
+		if getString(issue.State) == "open" {
+			lines = append(lines, todoLine(project, issue))
+		}
+	}
+
+	if *file == "" {
+		for _, line := range lines {
+			fmt.Println(line)
+		}
+		return
+	}
+
+	// Keep the file's other lines, replacing those for this
+	// project's issues, so that the file can be regenerated
+	// without disturbing other tasks.
+	data, err := ioutil.ReadFile(*file)
+	if err != nil && !os.IsNotExist(err) {
+		fatal(err)
+	}
+	var out []string
+	prefix := fmt.Sprintf("issue:%s#", project)
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" || todoHasKey(line, prefix) {
+			continue
+		}
+		out = append(out, line)
+	}
+	out = append(out, lines...)
+	if err := ioutil.WriteFile(*file, []byte(strings.Join(out, "\n")+"\n"), 0666); err != nil {
+		fatal(err)
+	}
+}
+
+// todoHasKey reports whether the todo.txt line has a word
+// beginning with prefix.
+func todoHasKey(line, prefix string) bool {
+	for _, f := range strings.Fields(line) {
+		if strings.HasPrefix(f, prefix) {
+			return true
+		}
+	}
+	return false
+}
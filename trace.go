@@ -0,0 +1,185 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"9fans.net/go/acme"
+	"github.com/google/go-github/v45/github"
+)
+
+// A traceFrame is a source position in a stack trace,
+// resolved to a file in the project's repository.
+type traceFrame struct {
+	File string // path in the stack trace
+	Line int
+	Path string // path in the repository
+	Ref  string // version of the file, or "" for the default branch
+}
+
+// frameRE matches the file:line position of a Go stack frame.
+var frameRE = regexp.MustCompile(`([^\s()"']+\.go):([0-9]+)\b`)
+
+// goVersionRE matches the "go version" output that golang/go issue
+// reports include, as in "go version go1.21.3 linux/amd64" or
+// "go version devel go1.22-abc1234 Tue Aug 1 ...".
+var goVersionRE = regexp.MustCompile(`go version (?:(go[0-9][0-9.a-z]*)|devel (?:go[0-9.]+-|\+)([0-9a-f]+))`)
+
+// pseudoVersionRE matches the commit hash at the end of a module pseudo-version.
+var pseudoVersionRE = regexp.MustCompile(`-[0-9]{14}-([0-9a-f]{12})$`)
+
+// traceFrames returns the stack frames in text that can be located
+// in the project's repository, each at most once, in order.
+// Files in the module cache are located at the module version;
+// for golang/go, files in GOROOT are located at the Go version
+// reported in text, if any.
+func traceFrames(project, text string) []*traceFrame {
+	goRef := ""
+	if m := goVersionRE.FindStringSubmatch(text); m != nil {
+		goRef = m[1] + m[2]
+	}
+	var frames []*traceFrame
+	seen := make(map[string]bool)
+	for _, m := range frameRE.FindAllStringSubmatch(text, -1) {
+		file, line := m[1], m[2]
+		if seen[file+":"+line] {
+			continue
+		}
+		seen[file+":"+line] = true
+		f := &traceFrame{File: file}
+		f.Line, _ = strconv.Atoi(line)
+		file = unescapeModulePath(strings.Replace(file, `\`, "/", -1)) // Windows
+		if i := strings.Index(strings.ToLower(file), strings.ToLower(project)+"@"); i >= 0 {
+			rest := file[i+len(project)+1:]
+			j := strings.Index(rest, "/")
+			if j < 0 {
+				continue
+			}
+			f.Ref, f.Path = strings.TrimSuffix(rest[:j], "+incompatible"), rest[j+1:]
+			if m := pseudoVersionRE.FindStringSubmatch(f.Ref); m != nil {
+				f.Ref = m[1]
+			}
+		} else if i := strings.Index(file, project+"/"); i >= 0 {
+			f.Path = file[i+len(project)+1:]
+		} else if i := strings.Index(file, "/src/"); i >= 0 && project == "golang/go" && isStdPath(file[i+len("/src/"):]) {
+			f.Path, f.Ref = file[i+1:], goRef
+		} else {
+			continue
+		}
+		frames = append(frames, f)
+	}
+	return frames
+}
+
+// unescapeModulePath undoes the escaping of upper-case letters
+// in module cache paths, where "!x" stands for "X".
+func unescapeModulePath(path string) string {
+	if !strings.Contains(path, "/pkg/mod/") {
+		return path
+	}
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '!' && i+1 < len(path) && 'a' <= path[i+1] && path[i+1] <= 'z' {
+			i++
+			b.WriteByte(path[i] - 'a' + 'A')
+			continue
+		}
+		b.WriteByte(path[i])
+	}
+	return b.String()
+}
+
+// isStdPath reports whether path, relative to a src directory, is
+// in the standard library or commands rather than in GOPATH:
+// its first element has no dot, as in runtime/panic.go.
+func isStdPath(path string) bool {
+	elem, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(elem, ".")
+}
+
+// locate reports the URL of the frame's position in the repository,
+// using the contents API to check that the file exists at the
+// frame's version, and returns the file's content.
+func (f *traceFrame) locate(project string) (url string, content string, err error) {
+	file, _, _, err := client.Repositories.GetContents(context.TODO(), projectOwner(project), projectRepo(project), f.Path, &github.RepositoryContentGetOptions{Ref: f.Ref})
+	if err != nil {
+		return "", "", err
+	}
+	if file == nil {
+		return "", "", fmt.Errorf("%s is a directory", f.Path)
+	}
+	content, err = file.GetContent()
+	return fmt.Sprintf("%s#L%d", file.GetHTMLURL(), f.Line), content, err
+}
+
+// openTraceWindow opens an acme window showing content,
+// the text of the frame's file, with the frame's line selected.
+func openTraceWindow(project string, f *traceFrame, content string) error {
+	ref := f.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	name := fmt.Sprintf("/issue/%s/blob/%s/%s", project, ref, f.Path)
+	w := acme.Show(name)
+	if w == nil {
+		var err error
+		if w, err = acme.New(); err != nil {
+			return err
+		}
+		w.Name(name)
+		w.Write("body", []byte(content))
+		w.Ctl("clean")
+	}
+	w.Addr("%d", f.Line)
+	w.Ctl("dot=addr")
+	w.Ctl("show")
+	return nil
+}
+
+func runTrace(project string, args []string) {
+	fs := lookupCommand("trace").flags()
+	openAcme := fs.Bool("a", false, "open each position in an acme window instead of printing links")
+	ref := fs.String("ref", "", "locate every frame at the git `ref`, such as a tag or commit")
+	parseFlags(fs, args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+	}
+	n := issueArgs(fs, fs.Args())[0]
+	issue, err := getIssue(project, n)
+	if err != nil {
+		fatal(err)
+	}
+	frames := traceFrames(project, getString(issue.Body))
+	if len(frames) == 0 {
+		fatalf("no stack frames in %s found in the text of #%d", project, n)
+	}
+	failed := false
+	for _, f := range frames {
+		if *ref != "" {
+			f.Ref = *ref
+		}
+		url, content, err := f.locate(project)
+		if err != nil {
+			fmt.Printf("%s:%d\t%v\n", f.File, f.Line, err)
+			failed = true
+			continue
+		}
+		if *openAcme {
+			if err := openTraceWindow(project, f, content); err != nil {
+				fatal(err)
+			}
+			continue
+		}
+		fmt.Printf("%s:%d\t%s\n", f.File, f.Line, url)
+	}
+	if failed {
+		exit(exitError)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// A triageChange is the change that the labeling rules make to an issue.
+type triageChange struct {
+	labels    []string
+	milestone string
+	assignee  string
+}
+
+func (c *triageChange) empty() bool {
+	return len(c.labels) == 0 && c.milestone == "" && c.assignee == ""
+}
+
+func (c *triageChange) String() string {
+	var f []string
+	for _, name := range c.labels {
+		f = append(f, "+"+name)
+	}
+	if c.milestone != "" {
+		f = append(f, "milestone:"+c.milestone)
+	}
+	if c.assignee != "" {
+		f = append(f, "assignee:"+c.assignee)
+	}
+	return strings.Join(f, " ")
+}
+
+// matches reports whether the rule matches the issue.
+func (r *triageRule) matches(issue *github.Issue) bool {
+	return r.In != "body" && r.re.MatchString(getString(issue.Title)) ||
+		r.In != "title" && r.re.MatchString(getString(issue.Body))
+}
+
+// triageRules returns the change that rules make to issue.
+// Every matching rule adds its labels, but only the first matching
+// rule that sets a milestone or assignee is used, and only if the
+// issue has none already.
+func triageRules(rules []*triageRule, issue *github.Issue) *triageChange {
+	c := new(triageChange)
+	have := make(map[string]bool)
+	for _, name := range getLabelNames(issue.Labels) {
+		have[name] = true
+	}
+	for _, r := range rules {
+		if !r.matches(issue) {
+			continue
+		}
+		for _, name := range r.Labels {
+			if !have[name] {
+				have[name] = true
+				c.labels = append(c.labels, name)
+			}
+		}
+		if c.milestone == "" && issue.Milestone == nil {
+			c.milestone = r.Milestone
+		}
+		if c.assignee == "" && len(issue.Assignees) == 0 {
+			c.assignee = r.Assignee
+		}
+	}
+	return c
+}
+
+// applyTriage makes the change c to issue n.
+func applyTriage(project string, n int, c *triageChange) error {
+	var edit github.IssueRequest
+	if c.milestone != "" {
+		var errbuf bytes.Buffer
+		edit.Milestone = findMilestone(&errbuf, project, &c.milestone)
+		if edit.Milestone == nil {
+			return fmt.Errorf("%s", strings.TrimSpace(errbuf.String()))
+		}
+	}
+	if c.assignee != "" {
+		logins, err := resolveAssignees(project, []string{c.assignee})
+		if err != nil {
+			return err
+		}
+		edit.Assignees = &logins
+	}
+	change := &hookChange{Assignees: edit.Assignees, AddLabels: c.labels}
+	if edit.Milestone != nil {
+		change.Milestone = &c.milestone
+	}
+	return putIssue(project, n, change, func() ([]string, error) {
+		var did []string
+		if edit.Milestone != nil || edit.Assignees != nil {
+			if _, _, err := client.Issues.Edit(context.TODO(), projectOwner(project), projectRepo(project), n, &edit); err != nil {
+				return did, err
+			}
+			did = append(did, "updated metadata")
+		}
+		if len(c.labels) > 0 {
+			if _, _, err := client.Issues.AddLabelsToIssue(context.TODO(), projectOwner(project), projectRepo(project), n, c.labels); err != nil {
+				return did, err
+			}
+			did = append(did, "added label"+suffix(len(c.labels))+" "+strings.Join(c.labels, " "))
+		}
+		return did, nil
+	})
+}
+
+func runTriage(project string, args []string) {
+	fs := lookupCommand("triage").flags()
+	applyRules := fs.Bool("apply-rules", false, "apply the labeling rules from the configuration file")
+	dryRun := fs.Bool("n", false, "print the changes without making them")
+	parseFlags(fs, args)
+	if !*applyRules || fs.NArg() == 0 {
+		fs.Usage()
+	}
+	rules := loadConfig().Rules
+	if len(rules) == 0 {
+		file, _ := configFile()
+		fatalf("no rules configured in %s", file)
+	}
+
+	all, err := searchIssues(project, strings.Join(fs.Args(), " "))
+	if err != nil {
+		fatal(err)
+	}
+	if len(all) == 0 {
+		exit(exitNoMatch)
+	}
+	sort.Slice(all, func(i, j int) bool { return getInt(all[i].Number) < getInt(all[j].Number) })
+
+	changed, failed := 0, false
+	for _, issue := range all {
+		n := getInt(issue.Number)
+		c := triageRules(rules, issue)
+		if c.empty() {
+			continue
+		}
+		changed++
+		fmt.Printf("#%d\t%s\n\t%v\n", n, getString(issue.Title), c)
+		if *dryRun {
+			continue
+		}
+		if err := applyTriage(project, n, c); err != nil {
+			fmt.Printf("\terror: %v\n", err)
+			failed = true
+		}
+	}
+	verb := "changed"
+	if *dryRun {
+		verb = "would change"
+	}
+	fmt.Printf("%s %d of %d issue%s\n", verb, changed, len(all), suffix(len(all)))
+	if failed {
+		exit(exitError)
+	}
+}
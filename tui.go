@@ -0,0 +1,481 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// The -tui flag browses issues in a full-screen terminal interface,
+// for users who do not run acme: the matching issues are listed
+// on the left, and the selected issue's thread is shown on the right.
+// The terminal is put in raw mode using stty, as the editor and
+// pager are run as commands, to avoid depending on a terminal library.
+
+const tuiHelp = "j/k: select  space/b: scroll  o: open  c: comment  l: label  m: milestone  u: undo  r: reload  q: quit"
+
+// A tui is the state of the -tui display.
+type tui struct {
+	project string
+	query   string
+	issues  []*github.Issue
+	sel     int // index of the selected issue
+	top     int // index of the first issue shown in the list
+	scroll  int // first line of the thread shown
+	threads map[int][]string
+	status  string
+	undo    []*issueState // states before each hotkey action, latest last
+
+	rows, cols int
+	saved      string // stty settings to restore
+	pending    []byte // input not yet returned by key
+	out        *bufio.Writer
+}
+
+func tuiMode(project, q string) {
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		usageErrorf("-tui requires a terminal")
+	}
+	t := &tui{
+		project: project,
+		query:   q,
+		threads: make(map[int][]string),
+		out:     bufio.NewWriter(os.Stdout),
+	}
+	if err := t.load(); err != nil {
+		fatal(err)
+	}
+	if len(t.issues) == 0 {
+		log.Print("no issues matched search")
+		exit(exitNoMatch)
+	}
+	loadConfig() // report a bad configuration before taking over the terminal
+	if err := t.start(); err != nil {
+		fatal(err)
+	}
+	err := t.loop()
+	t.stop()
+	if err != nil {
+		fatal(err)
+	}
+}
+
+// stty runs the stty command on the terminal with the given arguments.
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("stty %s: %v", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// start puts the terminal in raw mode and switches to the alternate screen.
+func (t *tui) start() error {
+	if t.saved == "" {
+		saved, err := stty("-g")
+		if err != nil {
+			return err
+		}
+		t.saved = saved
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return err
+	}
+	fmt.Fprintf(t.out, "\x1b[?1049h\x1b[?25l")
+	return t.out.Flush()
+}
+
+// stop restores the terminal to the state start found it in.
+func (t *tui) stop() {
+	fmt.Fprintf(t.out, "\x1b[?25h\x1b[?1049l")
+	t.out.Flush()
+	stty(t.saved)
+}
+
+// load loads the issues matching the query, keeping the selection
+// on the same issue if it still matches.
+func (t *tui) load() error {
+	all, err := searchIssues(t.project, t.query)
+	if err != nil {
+		return err
+	}
+	sort.Sort(issuesByTitle(all))
+	cur := t.selected()
+	t.issues = all
+	t.sel = 0
+	for i, issue := range all {
+		if getInt(issue.Number) == cur {
+			t.sel = i
+		}
+	}
+	t.threads = make(map[int][]string)
+	return nil
+}
+
+// selected returns the number of the selected issue, or 0 if there is none.
+func (t *tui) selected() int {
+	if t.sel >= len(t.issues) {
+		return 0
+	}
+	return getInt(t.issues[t.sel].Number)
+}
+
+// thread returns the lines of the printed history of issue n,
+// loading it if needed.
+func (t *tui) thread(n int) []string {
+	if lines, ok := t.threads[n]; ok {
+		return lines
+	}
+	var buf bytes.Buffer
+	if _, err := showIssue(&buf, t.project, n, false); err != nil {
+		return []string{err.Error()}
+	}
+	text := strings.Replace(buf.String(), "\t", "    ", -1)
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	t.threads[n] = lines
+	return lines
+}
+
+// key returns the next key typed: a single character,
+// or the name of a special key, like "up" or "pgdn".
+func (t *tui) key() (string, error) {
+	// A read may return no bytes without an error,
+	// so keep reading until there is a key to decode.
+	for len(t.pending) == 0 {
+		buf := make([]byte, 64)
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return "", err
+		}
+		t.pending = buf[:n]
+	}
+	b := t.pending
+	if b[0] == '\x1b' && len(b) >= 3 && (b[1] == '[' || b[1] == 'O') {
+		for seq, name := range map[string]string{
+			"A": "up", "B": "down", "C": "right", "D": "left",
+			"5~": "pgup", "6~": "pgdn", "H": "home", "F": "end",
+		} {
+			if strings.HasPrefix(string(b[2:]), seq) {
+				t.pending = b[2+len(seq):]
+				return name, nil
+			}
+		}
+		t.pending = b[3:]
+		return "", nil
+	}
+	r, size := utf8.DecodeRune(b)
+	t.pending = b[size:]
+	switch r {
+	case '\r', '\n':
+		return "enter", nil
+	case '\x1b':
+		return "esc", nil
+	case '\x7f', '\b':
+		return "backspace", nil
+	case '\x03':
+		return "ctrl-c", nil
+	}
+	return string(r), nil
+}
+
+// size updates the screen size.
+func (t *tui) size() {
+	t.rows, t.cols = 24, 80
+	if s, err := stty("size"); err == nil {
+		if f := strings.Fields(s); len(f) == 2 {
+			rows, _ := strconv.Atoi(f[0])
+			cols, _ := strconv.Atoi(f[1])
+			if rows > 2 && cols > 20 {
+				t.rows, t.cols = rows, cols
+			}
+		}
+	}
+}
+
+// fit returns s truncated or padded with spaces to width w.
+func fit(s string, w int) string {
+	n := 0
+	for i := range s {
+		if n == w {
+			return s[:i]
+		}
+		n++
+	}
+	return s + strings.Repeat(" ", w-n)
+}
+
+// wrapLines breaks the lines into lines of at most w characters.
+func wrapLines(lines []string, w int) []string {
+	var out []string
+	for _, line := range lines {
+		for utf8.RuneCountInString(line) > w {
+			s := fit(line, w)
+			out = append(out, s)
+			line = line[len(s):]
+		}
+		out = append(out, line)
+	}
+	return out
+}
+
+// draw redraws the screen.
+func (t *tui) draw() {
+	t.size()
+	height := t.rows - 1
+	left := t.cols * 2 / 5
+	right := t.cols - left - 1
+
+	if t.sel < t.top {
+		t.top = t.sel
+	}
+	if t.sel >= t.top+height {
+		t.top = t.sel - height + 1
+	}
+	var thread []string
+	if n := t.selected(); n != 0 {
+		thread = wrapLines(t.thread(n), right)
+	}
+	if t.scroll > len(thread)-height {
+		t.scroll = len(thread) - height
+	}
+	if t.scroll < 0 {
+		t.scroll = 0
+	}
+
+	for row := 0; row < height; row++ {
+		fmt.Fprintf(t.out, "\x1b[%d;1H", row+1)
+		item := ""
+		if i := t.top + row; i < len(t.issues) {
+			item = fmt.Sprintf("%d %s", getInt(t.issues[i].Number), getString(t.issues[i].Title))
+		}
+		if t.top+row == t.sel {
+			fmt.Fprintf(t.out, "\x1b[7m%s\x1b[0m", fit(item, left))
+		} else {
+			fmt.Fprintf(t.out, "%s", fit(item, left))
+		}
+		line := ""
+		if i := t.scroll + row; i < len(thread) {
+			line = thread[i]
+		}
+		fmt.Fprintf(t.out, "│%s", fit(line, right))
+	}
+	status := t.status
+	if status == "" {
+		status = fmt.Sprintf("%s: %d issue%s  %s", t.project, len(t.issues), suffix(len(t.issues)), tuiHelp)
+	}
+	fmt.Fprintf(t.out, "\x1b[%d;1H\x1b[7m%s\x1b[0m", t.rows, fit(status, t.cols))
+	t.out.Flush()
+}
+
+// prompt reads a line of text typed on the status line.
+// It returns false if the user types escape.
+func (t *tui) prompt(label string) (string, bool, error) {
+	var text []rune
+	for {
+		t.status = label + string(text)
+		t.draw()
+		k, err := t.key()
+		if err != nil {
+			return "", false, err
+		}
+		switch k {
+		case "enter":
+			t.status = ""
+			return strings.TrimSpace(string(text)), true, nil
+		case "esc", "ctrl-c":
+			t.status = ""
+			return "", false, nil
+		case "backspace":
+			if len(text) > 0 {
+				text = text[:len(text)-1]
+			}
+		default:
+			if r, size := utf8.DecodeRuneInString(k); size == len(k) && r >= ' ' {
+				text = append(text, r)
+			}
+		}
+	}
+}
+
+// hotkey applies the batch operation op, configured in tui-keys,
+// to issue n, saving the issue's state so that it can be undone.
+func (t *tui) hotkey(n int, op string) error {
+	words, err := splitBatchLine(op)
+	if err != nil {
+		return err
+	}
+	s, err := saveIssueState(t.project, n)
+	if err != nil {
+		return err
+	}
+	if words[0] == "comment" && len(words) > 1 {
+		// Record the comment, so that undo can delete it.
+		change := &hookChange{State: s.state, Comment: strings.Join(words[1:], " ")}
+		err := putIssue(t.project, n, change, func() ([]string, error) {
+			var err error
+			if s.comment, err = postCommentID(t.project, n, change.Comment); err != nil {
+				return nil, err
+			}
+			return []string{"saved comment"}, nil
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		words = append([]string{words[0], fmt.Sprint(n)}, words[1:]...)
+		if _, err := runBatchOp(t.project, words); err != nil {
+			return err
+		}
+	}
+	t.undo = append(t.undo, s)
+	t.refresh(n)
+	t.status = fmt.Sprintf("#%d: %s (u to undo)", n, op)
+	return nil
+}
+
+// undoHotkey undoes the latest hotkey action not yet undone.
+func (t *tui) undoHotkey() error {
+	if len(t.undo) == 0 {
+		t.status = "nothing to undo"
+		return nil
+	}
+	s := t.undo[len(t.undo)-1]
+	if err := s.restore(t.project, false); err != nil {
+		return err
+	}
+	t.undo = t.undo[:len(t.undo)-1]
+	t.refresh(s.number)
+	t.status = fmt.Sprintf("#%d: undone", s.number)
+	return nil
+}
+
+// refresh reloads issue n after a change, for the list and thread.
+func (t *tui) refresh(n int) {
+	delete(t.threads, n)
+	issue, err := getIssue(t.project, n)
+	if err != nil {
+		return
+	}
+	for i := range t.issues {
+		if getInt(t.issues[i].Number) == n {
+			t.issues[i] = issue
+		}
+	}
+}
+
+// comment posts a comment on issue n, written in the system editor.
+func (t *tui) comment(n int) error {
+	t.stop()
+	text := strings.TrimSpace(string(editText(nil)))
+	if err := t.start(); err != nil {
+		return err
+	}
+	if text == "" {
+		t.status = "no comment posted"
+		return nil
+	}
+	return commentIssue(t.project, n, text)
+}
+
+// openBrowser opens url in the system web browser.
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("cmd", "/c", "start", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
+
+func (t *tui) loop() error {
+	for {
+		t.draw()
+		k, err := t.key()
+		if err != nil {
+			return err
+		}
+		t.status = ""
+		n := t.selected()
+		page := t.rows - 2
+		if op, ok := loadConfig().TUIKeys[k]; ok {
+			if err := t.hotkey(n, op); err != nil {
+				t.status = err.Error()
+			}
+			continue
+		}
+		switch k {
+		case "q", "ctrl-c":
+			return nil
+		case "j", "down":
+			if t.sel+1 < len(t.issues) {
+				t.sel++
+				t.scroll = 0
+			}
+		case "k", "up":
+			if t.sel > 0 {
+				t.sel--
+				t.scroll = 0
+			}
+		case "home":
+			t.sel, t.scroll = 0, 0
+		case "end":
+			t.sel, t.scroll = len(t.issues)-1, 0
+		case " ", "pgdn":
+			t.scroll += page
+		case "b", "pgup":
+			t.scroll -= page
+		case "o":
+			if err := openBrowser(issueURL(t.project, n)); err != nil {
+				t.status = err.Error()
+			}
+		case "c":
+			err = t.comment(n)
+			delete(t.threads, n)
+		case "l":
+			var labels string
+			var ok bool
+			labels, ok, err = t.prompt(fmt.Sprintf("labels for #%d (+add -remove): ", n))
+			if ok && labels != "" {
+				_, err = runBatchOp(t.project, append([]string{"label", fmt.Sprint(n)}, strings.Fields(labels)...))
+				delete(t.threads, n)
+			}
+		case "m":
+			var name string
+			var ok bool
+			name, ok, err = t.prompt(fmt.Sprintf("milestone for #%d (or none): ", n))
+			if ok && name != "" {
+				_, err = runBatchOp(t.project, []string{"milestone", fmt.Sprint(n), name})
+				delete(t.threads, n)
+			}
+		case "u":
+			err = t.undoHotkey()
+		case "r":
+			err = t.load()
+		case "?":
+			t.status = tuiHelp
+		}
+		if err != nil {
+			t.status = err.Error()
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// When a GitHub Enterprise Server is not directly reachable,
+// the -transport flag or the transport setting gives another way
+// to connect to the API host: a Unix domain socket, as "unix:path",
+// or a command whose standard input and output are the connection,
+// like an ssh -W tunnel or a ProxyCommand. In the command, %h and %p
+// stand for the host and port being connected to. TLS still runs
+// end to end, over the tunnel.
+
+// newTunnelTransport returns a copy of t that makes its connections
+// to the GitHub API hosts using the transport spec.
+func newTunnelTransport(t *http.Transport, spec string) *http.Transport {
+	t = t.Clone()
+	// A proxy would receive the connections to the API hosts
+	// instead of the tunnel, so those never use one.
+	proxy := t.Proxy
+	t.Proxy = func(r *http.Request) (*url.URL, error) {
+		if proxy == nil || isAPIHost(r.URL.Hostname()) {
+			return nil, nil
+		}
+		return proxy(r)
+	}
+	dial := t.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || !isAPIHost(host) {
+			return dial(ctx, network, addr)
+		}
+		if path := strings.TrimPrefix(spec, "unix:"); path != spec {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		}
+		return dialCommand(strings.NewReplacer("%h", host, "%p", port, "%%", "%").Replace(spec), addr)
+	}
+	return t
+}
+
+// isAPIHost reports whether host serves the GitHub API or its uploads.
+func isAPIHost(host string) bool {
+	base, upload := apiURLs()
+	if base == "" {
+		base = "https://api.github.com/"
+	}
+	if upload == "" {
+		upload = "https://uploads.github.com/"
+	}
+	for _, s := range []string{base, upload} {
+		if u, err := url.Parse(s); err == nil && u.Hostname() == host {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTransport checks the syntax of a transport spec.
+func checkTransport(spec string) error {
+	if strings.TrimSpace(spec) == "" || spec == "unix:" {
+		return fmt.Errorf("invalid transport %q: want unix:path or a command", spec)
+	}
+	return nil
+}
+
+// dialCommand starts the command and returns a connection
+// reading its standard output and writing its standard input.
+func dialCommand(command, addr string) (net.Conn, error) {
+	cmd := userCommand(command)
+	cmd.Stderr = os.Stderr
+	w, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	r, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("transport: %v", err)
+	}
+	return &cmdConn{cmd: cmd, r: r, w: w, addr: addr}, nil
+}
+
+// A cmdConn is a connection through a command's standard input and output.
+// It does not support deadlines.
+type cmdConn struct {
+	cmd  *exec.Cmd
+	r    io.ReadCloser
+	w    io.WriteCloser
+	addr string
+}
+
+func (c *cmdConn) Read(b []byte) (int, error)  { return c.r.Read(b) }
+func (c *cmdConn) Write(b []byte) (int, error) { return c.w.Write(b) }
+
+func (c *cmdConn) Close() error {
+	c.w.Close()
+	c.r.Close()
+	if c.cmd.Process != nil {
+		c.cmd.Process.Kill()
+	}
+	c.cmd.Wait()
+	return nil
+}
+
+func (c *cmdConn) LocalAddr() net.Addr                { return cmdAddr("transport") }
+func (c *cmdConn) RemoteAddr() net.Addr               { return cmdAddr(c.addr) }
+func (c *cmdConn) SetDeadline(t time.Time) error      { return nil }
+func (c *cmdConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *cmdConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// A cmdAddr is the address of one end of a cmdConn.
+type cmdAddr string
+
+func (a cmdAddr) Network() string { return "cmd" }
+func (a cmdAddr) String() string  { return string(a) }
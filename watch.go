@@ -0,0 +1,207 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// A watchState is the state of an issue that the watch command compares
+// from one check to the next.
+type watchState struct {
+	Title  string
+	State  string
+	Labels []string
+}
+
+// A watchSnapshot records the watched issues as of a check.
+type watchSnapshot struct {
+	Time    time.Time
+	Issues  map[int]watchState
+	Queries map[string][]int // issue numbers matching each watched query
+}
+
+// checkWatched fetches the watched queries and issues of project,
+// returning the new snapshot and descriptions of the changes since prev.
+func checkWatched(project string, c *config, prev *watchSnapshot) (*watchSnapshot, []string, error) {
+	next := &watchSnapshot{
+		Time:    time.Now(),
+		Issues:  make(map[int]watchState),
+		Queries: make(map[string][]int),
+	}
+	issues := make(map[int]*github.Issue)
+	for _, q := range c.Watch.Queries {
+		all, err := searchIssues(project, savedQuery(q))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", describeQuery(q), err)
+		}
+		for _, issue := range all {
+			n := getInt(issue.Number)
+			issues[n] = issue
+			next.Queries[q] = append(next.Queries[q], n)
+		}
+		sort.Ints(next.Queries[q])
+	}
+
+	// Fetch the watched issues, along with issues that no longer
+	// match a query, which may have been closed.
+	ids := append([]int(nil), c.Watch.Issues...)
+	if prev != nil {
+		for _, list := range prev.Queries {
+			ids = append(ids, list...)
+		}
+	}
+	for _, n := range ids {
+		if issues[n] != nil {
+			continue
+		}
+		issue, resp, err := client.Issues.Get(context.TODO(), projectOwner(project), projectRepo(project), n)
+		if err != nil && resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone) {
+			// The issue was deleted or transferred. Leave it out
+			// of the snapshot rather than failing every check.
+			log.Printf("%s#%d: %v", project, n, err)
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		issues[n] = issue
+	}
+
+	var ns []int
+	for n, issue := range issues {
+		next.Issues[n] = watchState{
+			Title:  getString(issue.Title),
+			State:  getString(issue.State),
+			Labels: getLabelNames(issue.Labels),
+		}
+		ns = append(ns, n)
+	}
+	if prev == nil {
+		return next, nil, nil
+	}
+
+	sort.Ints(ns)
+	var events []string
+	event := func(n int, what string) {
+		events = append(events, fmt.Sprintf("%s#%d %s: %s\n%s", project, n, what, next.Issues[n].Title, issueURL(project, n)))
+	}
+	for _, n := range ns {
+		s := next.Issues[n]
+		old, ok := prev.Issues[n]
+		if !ok {
+			if getTime(issues[n].CreatedAt).After(prev.Time) {
+				event(n, "opened")
+			}
+			continue
+		}
+		if old.State != s.State {
+			event(n, s.State)
+		}
+		had := make(map[string]bool)
+		for _, name := range old.Labels {
+			had[name] = true
+		}
+		for _, name := range s.Labels {
+			if !had[name] {
+				event(n, "labeled "+name)
+			}
+		}
+	}
+	return next, events, nil
+}
+
+// notify posts text to the configured chat webhooks.
+func notify(c *config, text string) {
+	for _, h := range c.Webhooks {
+		var body interface{}
+		switch h.Kind {
+		case "matrix":
+			body = map[string]string{"msgtype": "m.text", "body": text}
+		default: // slack, mattermost
+			body = map[string]string{"text": text}
+		}
+		data, _ := json.Marshal(body)
+		resp, err := http.Post(h.URL, "application/json", bytes.NewReader(data))
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode/100 != 2 {
+				err = fmt.Errorf("%s", resp.Status)
+			}
+		}
+		if err != nil {
+			log.Printf("webhook %s: %v", h.Kind, err)
+			metrics.Lock()
+			metrics.webhookErrors++
+			metrics.Unlock()
+		}
+	}
+}
+
+func runWatch(project string, args []string) {
+	fs := lookupCommand("watch").flags()
+	once := fs.Bool("once", false, "check once and exit")
+	metricsAddr := fs.String("metrics", "", "serve Prometheus metrics at /metrics on `addr`")
+	parseFlags(fs, args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+	}
+
+	c := loadConfig()
+	if len(c.Watch.Queries) == 0 && len(c.Watch.Issues) == 0 {
+		file, _ := configFile()
+		fatalf("no watched queries or issues configured in %s", file)
+	}
+	interval := c.Watch.Interval
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	if *metricsAddr != "" {
+		http.DefaultTransport = newMetricsTransport(http.DefaultTransport)
+		mux := http.NewServeMux()
+		mux.HandleFunc("/metrics", serveMetrics)
+		go func() {
+			fatal(http.ListenAndServe(*metricsAddr, countRequests(mux)))
+		}()
+	}
+
+	var prev *watchSnapshot
+	var snap watchSnapshot
+	if readCache(project, "watch", anyAge, &snap) {
+		prev = &snap
+	}
+	for {
+		next, events, err := checkWatched(project, c, prev)
+		if err != nil {
+			log.Print(err)
+		} else {
+			for _, text := range events {
+				fmt.Printf("%s\n", text)
+				notify(c, text)
+			}
+			writeCache(project, "watch", next)
+			prev = next
+			metrics.Lock()
+			metrics.lastSync = next.Time
+			metrics.Unlock()
+		}
+		if *once {
+			if err != nil {
+				fatal(err)
+			}
+			return
+		}
+		time.Sleep(interval)
+	}
+}
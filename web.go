@@ -0,0 +1,132 @@
+// Copyright 2022 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// serveHTML serves the read-only web pages of serve mode:
+// / lists the issues matching the query in the q form value,
+// and /N shows the issue numbered N with its comments.
+func (s *server) serveHTML(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/" {
+		q := r.FormValue("q")
+		data, stale, err := s.search(q, 1)
+		if err != nil {
+			serveError(w, err)
+			return
+		}
+		var list []*Issue
+		if err := json.Unmarshal(data, &list); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.render(w, listHTML, stale, map[string]interface{}{
+			"Project": s.project,
+			"Query":   q,
+			"Issues":  list,
+		})
+		return
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+	if err != nil || n <= 0 {
+		http.NotFound(w, r)
+		return
+	}
+	data, stale, err := s.issue(n, 1)
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+	issue := new(Issue)
+	if err := json.Unmarshal(data, issue); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.render(w, issueHTML, stale, map[string]interface{}{
+		"Project": s.project,
+		"Issue":   issue,
+	})
+}
+
+func (s *server) render(w http.ResponseWriter, t *template.Template, stale bool, data map[string]interface{}) {
+	data["Stale"] = stale
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if stale {
+		w.Header().Set("Warning", staleWarning)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+var htmlFuncs = template.FuncMap{
+	"date": func(t time.Time) string { return t.Format("2006-01-02 15:04") },
+	"trim": strings.TrimSpace,
+}
+
+const htmlHead = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 60em; margin: 1em auto; padding: 0 1em; }
+pre { white-space: pre-wrap; font-family: inherit; }
+.meta { color: #666; }
+.stale { background: #ffd; padding: 0.5em; }
+.comment { border-top: 1px solid #ccc; padding-top: 0.5em; }
+table td { padding: 0.1em 0.5em; vertical-align: top; }
+</style>
+</head>
+<body>
+{{if .Stale}}<p class="stale">GitHub could not be reached; this is an older copy.</p>{{end}}
+`
+
+var listHTML = template.Must(template.New("list").Funcs(htmlFuncs).Parse(strings.Replace(htmlHead, "{{.Title}}", "{{.Project}} issues", 1) + `
+<h1>{{.Project}} issues</h1>
+<form action="/">
+<input name="q" size="60" value="{{.Query}}"> <input type="submit" value="Search">
+</form>
+<table>
+{{range .Issues}}<tr><td><a href="/{{.Number}}">{{.Number}}</a></td><td>{{.Title}}</td></tr>
+{{else}}<tr><td>No issues found.</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+var issueHTML = template.Must(template.New("issue").Funcs(htmlFuncs).Parse(strings.Replace(htmlHead, "{{.Title}}", "{{.Project}}#{{.Issue.Number}} {{.Issue.Title}}", 1) + `
+{{with .Issue}}
+<p><a href="/">all issues</a></p>
+<h1>{{.Title}} <span class="meta">#{{.Number}}</span></h1>
+<table class="meta">
+<tr><td>State</td><td>{{.State}}{{if not .Closed.IsZero}} {{date .Closed}}{{end}}</td></tr>
+<tr><td>Reporter</td><td>{{.Reporter}}, {{date .Created}}</td></tr>
+{{if .Assignee}}<tr><td>Assignee</td><td>{{.Assignee}}</td></tr>{{end}}
+{{if .Labels}}<tr><td>Labels</td><td>{{range $i, $l := .Labels}}{{if $i}}, {{end}}{{$l}}{{end}}</td></tr>{{end}}
+{{if .Milestone}}<tr><td>Milestone</td><td>{{.Milestone}}</td></tr>{{end}}
+<tr><td>URL</td><td><a href="{{trim .URL}}">{{trim .URL}}</a></td></tr>
+</table>
+<pre>{{.Text}}</pre>
+{{range .Comments}}<div class="comment">
+<p class="meta">{{.Author}}, {{date .Time}}</p>
+<pre>{{.Text}}</pre>
+</div>
+{{end}}
+{{end}}
+</body>
+</html>
+`))